@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
@@ -8,7 +9,6 @@ import (
 
 	"github.com/binks-cli/binks/internal/executor"
 	"github.com/binks-cli/binks/shell"
-	"github.com/kballard/go-shellquote"
 	"golang.org/x/term"
 )
 
@@ -34,9 +34,13 @@ func main() {
 	altScreen := os.Getenv("BINKS_ALT_SCREEN") == "1"
 	if altScreen {
 		enableAltScreen()
-		// Ensure alt screen is disabled on SIGINT/SIGTERM
+		// Ensure alt screen is disabled on SIGTERM, and on SIGINT outside the
+		// REPL (which uses Ctrl+C to cancel the running command instead)
 		c := make(chan os.Signal, 1)
-		signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+		signal.Notify(c, syscall.SIGTERM)
+		if len(os.Args) >= 2 {
+			signal.Notify(c, os.Interrupt)
+		}
 		go func() {
 			<-c
 			disableAltScreen()
@@ -48,6 +52,7 @@ func main() {
 		// Start interactive REPL mode
 		sess := shell.NewSession()
 		err := shell.RunREPL(sess)
+		_ = sess.Close()
 		if err != nil {
 			fmt.Fprint(os.Stderr, shell.ErrorMessage(err))
 			if altScreen {
@@ -63,11 +68,24 @@ func main() {
 		return
 	}
 
+	if isMCPCommand(os.Args[1:]) {
+		// Serve MCP over stdio; stdout carries the protocol
+		if err := runMCP(os.Args[2:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+			fmt.Fprint(os.Stderr, shell.ErrorMessage(err))
+			os.Exit(1)
+		}
+		return
+	}
+
+	sh, err := shell.ConfiguredShell()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "binks: %v\n", err)
+	}
 	// Properly quote and join all arguments after the program name to form the command
-	command := shellquote.Join(os.Args[1:]...)
+	command := sh.Join(os.Args[1:]...)
 
-	exec := executor.NewBashExecutor()
-	output, err := exec.RunCommand(command)
+	exec := executor.NewShellExecutor(sh)
+	output, err := exec.RunCommand(context.Background(), command)
 
 	if err != nil {
 		fmt.Fprint(os.Stderr, shell.ErrorMessage(err))
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/binks-cli/binks/internal/mcp"
+	"github.com/binks-cli/binks/shell"
+)
+
+// defaultServeTools are exposed by `binks mcp serve` unless an allowlist says
+// otherwise; they only read session state.
+var defaultServeTools = []string{"get_cwd", "get_git_branch", "get_history"}
+
+// serveTool is a tool `binks mcp serve` can expose, backed by a shell session.
+type serveTool struct {
+	tool    mcp.Tool
+	handler func(ctx context.Context, sess *shell.Session, args map[string]any) (string, error)
+}
+
+var serveTools = []serveTool{
+	{
+		tool: mcp.Tool{
+			Name:        "run_command",
+			Description: "Run a shell command in the binks session's working directory and return its combined output.",
+			InputSchema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"command": map[string]any{"type": "string", "description": "The shell command to run"},
+				},
+				"required": []string{"command"},
+			},
+		},
+		handler: func(ctx context.Context, sess *shell.Session, args map[string]any) (string, error) {
+			cmd, _ := args["command"].(string)
+			if strings.TrimSpace(cmd) == "" {
+				return "", errors.New("command is required")
+			}
+			return sess.RunCommandContext(ctx, cmd)
+		},
+	},
+	{
+		tool: mcp.Tool{
+			Name:        "change_dir",
+			Description: "Change the binks session's working directory (~ is expanded) and return the new one.",
+			InputSchema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"path": map[string]any{"type": "string", "description": "Directory to change to"},
+				},
+				"required": []string{"path"},
+			},
+		},
+		handler: func(_ context.Context, sess *shell.Session, args map[string]any) (string, error) {
+			path, _ := args["path"].(string)
+			if err := sess.ChangeDir(path); err != nil {
+				return "", err
+			}
+			return sess.Cwd(), nil
+		},
+	},
+	{
+		tool: mcp.Tool{
+			Name:        "get_cwd",
+			Description: "Return the binks session's working directory.",
+		},
+		handler: func(_ context.Context, sess *shell.Session, _ map[string]any) (string, error) {
+			return sess.Cwd(), nil
+		},
+	},
+	{
+		tool: mcp.Tool{
+			Name:        "get_git_branch",
+			Description: "Return the git branch (or short commit when detached) of the working directory, or an empty string outside a repository.",
+		},
+		handler: func(_ context.Context, sess *shell.Session, _ map[string]any) (string, error) {
+			return shell.GetGitBranch(sess.Cwd()), nil
+		},
+	},
+	{
+		tool: mcp.Tool{
+			Name:        "get_history",
+			Description: "List the commands run in this session, oldest first, with their exit codes.",
+			InputSchema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"limit": map[string]any{"type": "integer", "description": "Only return the last N commands"},
+				},
+			},
+		},
+		handler: func(_ context.Context, sess *shell.Session, args map[string]any) (string, error) {
+			history := sess.History()
+			if n, ok := args["limit"].(float64); ok && n >= 0 && int(n) < len(history) {
+				history = history[len(history)-int(n):]
+			}
+			var b strings.Builder
+			for _, rec := range history {
+				fmt.Fprintf(&b, "[exit %d] %s\n", rec.ExitCode, rec.Command)
+			}
+			return b.String(), nil
+		},
+	},
+}
+
+// runMCP handles `binks mcp <subcommand>`.
+func runMCP(args []string, in io.Reader, out, errOut io.Writer) error {
+	if len(args) == 0 || args[0] != "serve" {
+		return errors.New("usage: binks mcp serve [--allow tool,...]")
+	}
+	return runMCPServe(args[1:], in, out, errOut)
+}
+
+// runMCPServe serves a binks session's tools over MCP on in/out until in is closed.
+func runMCPServe(args []string, in io.Reader, out, errOut io.Writer) error {
+	fs := flag.NewFlagSet("binks mcp serve", flag.ContinueOnError)
+	fs.SetOutput(errOut)
+	allowFlag := fs.String("allow", "", "comma-separated tools to expose, or \"all\" (default: read-only tools)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	cfg := shell.LoadConfig()
+	allow := cfg.MCP.Serve.Allow
+	if *allowFlag != "" {
+		allow = strings.Split(*allowFlag, ",")
+	}
+	tools, err := allowedServeTools(allow)
+	if err != nil {
+		return err
+	}
+
+	// Output goes back to the client, so commands must never take over the terminal.
+	exec, err := shell.NewExecutor(cfg.Exec, true)
+	if err != nil {
+		fmt.Fprintf(errOut, "binks: %v\n", err)
+	}
+	sess := &shell.Session{
+		Executor: exec,
+		Exec:     cfg.Exec,
+		Out:      errOut,
+		Err:      errOut,
+	}
+	defer sess.Close()
+	if err := sess.ChangeDir("."); err != nil {
+		return err
+	}
+
+	srv := mcp.NewServer(mcp.Implementation{Name: "binks", Version: "0.1.0"})
+	srv.Instructions = "Tools run in a persistent binks shell session; change_dir affects later commands."
+	for _, t := range tools {
+		handler := t.handler
+		srv.AddTool(t.tool, func(ctx context.Context, args map[string]any) (string, error) {
+			return handler(ctx, sess, args)
+		})
+	}
+	fmt.Fprintf(errOut, "binks: serving MCP tools: %s\n", strings.Join(toolNames(tools), ", "))
+	return srv.Serve(context.Background(), in, out)
+}
+
+// allowedServeTools resolves an allowlist to the tools it names, in a stable order.
+func allowedServeTools(allow []string) ([]serveTool, error) {
+	if len(allow) == 0 {
+		allow = defaultServeTools
+	}
+	want := make(map[string]bool)
+	for _, name := range allow {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if name == "all" {
+			return serveTools, nil
+		}
+		want[name] = true
+	}
+	var tools []serveTool
+	for _, t := range serveTools {
+		if want[t.tool.Name] {
+			tools = append(tools, t)
+			delete(want, t.tool.Name)
+		}
+	}
+	if len(want) > 0 {
+		unknown := make([]string, 0, len(want))
+		for name := range want {
+			unknown = append(unknown, name)
+		}
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("unknown MCP tool(s): %s", strings.Join(unknown, ", "))
+	}
+	if len(tools) == 0 {
+		return nil, errors.New("no MCP tools allowed")
+	}
+	return tools, nil
+}
+
+func toolNames(tools []serveTool) []string {
+	names := make([]string, len(tools))
+	for i, t := range tools {
+		names[i] = t.tool.Name
+	}
+	return names
+}
+
+// isMCPCommand reports whether the arguments ask for the MCP subcommand rather
+// than a shell command.
+func isMCPCommand(args []string) bool {
+	return len(args) > 0 && args[0] == "mcp"
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/binks-cli/binks/internal/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startMCPServe runs `binks mcp serve` in-process and connects a client to it.
+func startMCPServe(t *testing.T, args ...string) *mcp.Client {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Chdir(t.TempDir()) // change_dir moves the process; restore it afterwards
+	reqR, reqW := io.Pipe()
+	respR, respW := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- runMCPServe(args, reqR, respW, io.Discard)
+		_ = respW.Close()
+	}()
+	c := mcp.NewClient("binks", respR, reqW)
+	require.NoError(t, c.Initialize(context.Background()))
+	t.Cleanup(func() {
+		_ = c.Close()
+		<-done
+	})
+	return c
+}
+
+func toolList(t *testing.T, c *mcp.Client) []string {
+	t.Helper()
+	tools, err := c.ListTools(context.Background())
+	require.NoError(t, err)
+	var names []string
+	for _, tool := range tools {
+		names = append(names, tool.Name)
+	}
+	return names
+}
+
+func TestMCPServe_DefaultToolsAreReadOnly(t *testing.T) {
+	c := startMCPServe(t)
+	assert.Equal(t, "binks", c.ServerInfo.Name)
+	assert.Equal(t, []string{"get_cwd", "get_git_branch", "get_history"}, toolList(t, c))
+
+	_, err := c.CallTool(context.Background(), "run_command", map[string]any{"command": "echo hi"})
+	assert.EqualError(t, err, `MCP error -32602: unknown tool "run_command"`)
+}
+
+func TestMCPServe_SessionTools(t *testing.T) {
+	c := startMCPServe(t, "--allow", "all")
+	ctx := context.Background()
+	assert.Len(t, toolList(t, c), 5)
+
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0o755))
+	res, err := c.CallTool(ctx, "change_dir", map[string]any{"path": filepath.Join(dir, "sub")})
+	require.NoError(t, err)
+	want, _ := filepath.EvalSymlinks(filepath.Join(dir, "sub"))
+	got, _ := filepath.EvalSymlinks(res.Text())
+	assert.Equal(t, want, got)
+
+	res, err = c.CallTool(ctx, "run_command", map[string]any{"command": "basename \"$PWD\""})
+	require.NoError(t, err)
+	assert.Equal(t, "sub\n", res.Text())
+
+	res, err = c.CallTool(ctx, "run_command", map[string]any{"command": "echo oops; exit 3"})
+	require.NoError(t, err)
+	assert.True(t, res.IsError)
+	assert.Equal(t, "oops\n\nError: exit status 3", res.Text())
+
+	res, err = c.CallTool(ctx, "get_history", map[string]any{"limit": 1})
+	require.NoError(t, err)
+	assert.Equal(t, "[exit 3] echo oops; exit 3\n", res.Text())
+
+	res, err = c.CallTool(ctx, "get_git_branch", nil)
+	require.NoError(t, err)
+	assert.Empty(t, res.Text())
+}
+
+func TestAllowedServeTools(t *testing.T) {
+	tools, err := allowedServeTools([]string{" run_command", "get_cwd"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"run_command", "get_cwd"}, toolNames(tools))
+
+	_, err = allowedServeTools([]string{"get_cwd", "rm_rf", "format"})
+	assert.EqualError(t, err, "unknown MCP tool(s): format, rm_rf")
+
+	_, err = allowedServeTools([]string{" "})
+	assert.EqualError(t, err, "no MCP tools allowed")
+}
+
+func TestRunMCP_Usage(t *testing.T) {
+	err := runMCP([]string{"bogus"}, nil, io.Discard, io.Discard)
+	assert.EqualError(t, err, "usage: binks mcp serve [--allow tool,...]")
+}
@@ -40,4 +40,11 @@ echo "8. Testing usage message:"
 ./binks 2>&1 || echo "Usage message displayed"
 echo
 
+echo "9. Asking the AI (replayed from a recorded cassette, no network or API key needed):"
+printf '>> how do I list hidden files?\ny\nexit\n' | \
+  OPENAI_API_KEY=replay BINKS_AI_PROVIDER=openai \
+  BINKS_CASSETTE=test/testdata/openai_list_files.json BINKS_CASSETTE_MODE=replay \
+  ./binks
+echo
+
 echo "=== Demo Complete ==="
@@ -5,16 +5,15 @@ go 1.24
 require (
 	github.com/chzyer/readline v1.5.1
 	github.com/creack/pty v1.1.24
-	github.com/kballard/go-shellquote v0.0.0-20180428030007-95032a82bc51
+	github.com/fatih/color v1.18.0
 	github.com/mattn/go-isatty v0.0.20
+	golang.org/x/sys v0.33.0
 	golang.org/x/term v0.32.0
 )
 
 require (
-	github.com/fatih/color v1.18.0 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/stretchr/objx v0.5.2 // indirect
-	golang.org/x/sys v0.33.0 // indirect
 )
 
 require (
@@ -1,13 +1,104 @@
 package agent
 
-// Agent is an interface for responding to prompts.
+import "context"
+
+// Message roles understood by chat-style model APIs.
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	RoleTool      = "tool"
+)
+
+// Message is a single entry in a conversation with an agent.
+type Message struct {
+	Role    string
+	Content string
+	// ToolCalls holds the tools an assistant message asked to call.
+	ToolCalls []ToolCall
+	// ToolCallID links a RoleTool message to the call it answers.
+	ToolCallID string
+}
+
+// ToolCall is a request from the model to run one of the offered tools.
+type ToolCall struct {
+	ID        string
+	Name      string
+	Arguments string // JSON-encoded arguments object
+}
+
+// Reply is a model response that may ask for tools to be called instead of,
+// or in addition to, returning text.
+type Reply struct {
+	Content   string
+	ToolCalls []ToolCall
+	Usage     Usage
+}
+
+// Usage counts the tokens a request consumed.
+type Usage struct {
+	Model            string // model that served the request, as reported by the provider
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// Agent is an interface for responding to a conversation.
+// The last message is normally the user's current query. Cancelling ctx
+// aborts the request.
 type Agent interface {
-	Respond(prompt string) (string, error)
+	Respond(ctx context.Context, messages []Message) (string, error)
+}
+
+// StreamingAgent is an optional interface for agents that can emit partial
+// output while a response is still being generated.
+type StreamingAgent interface {
+	Agent
+	// RespondStream behaves like Respond but calls onToken with each chunk of
+	// text as it arrives. The returned string is the complete response.
+	RespondStream(ctx context.Context, messages []Message, onToken func(string)) (string, error)
+}
+
+// ToolCallingAgent is an optional interface for agents that support function
+// calling. The caller runs any requested tools and sends their results back as
+// RoleTool messages until the reply contains no more tool calls.
+type ToolCallingAgent interface {
+	Agent
+	// RespondWithTools offers tools to the model. If onToken is non-nil, text
+	// is streamed to it as it arrives.
+	RespondWithTools(ctx context.Context, messages []Message, tools []Tool, onToken func(string)) (Reply, error)
 }
 
-// AgentFunc allows using a function as an Agent for testing.
-type AgentFunc func(string) (string, error)
+// ModelLister is implemented by agents that can list the models their server offers.
+type ModelLister interface {
+	ListModels(ctx context.Context) ([]string, error)
+}
+
+// UsageReporter is implemented by agents that report token usage. LastUsage
+// describes the most recent successful request, and is zero when the provider
+// reported none.
+type UsageReporter interface {
+	LastUsage() Usage
+}
+
+// AgentFunc allows using a function as an Agent for testing. The context is
+// not passed on.
+type AgentFunc func([]Message) (string, error)
+
+func (f AgentFunc) Respond(_ context.Context, messages []Message) (string, error) {
+	return f(messages)
+}
+
+// UserPrompt returns a single-turn conversation holding prompt as a user message.
+func UserPrompt(prompt string) []Message {
+	return []Message{{Role: RoleUser, Content: prompt}}
+}
 
-func (f AgentFunc) Respond(prompt string) (string, error) {
-	return f(prompt)
+// LastUserMessage returns the content of the most recent user message, or "" if there is none.
+func LastUserMessage(messages []Message) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == RoleUser {
+			return messages[i].Content
+		}
+	}
+	return ""
 }
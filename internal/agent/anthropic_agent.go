@@ -0,0 +1,397 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// anthropicVersion is the Messages API version binks speaks.
+	anthropicVersion = "2023-06-01"
+	// anthropicMaxTokens is the reply limit sent when none is configured; the
+	// Messages API requires one.
+	anthropicMaxTokens = 1024
+)
+
+// AnthropicAgent implements the Agent interface using Anthropic's Messages API.
+type AnthropicAgent struct {
+	APIKey      string
+	Model       string
+	BaseURL     string
+	Temperature *float64 // nil uses the model's default
+	MaxTokens   int
+	Timeout     time.Duration // per request; 0 means requestTimeout
+	Retry       RetryPolicy
+	Client      interface {
+		Do(req *http.Request) (*http.Response, error)
+	}
+
+	sleep func(time.Duration) // waits between retries; time.Sleep when nil
+	usage Usage               // usage of the last successful request
+}
+
+// NewAnthropicAgent creates a new AnthropicAgent, reading config from environment variables.
+func NewAnthropicAgent() *AnthropicAgent {
+	key := os.Getenv("ANTHROPIC_API_KEY")
+	model := os.Getenv("ANTHROPIC_MODEL")
+	if model == "" {
+		model = "claude-3-5-haiku-latest"
+	}
+	base := os.Getenv("ANTHROPIC_API_BASE")
+	if base == "" {
+		base = "https://api.anthropic.com/v1"
+	}
+	return &AnthropicAgent{
+		APIKey:    key,
+		Model:     model,
+		BaseURL:   base,
+		MaxTokens: anthropicMaxTokens,
+		Retry:     DefaultRetryPolicy,
+		Client:    newHTTPClient(),
+	}
+}
+
+type anthropicMessage struct {
+	Role    string           `json:"role"`
+	Content []anthropicBlock `json:"content"`
+}
+
+// anthropicBlock is one content block: text, a tool_use request from the
+// model, or the tool_result answering it.
+type anthropicBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   string          `json:"content,omitempty"`
+}
+
+type anthropicTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	InputSchema map[string]any `json:"input_schema"`
+}
+
+type anthropicRequest struct {
+	Model       string             `json:"model"`
+	System      string             `json:"system,omitempty"`
+	Messages    []anthropicMessage `json:"messages"`
+	MaxTokens   int                `json:"max_tokens"`
+	Temperature *float64           `json:"temperature,omitempty"`
+	Stream      bool               `json:"stream,omitempty"`
+	Tools       []anthropicTool    `json:"tools,omitempty"`
+}
+
+type anthropicError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// apiError converts an error envelope into a typed error, taking the status
+// and Retry-After from resp when the error came with one.
+func (e anthropicError) apiError(resp *http.Response) *APIError {
+	apiErr := &APIError{Provider: "Anthropic", Type: e.Type, Message: e.Message}
+	if resp != nil {
+		apiErr.StatusCode = resp.StatusCode
+		apiErr.RetryAfter = retryAfter(resp.Header)
+	}
+	return apiErr.classify()
+}
+
+type anthropicResponse struct {
+	Type    string           `json:"type"`
+	Model   string           `json:"model"`
+	Content []anthropicBlock `json:"content"`
+	Usage   anthropicUsage   `json:"usage"`
+	Error   *anthropicError  `json:"error,omitempty"`
+}
+
+// anthropicUsage is the token count of a message. Streams report input tokens
+// in message_start and output tokens in message_delta.
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// anthropicStreamEvent is a single server-sent event of a streamed message.
+type anthropicStreamEvent struct {
+	Type         string          `json:"type"`
+	Index        int             `json:"index"`
+	ContentBlock *anthropicBlock `json:"content_block,omitempty"`
+	Delta        struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+	} `json:"delta"`
+	Message *anthropicResponse `json:"message,omitempty"` // message_start
+	Usage   *anthropicUsage    `json:"usage,omitempty"`   // message_delta
+	Error   *anthropicError    `json:"error,omitempty"`
+}
+
+// Respond sends the conversation to Anthropic and returns the reply.
+func (a *AnthropicAgent) Respond(ctx context.Context, messages []Message) (string, error) {
+	reply, err := a.complete(ctx, messages, nil, nil)
+	return reply.Content, err
+}
+
+// RespondStream sends the conversation with streaming enabled and calls onToken
+// for every text delta as it arrives. It returns the full reply once the stream ends.
+func (a *AnthropicAgent) RespondStream(ctx context.Context, messages []Message, onToken func(string)) (string, error) {
+	if onToken == nil {
+		onToken = func(string) {}
+	}
+	reply, err := a.complete(ctx, messages, nil, onToken)
+	return reply.Content, err
+}
+
+// RespondWithTools offers tools to the model using Anthropic tool use.
+func (a *AnthropicAgent) RespondWithTools(ctx context.Context, messages []Message, tools []Tool, onToken func(string)) (Reply, error) {
+	return a.complete(ctx, messages, tools, onToken)
+}
+
+// complete performs one Messages API call, streaming when onToken is non-nil.
+// Transient failures are retried according to a.Retry.
+func (a *AnthropicAgent) complete(ctx context.Context, messages []Message, tools []Tool, onToken func(string)) (Reply, error) {
+	system, converted := toAnthropicMessages(redactMessages(messages))
+	payload := anthropicRequest{
+		Model:       a.Model,
+		System:      system,
+		Messages:    converted,
+		MaxTokens:   a.MaxTokens,
+		Temperature: a.Temperature,
+		Stream:      onToken != nil,
+	}
+	if payload.MaxTokens <= 0 {
+		payload.MaxTokens = anthropicMaxTokens
+	}
+	for _, t := range tools {
+		schema := t.Parameters
+		if schema == nil {
+			schema = map[string]any{"type": "object", "properties": map[string]any{}}
+		}
+		payload.Tools = append(payload.Tools, anthropicTool{Name: t.Name, Description: t.Description, InputSchema: schema})
+	}
+	var reply Reply
+	onToken, check := streamOnce(onToken)
+	a.usage = Usage{}
+	err := a.Retry.do(ctx, a.sleep, func() error {
+		var err error
+		reply, err = a.attempt(ctx, messages, payload, onToken)
+		return check(err)
+	})
+	if err == nil {
+		a.usage = reply.Usage
+	}
+	return reply, err
+}
+
+// LastUsage reports the tokens used by the last successful request.
+func (a *AnthropicAgent) LastUsage() Usage {
+	return a.usage
+}
+
+// attempt sends one Messages API request and reads its reply.
+func (a *AnthropicAgent) attempt(ctx context.Context, messages []Message, payload anthropicRequest, onToken func(string)) (Reply, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeoutOr(a.Timeout))
+	defer cancel()
+	resp, err := a.send(ctx, messages, payload)
+	if err != nil {
+		return Reply{}, err
+	}
+	defer resp.Body.Close()
+	if onToken == nil || !strings.Contains(resp.Header.Get("Content-Type"), "text/event-stream") {
+		// Errors come back as a plain JSON envelope even when streaming.
+		reply, err := a.readResponse(resp)
+		if err == nil && onToken != nil && reply.Content != "" {
+			onToken(reply.Content)
+		}
+		return reply, err
+	}
+	return a.readStream(resp.Body, onToken)
+}
+
+// send encodes the Messages API request and performs it.
+func (a *AnthropicAgent) send(ctx context.Context, messages []Message, payload anthropicRequest) (*http.Response, error) {
+	debug := os.Getenv("BINKS_DEBUG_AI") == "1"
+	if debug {
+		fmt.Fprintf(os.Stderr, "[AnthropicAgent] Received %d messages, prompt: %q\n", len(messages), Redact(LastUserMessage(messages)))
+	}
+	if a.APIKey == "" {
+		return nil, errors.New("AI is not configured. Set ANTHROPIC_API_KEY environment variable")
+	}
+	url := a.BaseURL + "/messages"
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	if debug {
+		fmt.Fprintf(os.Stderr, "[AnthropicAgent] Sending request to %s: %s\n", url, string(body))
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-api-key", a.APIKey)
+	req.Header.Set("anthropic-version", anthropicVersion)
+	req.Header.Set("Content-Type", "application/json")
+	if payload.Stream {
+		req.Header.Set("Accept", "text/event-stream")
+	}
+	resp, err := a.Client.Do(req)
+	if err != nil {
+		return nil, transportError(err)
+	}
+	return resp, nil
+}
+
+// toAnthropicMessages converts a conversation to the Messages API format.
+// System messages move to the top-level system field, tool results become
+// user turns, and consecutive turns from the same role are merged.
+func toAnthropicMessages(messages []Message) (string, []anthropicMessage) {
+	var system []string
+	var out []anthropicMessage
+	for _, m := range messages {
+		var role string
+		var blocks []anthropicBlock
+		switch m.Role {
+		case RoleSystem:
+			system = append(system, m.Content)
+			continue
+		case RoleTool:
+			role = RoleUser
+			blocks = append(blocks, anthropicBlock{Type: "tool_result", ToolUseID: m.ToolCallID, Content: m.Content})
+		default:
+			role = m.Role
+			if m.Content != "" {
+				blocks = append(blocks, anthropicBlock{Type: "text", Text: m.Content})
+			}
+			for _, tc := range m.ToolCalls {
+				input := json.RawMessage(tc.Arguments)
+				if !json.Valid(input) {
+					input = json.RawMessage("{}")
+				}
+				blocks = append(blocks, anthropicBlock{Type: "tool_use", ID: tc.ID, Name: tc.Name, Input: input})
+			}
+		}
+		if len(blocks) == 0 {
+			continue
+		}
+		if n := len(out); n > 0 && out[n-1].Role == role {
+			out[n-1].Content = append(out[n-1].Content, blocks...)
+			continue
+		}
+		out = append(out, anthropicMessage{Role: role, Content: blocks})
+	}
+	return strings.Join(system, "\n\n"), out
+}
+
+// readResponse parses a non-streamed Messages API response.
+func (a *AnthropicAgent) readResponse(resp *http.Response) (Reply, error) {
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Reply{}, requestError(err)
+	}
+	if os.Getenv("BINKS_DEBUG_AI") == "1" {
+		fmt.Fprintf(os.Stderr, "[AnthropicAgent] Raw response: %s\n", string(respBody))
+	}
+	var aiResp anthropicResponse
+	if err := json.Unmarshal(respBody, &aiResp); err != nil {
+		if resp.StatusCode >= 400 {
+			// e.g. an HTML page from a proxy
+			return Reply{}, anthropicError{Message: fmt.Sprintf("%d %s", resp.StatusCode, http.StatusText(resp.StatusCode))}.apiError(resp)
+		}
+		return Reply{}, errors.New("AI error: failed to parse response")
+	}
+	if aiResp.Error != nil {
+		return Reply{}, aiResp.Error.apiError(resp)
+	}
+	var content strings.Builder
+	reply := Reply{Usage: Usage{Model: aiResp.Model, PromptTokens: aiResp.Usage.InputTokens, CompletionTokens: aiResp.Usage.OutputTokens}}
+	for _, block := range aiResp.Content {
+		switch block.Type {
+		case "text":
+			content.WriteString(block.Text)
+		case "tool_use":
+			reply.ToolCalls = append(reply.ToolCalls, ToolCall{ID: block.ID, Name: block.Name, Arguments: string(block.Input)})
+		}
+	}
+	reply.Content = strings.TrimRight(content.String(), "\n\r ")
+	if reply.Content == "" && len(reply.ToolCalls) == 0 {
+		return Reply{}, errors.New("AI error: no response from model")
+	}
+	return reply, nil
+}
+
+// readStream accumulates a streamed message, forwarding text deltas to onToken
+// and assembling tool_use input from its JSON fragments.
+func (a *AnthropicAgent) readStream(r io.Reader, onToken func(string)) (Reply, error) {
+	var content strings.Builder
+	var calls []ToolCall
+	toolIndex := make(map[int]int) // content block index -> calls index
+	var usage Usage
+	err := readSSE(r, func(data string) error {
+		if os.Getenv("BINKS_DEBUG_AI") == "1" {
+			fmt.Fprintf(os.Stderr, "[AnthropicAgent] Stream event: %s\n", data)
+		}
+		var event anthropicStreamEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return errors.New("AI error: failed to parse response")
+		}
+		switch event.Type {
+		case "error":
+			if event.Error == nil {
+				return anthropicError{Message: "unknown error"}.apiError(nil)
+			}
+			return event.Error.apiError(nil)
+		case "message_start":
+			if event.Message != nil {
+				usage.Model = event.Message.Model
+				usage.PromptTokens = event.Message.Usage.InputTokens
+			}
+		case "message_delta":
+			if event.Usage != nil {
+				usage.CompletionTokens = event.Usage.OutputTokens
+			}
+		case "content_block_start":
+			if event.ContentBlock != nil && event.ContentBlock.Type == "tool_use" {
+				toolIndex[event.Index] = len(calls)
+				calls = append(calls, ToolCall{ID: event.ContentBlock.ID, Name: event.ContentBlock.Name})
+			}
+		case "content_block_delta":
+			switch event.Delta.Type {
+			case "text_delta":
+				if event.Delta.Text != "" {
+					content.WriteString(event.Delta.Text)
+					onToken(event.Delta.Text)
+				}
+			case "input_json_delta":
+				if i, ok := toolIndex[event.Index]; ok {
+					calls[i].Arguments += event.Delta.PartialJSON
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return Reply{}, requestError(err)
+	}
+	for i := range calls {
+		if calls[i].Arguments == "" {
+			calls[i].Arguments = "{}"
+		}
+	}
+	if content.Len() == 0 && len(calls) == 0 {
+		return Reply{}, errors.New("AI error: no response from model")
+	}
+	return Reply{Content: strings.TrimRight(content.String(), "\n\r "), ToolCalls: calls, Usage: usage}, nil
+}
@@ -0,0 +1,167 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// anthropicStandIn serves the Messages API with handler and returns an agent pointed at it.
+func anthropicStandIn(t *testing.T, handler http.HandlerFunc) *AnthropicAgent {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	a := NewAnthropicAgent()
+	a.APIKey = "test-key"
+	a.BaseURL = srv.URL
+	a.sleep = func(time.Duration) {}
+	return a
+}
+
+func decodeAnthropicRequest(t *testing.T, r *http.Request) anthropicRequest {
+	t.Helper()
+	var req anthropicRequest
+	require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+	return req
+}
+
+func TestAnthropicAgent_Respond_Success(t *testing.T) {
+	var got anthropicRequest
+	a := anthropicStandIn(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/messages", r.URL.Path)
+		assert.Equal(t, "test-key", r.Header.Get("x-api-key"))
+		assert.Equal(t, anthropicVersion, r.Header.Get("anthropic-version"))
+		got = decodeAnthropicRequest(t, r)
+		_, _ = io.WriteString(w, `{"type":"message","content":[{"type":"text","text":"Hello"},{"type":"text","text":" there!\n"}]}`)
+	})
+	a.Model = "claude-test"
+	resp, err := a.Respond(context.Background(), []Message{
+		{Role: RoleSystem, Content: "You are binks."},
+		{Role: RoleUser, Content: "Hi"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Hello there!", resp)
+	assert.Equal(t, "claude-test", got.Model)
+	assert.Equal(t, "You are binks.", got.System)
+	assert.Equal(t, anthropicMaxTokens, got.MaxTokens)
+	assert.Equal(t, []anthropicMessage{{Role: RoleUser, Content: []anthropicBlock{{Type: "text", Text: "Hi"}}}}, got.Messages)
+}
+
+func TestAnthropicAgent_Respond_NoAPIKey(t *testing.T) {
+	a := NewAnthropicAgent()
+	a.APIKey = ""
+	_, err := a.Respond(context.Background(), UserPrompt("Hi"))
+	assert.EqualError(t, err, "AI is not configured. Set ANTHROPIC_API_KEY environment variable")
+}
+
+func TestAnthropicAgent_Respond_ErrorEnvelope(t *testing.T) {
+	a := anthropicStandIn(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = io.WriteString(w, `{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`)
+	})
+	_, err := a.Respond(context.Background(), UserPrompt("Hi"))
+	assert.EqualError(t, err, "Anthropic API error: invalid x-api-key")
+}
+
+func TestAnthropicAgent_Respond_Timeout(t *testing.T) {
+	a := NewAnthropicAgent()
+	a.APIKey = "test-key"
+	a.Client = &fakeHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			return nil, context.DeadlineExceeded
+		},
+	}
+	_, err := a.Respond(context.Background(), UserPrompt("Hi"))
+	assert.EqualError(t, err, "AI request timed out")
+}
+
+func TestAnthropicAgent_RespondStream(t *testing.T) {
+	a := anthropicStandIn(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.True(t, decodeAnthropicRequest(t, r).Stream)
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, strings.Join([]string{
+			"event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"model\":\"claude-test\",\"usage\":{\"input_tokens\":25,\"output_tokens\":1}}}\n",
+			"event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"text\",\"text\":\"\"}}\n",
+			"event: ping\ndata: {\"type\":\"ping\"}\n",
+			"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"Hel\"}}\n",
+			"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"lo\"}}\n",
+			"event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"end_turn\"},\"usage\":{\"output_tokens\":9}}\n",
+			"event: message_stop\ndata: {\"type\":\"message_stop\"}\n",
+		}, "\n"))
+	})
+	var tokens []string
+	resp, err := a.RespondStream(context.Background(), UserPrompt("Hi"), func(tok string) { tokens = append(tokens, tok) })
+	require.NoError(t, err)
+	assert.Equal(t, "Hello", resp)
+	assert.Equal(t, []string{"Hel", "lo"}, tokens)
+	assert.Equal(t, Usage{Model: "claude-test", PromptTokens: 25, CompletionTokens: 9}, a.LastUsage())
+}
+
+func TestAnthropicAgent_RespondStream_ErrorEvent(t *testing.T) {
+	a := anthropicStandIn(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, "event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n")
+	})
+	_, err := a.RespondStream(context.Background(), UserPrompt("Hi"), nil)
+	assert.EqualError(t, err, "Anthropic API error: Overloaded")
+}
+
+func TestAnthropicAgent_RespondWithTools(t *testing.T) {
+	var got anthropicRequest
+	a := anthropicStandIn(t, func(w http.ResponseWriter, r *http.Request) {
+		got = decodeAnthropicRequest(t, r)
+		_, _ = io.WriteString(w, `{"type":"message","content":[{"type":"text","text":"Checking."},{"type":"tool_use","id":"tu_2","name":"list_dir","input":{"path":"."}}]}`)
+	})
+	history := []Message{
+		{Role: RoleUser, Content: "what's here?"},
+		{Role: RoleAssistant, ToolCalls: []ToolCall{
+			{ID: "tu_1", Name: "run_command", Arguments: `{"command":"pwd"}`},
+			{ID: "tu_0", Name: "read_file", Arguments: `not json`},
+		}},
+		{Role: RoleTool, ToolCallID: "tu_1", Content: "/tmp"},
+		{Role: RoleTool, ToolCallID: "tu_0", Content: "denied"},
+	}
+	reply, err := a.RespondWithTools(context.Background(), history, []Tool{{Name: "list_dir", Description: "List"}}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Checking.", reply.Content)
+	assert.Equal(t, []ToolCall{{ID: "tu_2", Name: "list_dir", Arguments: `{"path":"."}`}}, reply.ToolCalls)
+
+	require.Len(t, got.Tools, 1)
+	assert.Equal(t, "object", got.Tools[0].InputSchema["type"])
+	require.Len(t, got.Messages, 3, "tool results are merged into one user turn")
+	assert.Equal(t, "tool_use", got.Messages[1].Content[0].Type)
+	assert.JSONEq(t, `{"command":"pwd"}`, string(got.Messages[1].Content[0].Input))
+	assert.JSONEq(t, `{}`, string(got.Messages[1].Content[1].Input))
+	assert.Equal(t, RoleUser, got.Messages[2].Role)
+	assert.Equal(t, []anthropicBlock{
+		{Type: "tool_result", ToolUseID: "tu_1", Content: "/tmp"},
+		{Type: "tool_result", ToolUseID: "tu_0", Content: "denied"},
+	}, got.Messages[2].Content)
+}
+
+func TestAnthropicAgent_RespondWithTools_Stream(t *testing.T) {
+	a := anthropicStandIn(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, strings.Join([]string{
+			`data: {"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"tu_1","name":"run_command","input":{}}}`,
+			`data: {"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"command\":"}}`,
+			`data: {"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"\"ls\"}"}}`,
+			`data: {"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"tu_2","name":"get_cwd","input":{}}}`,
+			`data: {"type":"message_stop"}`,
+		}, "\n\n"))
+	})
+	reply, err := a.RespondWithTools(context.Background(), UserPrompt("list"), []Tool{{Name: "run_command"}}, func(string) {})
+	require.NoError(t, err)
+	assert.Equal(t, []ToolCall{
+		{ID: "tu_1", Name: "run_command", Arguments: `{"command":"ls"}`},
+		{ID: "tu_2", Name: "get_cwd", Arguments: "{}"},
+	}, reply.ToolCalls)
+}
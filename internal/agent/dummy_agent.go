@@ -1,11 +1,14 @@
 package agent
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+)
 
 // DummyAgent is a stub implementation of the Agent interface.
 type DummyAgent struct{}
 
-// Respond returns a fixed response for development/testing.
-func (d *DummyAgent) Respond(prompt string) (string, error) {
-	return fmt.Sprintf("Echo: %s", prompt), nil
+// Respond echoes the latest user message for development/testing.
+func (d *DummyAgent) Respond(_ context.Context, messages []Message) (string, error) {
+	return fmt.Sprintf("Echo: %s", LastUserMessage(messages)), nil
 }
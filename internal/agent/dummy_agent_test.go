@@ -1,13 +1,14 @@
 package agent
 
 import (
+	"context"
 	"testing"
 )
 
 func TestDummyAgent_Respond(t *testing.T) {
 	agent := &DummyAgent{}
 	prompt := "Hello, Agent!"
-	response, err := agent.Respond(prompt)
+	response, err := agent.Respond(context.Background(), UserPrompt(prompt))
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
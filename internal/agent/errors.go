@@ -0,0 +1,85 @@
+package agent
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kinds of provider failure. Use errors.Is to test an error returned by an
+// agent against them.
+var (
+	ErrRateLimited    = errors.New("rate limited")
+	ErrQuotaExceeded  = errors.New("quota exceeded")
+	ErrAuthFailed     = errors.New("authentication failed")
+	ErrContextTooLong = errors.New("context too long")
+	ErrUnavailable    = errors.New("provider unavailable")
+)
+
+// APIError is an error reported by an AI provider's API.
+type APIError struct {
+	Provider   string // e.g. "OpenAI"
+	StatusCode int    // HTTP status, or 0 for errors inside a successful response
+	Type       string // provider error type, e.g. "invalid_request_error"
+	Code       string // provider error code, e.g. "context_length_exceeded"
+	Message    string
+	RetryAfter time.Duration // from the Retry-After header, if any
+	Kind       error         // one of the Err* kinds, or nil
+}
+
+func (e *APIError) Error() string {
+	return e.Provider + " API error: " + e.Message
+}
+
+// Unwrap exposes the error kind to errors.Is.
+func (e *APIError) Unwrap() error {
+	return e.Kind
+}
+
+// transientError marks a transport failure (e.g. a dropped connection) that is
+// worth retrying, keeping its message.
+type transientError struct{ error }
+
+func (e transientError) Unwrap() error { return ErrUnavailable }
+
+// classify fills in the kind of an API error from its status and error code.
+func (e *APIError) classify() *APIError {
+	text := strings.ToLower(e.Code + " " + e.Type + " " + e.Message)
+	switch {
+	case e.Code == "insufficient_quota":
+		e.Kind = ErrQuotaExceeded
+	case e.StatusCode == http.StatusTooManyRequests || strings.Contains(text, "rate_limit"):
+		e.Kind = ErrRateLimited
+	case e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden ||
+		strings.Contains(text, "invalid_api_key") || strings.Contains(text, "authentication_error"):
+		e.Kind = ErrAuthFailed
+	case e.StatusCode == http.StatusRequestEntityTooLarge || strings.Contains(text, "context_length_exceeded") ||
+		strings.Contains(text, "prompt is too long") || strings.Contains(text, "maximum context length"):
+		e.Kind = ErrContextTooLong
+	case e.StatusCode >= 500 || strings.Contains(text, "overloaded"):
+		e.Kind = ErrUnavailable
+	}
+	return e
+}
+
+// retryAfter reads how long the server asked us to wait before retrying.
+func retryAfter(h http.Header) time.Duration {
+	if ms, err := strconv.Atoi(h.Get("retry-after-ms")); err == nil && ms > 0 {
+		return time.Duration(ms) * time.Millisecond
+	}
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if at, err := http.ParseTime(v); err == nil {
+		if d := time.Until(at); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
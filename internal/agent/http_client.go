@@ -0,0 +1,47 @@
+package agent
+
+import (
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/binks-cli/binks/internal/cassette"
+)
+
+var (
+	cassetteMu sync.Mutex
+	// cassettes are shared by every agent in the process, so that one
+	// cassette holds all the requests of a session.
+	cassettes = map[string]http.RoundTripper{}
+)
+
+// newHTTPClient returns the HTTP client for an agent. When BINKS_CASSETTE names
+// a cassette file, requests are recorded to it or replayed from it
+// (BINKS_CASSETTE_MODE=record|replay); a cassette that cannot be opened fails
+// every request rather than falling back to the network.
+func newHTTPClient() *http.Client {
+	path := os.Getenv("BINKS_CASSETTE")
+	if path == "" {
+		return &http.Client{}
+	}
+	key := path + "\x00" + os.Getenv("BINKS_CASSETTE_MODE")
+	cassetteMu.Lock()
+	defer cassetteMu.Unlock()
+	transport, ok := cassettes[key]
+	if !ok {
+		rec, err := cassette.FromEnv()
+		if err != nil {
+			transport = failingTransport{err}
+		} else {
+			transport = rec
+		}
+		cassettes[key] = transport
+	}
+	return &http.Client{Transport: transport}
+}
+
+type failingTransport struct{ err error }
+
+func (t failingTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, t.err
+}
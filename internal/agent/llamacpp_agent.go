@@ -0,0 +1,95 @@
+package agent
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// LlamaCppAgent implements the Agent interface against a local llama.cpp
+// server (llama-server) through its OpenAI-compatible API. Function calling is
+// not offered, since it depends on how the server was started; suggestions use
+// the code-block confirmation flow instead.
+type LlamaCppAgent struct {
+	APIKey      string // only needed for servers started with --api-key
+	Model       string
+	BaseURL     string
+	Temperature *float64      // nil uses the server's default
+	MaxTokens   int           // 0 leaves the reply length to the server
+	Timeout     time.Duration // per request; 0 means localRequestTimeout
+	Client      interface {
+		Do(req *http.Request) (*http.Response, error)
+	}
+
+	usage Usage // usage of the last successful request
+}
+
+// NewLlamaCppAgent creates a new LlamaCppAgent, reading config from environment variables.
+func NewLlamaCppAgent() *LlamaCppAgent {
+	base := os.Getenv("LLAMACPP_API_BASE")
+	if base == "" {
+		base = "http://localhost:8080/v1"
+	}
+	return &LlamaCppAgent{
+		APIKey:  os.Getenv("LLAMACPP_API_KEY"),
+		Model:   os.Getenv("LLAMACPP_MODEL"), // llama-server serves whichever model it loaded
+		BaseURL: strings.TrimRight(base, "/"),
+		Timeout: localRequestTimeout,
+		Client:  newHTTPClient(),
+	}
+}
+
+func (a *LlamaCppAgent) openAI() *OpenAIAgent {
+	return &OpenAIAgent{
+		APIKey:      a.APIKey,
+		Model:       a.Model,
+		BaseURL:     a.BaseURL,
+		Temperature: a.Temperature,
+		MaxTokens:   a.MaxTokens,
+		Timeout:     localTimeoutOr(a.Timeout),
+		Client:      a.Client,
+		keyOptional: true,
+	}
+}
+
+// Respond sends the conversation to the llama.cpp server and returns the reply.
+func (a *LlamaCppAgent) Respond(ctx context.Context, messages []Message) (string, error) {
+	oa := a.openAI()
+	reply, err := oa.Respond(ctx, toolFreeMessages(messages))
+	a.usage = oa.usage
+	return reply, err
+}
+
+// RespondStream streams the reply, calling onToken for every content delta.
+func (a *LlamaCppAgent) RespondStream(ctx context.Context, messages []Message, onToken func(string)) (string, error) {
+	oa := a.openAI()
+	reply, err := oa.RespondStream(ctx, toolFreeMessages(messages), onToken)
+	a.usage = oa.usage
+	return reply, err
+}
+
+// LastUsage reports the tokens used by the last successful request.
+func (a *LlamaCppAgent) LastUsage() Usage {
+	return a.usage
+}
+
+// ListModels returns the models the server has loaded.
+func (a *LlamaCppAgent) ListModels(ctx context.Context) ([]string, error) {
+	return a.openAI().ListModels(ctx)
+}
+
+// toolFreeMessages turns tool results into user turns for servers without
+// function calling.
+func toolFreeMessages(messages []Message) []Message {
+	out := make([]Message, 0, len(messages))
+	for _, m := range messages {
+		if m.Role == RoleTool {
+			m.Role = RoleUser
+		}
+		m.ToolCalls, m.ToolCallID = nil, ""
+		out = append(out, m)
+	}
+	return out
+}
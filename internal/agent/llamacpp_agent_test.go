@@ -0,0 +1,63 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLlamaCppAgent_RespondWithoutKey(t *testing.T) {
+	var got openAIRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/chat/completions", r.URL.Path)
+		assert.Empty(t, r.Header.Get("Authorization"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		_, _ = io.WriteString(w, `{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`)
+	}))
+	defer srv.Close()
+	a := NewLlamaCppAgent()
+	a.APIKey = ""
+	a.BaseURL = srv.URL + "/v1"
+
+	resp, err := a.Respond(context.Background(), []Message{
+		{Role: RoleAssistant, ToolCalls: []ToolCall{{ID: "c1", Name: "list_dir"}}},
+		{Role: RoleTool, ToolCallID: "c1", Content: "a.txt"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "ok", resp)
+	assert.Empty(t, got.Tools)
+	assert.Equal(t, []openAIMessage{{Role: RoleAssistant}, {Role: RoleUser, Content: "a.txt"}}, got.Messages)
+}
+
+func TestLlamaCppAgent_ListModels(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/models", r.URL.Path)
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		_, _ = io.WriteString(w, `{"object":"list","data":[{"id":"qwen2.5-7b-instruct-q4_k_m.gguf"}]}`)
+	}))
+	defer srv.Close()
+	a := &LlamaCppAgent{APIKey: "secret", BaseURL: srv.URL + "/v1", Client: srv.Client()}
+	models, err := a.ListModels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"qwen2.5-7b-instruct-q4_k_m.gguf"}, models)
+}
+
+func TestLlamaCppAgent_IsNotToolCalling(t *testing.T) {
+	var a Agent = NewLlamaCppAgent()
+	_, ok := a.(ToolCallingAgent)
+	assert.False(t, ok, "local servers use the code-block confirmation flow")
+	_, ok = a.(StreamingAgent)
+	assert.True(t, ok)
+}
+
+func TestLlamaCppAgent_ZeroTimeoutIsLocal(t *testing.T) {
+	assert.Equal(t, localRequestTimeout, (&LlamaCppAgent{}).openAI().Timeout)
+	assert.Equal(t, time.Minute, (&LlamaCppAgent{Timeout: time.Minute}).openAI().Timeout)
+}
@@ -1,6 +1,9 @@
 package agent
 
-import "errors"
+import (
+	"context"
+	"errors"
+)
 
 // AgentResult represents a mock response from the agent.
 type AgentResult struct {
@@ -8,13 +11,15 @@ type AgentResult struct {
 	Err    error
 }
 
-// MockAgent implements Agent and returns predefined responses for prompts.
+// MockAgent implements Agent and returns predefined responses keyed by the
+// latest user message.
 type MockAgent struct {
 	Responses map[string]AgentResult
 	Default   AgentResult // fallback if prompt not found
 }
 
-func (m *MockAgent) Respond(prompt string) (string, error) {
+func (m *MockAgent) Respond(_ context.Context, messages []Message) (string, error) {
+	prompt := LastUserMessage(messages)
 	if m.Responses == nil {
 		return m.Default.Output, m.Default.Err
 	}
@@ -24,6 +29,14 @@ func (m *MockAgent) Respond(prompt string) (string, error) {
 	return m.Default.Output, m.Default.Err
 }
 
+// RespondStructured returns the same responses as Respond, so a mock answer
+// written as a JSON Suggestion exercises the structured path and any other
+// answer the markdown fallback.
+func (m *MockAgent) RespondStructured(ctx context.Context, messages []Message, _ []Tool, _ func(string)) (Reply, error) {
+	out, err := m.Respond(ctx, messages)
+	return Reply{Content: out}, err
+}
+
 // NewMockAgent creates a MockAgent with the given responses and optional default.
 func NewMockAgent(responses map[string]AgentResult, defaultResult ...AgentResult) *MockAgent {
 	var def AgentResult
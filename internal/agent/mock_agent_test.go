@@ -1,6 +1,7 @@
 package agent
 
 import (
+	"context"
 	"errors"
 	"testing"
 
@@ -16,19 +17,29 @@ func TestMockAgent_Respond(t *testing.T) {
 	mock := NewMockAgent(responses, AgentResult{"default", nil})
 
 	t.Run("returns mapped response", func(t *testing.T) {
-		out, err := mock.Respond("foo")
+		out, err := mock.Respond(context.Background(), UserPrompt("foo"))
 		assert.NoError(t, err)
 		assert.Equal(t, "echo hi", out)
 	})
 	t.Run("returns mapped error", func(t *testing.T) {
-		out, err := mock.Respond("baz")
+		out, err := mock.Respond(context.Background(), UserPrompt("baz"))
 		assert.Error(t, err)
 		assert.Equal(t, "API timeout", err.Error())
 		assert.Equal(t, "", out)
 	})
 	t.Run("returns default for unknown", func(t *testing.T) {
-		out, err := mock.Respond("unknown")
+		out, err := mock.Respond(context.Background(), UserPrompt("unknown"))
 		assert.NoError(t, err)
 		assert.Equal(t, "default", out)
 	})
 }
+
+func TestMockAgent_RespondStructured(t *testing.T) {
+	mock := NewMockAgent(nil, AgentResult{Output: `{"explanation":"hi","commands":[]}`})
+	var sa StructuredAgent = mock
+	reply, err := sa.RespondStructured(context.Background(), UserPrompt("x"), nil, nil)
+	assert.NoError(t, err)
+	sugg, ok := ParseSuggestion(reply.Content)
+	assert.True(t, ok)
+	assert.Equal(t, "hi", sugg.Explanation)
+}
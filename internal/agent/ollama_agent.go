@@ -0,0 +1,230 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// localRequestTimeout bounds requests to local model servers, which are often
+// much slower than hosted APIs.
+const localRequestTimeout = 2 * time.Minute
+
+// localTimeoutOr returns the request timeout to use for a local agent's
+// Timeout setting.
+func localTimeoutOr(d time.Duration) time.Duration {
+	if d > 0 {
+		return d
+	}
+	return localRequestTimeout
+}
+
+// OllamaAgent implements the Agent interface against a local Ollama server's
+// /api/chat endpoint, so prompts never leave the machine.
+type OllamaAgent struct {
+	Model       string
+	BaseURL     string
+	Temperature *float64      // nil uses the model's default
+	MaxTokens   int           // 0 leaves the reply length to the model
+	Timeout     time.Duration // per request; 0 means localRequestTimeout
+	Client      interface {
+		Do(req *http.Request) (*http.Response, error)
+	}
+
+	usage Usage // usage of the last successful request
+}
+
+// NewOllamaAgent creates a new OllamaAgent, reading config from environment
+// variables. OLLAMA_HOST follows Ollama's own convention and may omit the scheme.
+func NewOllamaAgent() *OllamaAgent {
+	model := os.Getenv("OLLAMA_MODEL")
+	if model == "" {
+		model = "llama3.2"
+	}
+	base := os.Getenv("OLLAMA_HOST")
+	if base == "" {
+		base = "http://localhost:11434"
+	} else if !strings.Contains(base, "://") {
+		base = "http://" + base
+	}
+	return &OllamaAgent{
+		Model:   model,
+		BaseURL: strings.TrimRight(base, "/"),
+		Timeout: localRequestTimeout,
+		Client:  newHTTPClient(),
+	}
+}
+
+type ollamaMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type ollamaRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Stream   bool            `json:"stream"`
+	Options  *ollamaOptions  `json:"options,omitempty"`
+}
+
+type ollamaOptions struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	NumPredict  int      `json:"num_predict,omitempty"`
+}
+
+// ollamaResponse is a whole reply, or one line of a streamed reply.
+type ollamaResponse struct {
+	Model   string        `json:"model"`
+	Message ollamaMessage `json:"message"`
+	Done    bool          `json:"done"`
+	Error   string        `json:"error,omitempty"`
+	// Token counts, sent with the final chunk.
+	PromptEvalCount int `json:"prompt_eval_count"`
+	EvalCount       int `json:"eval_count"`
+}
+
+type ollamaTags struct {
+	Models []struct {
+		Name string `json:"name"`
+	} `json:"models"`
+}
+
+// Respond sends the conversation to Ollama and returns the reply.
+func (a *OllamaAgent) Respond(ctx context.Context, messages []Message) (string, error) {
+	return a.chat(ctx, messages, nil)
+}
+
+// RespondStream streams the reply, calling onToken for every chunk as it
+// arrives. It returns the full reply once the stream ends.
+func (a *OllamaAgent) RespondStream(ctx context.Context, messages []Message, onToken func(string)) (string, error) {
+	if onToken == nil {
+		onToken = func(string) {}
+	}
+	return a.chat(ctx, messages, onToken)
+}
+
+// LastUsage reports the tokens used by the last successful request.
+func (a *OllamaAgent) LastUsage() Usage {
+	return a.usage
+}
+
+// ListModels returns the models pulled into the Ollama server.
+func (a *OllamaAgent) ListModels(ctx context.Context) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, localTimeoutOr(a.Timeout))
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", a.BaseURL+"/api/tags", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := a.Client.Do(req)
+	if err != nil {
+		return nil, a.requestError(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, a.requestError(err)
+	}
+	var tags ollamaTags
+	if err := json.Unmarshal(body, &tags); err != nil {
+		return nil, errors.New("AI error: failed to parse response")
+	}
+	models := make([]string, 0, len(tags.Models))
+	for _, m := range tags.Models {
+		models = append(models, m.Name)
+	}
+	return models, nil
+}
+
+// chat performs one /api/chat call, streaming when onToken is non-nil.
+func (a *OllamaAgent) chat(ctx context.Context, messages []Message, onToken func(string)) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, localTimeoutOr(a.Timeout))
+	defer cancel()
+	a.usage = Usage{}
+	debug := os.Getenv("BINKS_DEBUG_AI") == "1"
+	if debug {
+		fmt.Fprintf(os.Stderr, "[OllamaAgent] Received %d messages, prompt: %q\n", len(messages), Redact(LastUserMessage(messages)))
+	}
+	payload := ollamaRequest{Model: a.Model, Stream: onToken != nil}
+	if a.Temperature != nil || a.MaxTokens > 0 {
+		payload.Options = &ollamaOptions{Temperature: a.Temperature, NumPredict: a.MaxTokens}
+	}
+	for _, m := range toolFreeMessages(redactMessages(messages)) {
+		payload.Messages = append(payload.Messages, ollamaMessage{Role: m.Role, Content: m.Content})
+	}
+	url := a.BaseURL + "/api/chat"
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	if debug {
+		fmt.Fprintf(os.Stderr, "[OllamaAgent] Sending request to %s: %s\n", url, string(body))
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := a.Client.Do(req)
+	if err != nil {
+		return "", a.requestError(err)
+	}
+	defer resp.Body.Close()
+
+	// Streamed replies are newline-delimited JSON objects; a non-streamed reply
+	// (or an error) is a single one.
+	var content strings.Builder
+	var usage Usage
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if debug {
+			fmt.Fprintf(os.Stderr, "[OllamaAgent] Response line: %s\n", line)
+		}
+		var chunk ollamaResponse
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return "", errors.New("AI error: failed to parse response")
+		}
+		if chunk.Error != "" {
+			return "", errors.New("Ollama error: " + chunk.Error)
+		}
+		if chunk.Message.Content != "" {
+			content.WriteString(chunk.Message.Content)
+			if onToken != nil {
+				onToken(chunk.Message.Content)
+			}
+		}
+		if chunk.Done {
+			usage = Usage{Model: chunk.Model, PromptTokens: chunk.PromptEvalCount, CompletionTokens: chunk.EvalCount}
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", a.requestError(err)
+	}
+	if content.Len() == 0 {
+		return "", errors.New("AI error: no response from model")
+	}
+	a.usage = usage
+	return strings.TrimRight(content.String(), "\n\r "), nil
+}
+
+// requestError adds a hint when the local server is not running.
+func (a *OllamaAgent) requestError(err error) error {
+	if strings.Contains(err.Error(), "connection refused") {
+		return fmt.Errorf("AI error: cannot reach Ollama at %s (is `ollama serve` running?)", a.BaseURL)
+	}
+	return requestError(err)
+}
@@ -0,0 +1,121 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ollamaStandIn(t *testing.T, handler http.HandlerFunc) *OllamaAgent {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	a := NewOllamaAgent()
+	a.BaseURL = srv.URL
+	a.Model = "llama-test"
+	return a
+}
+
+func TestNewOllamaAgent_Host(t *testing.T) {
+	t.Setenv("OLLAMA_HOST", "127.0.0.1:11500")
+	assert.Equal(t, "http://127.0.0.1:11500", NewOllamaAgent().BaseURL)
+	t.Setenv("OLLAMA_HOST", "https://gpu-box:11434/")
+	assert.Equal(t, "https://gpu-box:11434", NewOllamaAgent().BaseURL)
+}
+
+func TestOllamaAgent_Respond(t *testing.T) {
+	var got ollamaRequest
+	a := ollamaStandIn(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/chat", r.URL.Path)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		_, _ = io.WriteString(w, `{"message":{"role":"assistant","content":"Use this:\n`+"```bash\\nls -la\\n```"+`\n"},"done":true}`)
+	})
+	resp, err := a.Respond(context.Background(), []Message{
+		{Role: RoleSystem, Content: "sys"},
+		{Role: RoleUser, Content: "list files"},
+		{Role: RoleTool, Content: "result", ToolCallID: "c1"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Use this:\n```bash\nls -la\n```", resp)
+	assert.False(t, got.Stream)
+	assert.Equal(t, "llama-test", got.Model)
+	assert.Equal(t, []ollamaMessage{
+		{Role: RoleSystem, Content: "sys"},
+		{Role: RoleUser, Content: "list files"},
+		{Role: RoleUser, Content: "result"},
+	}, got.Messages)
+}
+
+func TestOllamaAgent_RespondStream(t *testing.T) {
+	a := ollamaStandIn(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		_, _ = io.WriteString(w, `{"message":{"role":"assistant","content":"Hel"},"done":false}`+"\n"+
+			`{"message":{"role":"assistant","content":"lo"},"done":false}`+"\n"+
+			`{"model":"llama-test","message":{"role":"assistant","content":""},"done":true,"prompt_eval_count":30,"eval_count":2}`+"\n")
+	})
+	var tokens []string
+	resp, err := a.RespondStream(context.Background(), UserPrompt("hi"), func(tok string) { tokens = append(tokens, tok) })
+	require.NoError(t, err)
+	assert.Equal(t, "Hello", resp)
+	assert.Equal(t, []string{"Hel", "lo"}, tokens)
+	assert.Equal(t, Usage{Model: "llama-test", PromptTokens: 30, CompletionTokens: 2}, a.LastUsage())
+}
+
+func TestOllamaAgent_Errors(t *testing.T) {
+	a := ollamaStandIn(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = io.WriteString(w, `{"error":"model \"llama-test\" not found, try pulling it first"}`)
+	})
+	_, err := a.Respond(context.Background(), UserPrompt("hi"))
+	assert.EqualError(t, err, `Ollama error: model "llama-test" not found, try pulling it first`)
+
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close() // nothing listens here any more
+	a.BaseURL = srv.URL
+	_, err = a.RespondStream(context.Background(), UserPrompt("hi"), nil)
+	assert.EqualError(t, err, "AI error: cannot reach Ollama at "+srv.URL+" (is `ollama serve` running?)")
+}
+
+func TestOllamaAgent_ListModels(t *testing.T) {
+	a := ollamaStandIn(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/tags", r.URL.Path)
+		_, _ = io.WriteString(w, `{"models":[{"name":"llama3.2:latest"},{"name":"qwen2.5-coder:7b"}]}`)
+	})
+	models, err := a.ListModels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"llama3.2:latest", "qwen2.5-coder:7b"}, models)
+}
+
+func TestOllamaAgent_Options(t *testing.T) {
+	var raw map[string]any
+	a := ollamaStandIn(t, func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&raw))
+		_, _ = io.WriteString(w, `{"message":{"content":"ok"},"done":true}`)
+	})
+	temp := 0.5
+	a.Temperature, a.MaxTokens = &temp, 200
+	_, err := a.Respond(context.Background(), UserPrompt("hi"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"temperature": 0.5, "num_predict": float64(200)}, raw["options"])
+}
+
+func TestOllamaAgent_ZeroTimeoutIsLocal(t *testing.T) {
+	var left time.Duration
+	a := &OllamaAgent{Model: "m", BaseURL: "http://ollama.test", Client: &fakeHTTPClient{DoFunc: func(req *http.Request) (*http.Response, error) {
+		deadline, ok := req.Context().Deadline()
+		require.True(t, ok)
+		left = time.Until(deadline)
+		return nil, errors.New("offline")
+	}}}
+	_, err := a.Respond(context.Background(), []Message{{Role: RoleUser, Content: "hi"}})
+	require.Error(t, err)
+	assert.Greater(t, left, requestTimeout, "a local server gets the local timeout")
+}
@@ -13,14 +13,29 @@ import (
 	"time"
 )
 
+// requestTimeout bounds a single request to a hosted AI provider unless the
+// agent sets its own Timeout.
+const requestTimeout = 15 * time.Second
+
 // OpenAIAgent implements the Agent interface using OpenAI's API.
 type OpenAIAgent struct {
-	APIKey  string
-	Model   string
-	BaseURL string
-	Client  interface {
+	APIKey      string
+	Model       string
+	BaseURL     string
+	Temperature *float64      // nil uses the model's default
+	MaxTokens   int           // 0 leaves the reply length to the model
+	Timeout     time.Duration // per request; 0 means requestTimeout
+	Retry       RetryPolicy
+	// Structured asks for answers as a JSON Suggestion in RespondStructured.
+	// nil does so only for models known to support structured outputs.
+	Structured *bool
+	Client     interface {
 		Do(req *http.Request) (*http.Response, error)
 	}
+
+	keyOptional bool                // local OpenAI-compatible servers need no key
+	sleep       func(time.Duration) // waits between retries; time.Sleep when nil
+	usage       Usage               // usage of the last successful request
 }
 
 // NewOpenAIAgent creates a new OpenAIAgent, reading config from environment variables.
@@ -38,84 +53,468 @@ func NewOpenAIAgent() *OpenAIAgent {
 		APIKey:  key,
 		Model:   model,
 		BaseURL: base,
-		Client:  &http.Client{},
+		Retry:   DefaultRetryPolicy,
+		Client:  newHTTPClient(),
 	}
 }
 
 type openAIMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role       string           `json:"role"`
+	Content    string           `json:"content"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+type openAIToolCall struct {
+	ID       string             `json:"id"`
+	Type     string             `json:"type"`
+	Function openAIFunctionCall `json:"function"`
+}
+
+type openAIFunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+type openAITool struct {
+	Type     string         `json:"type"`
+	Function openAIFunction `json:"function"`
+}
+
+type openAIFunction struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Parameters  map[string]any `json:"parameters,omitempty"`
 }
 
 type openAIRequest struct {
-	Model     string          `json:"model"`
-	Messages  []openAIMessage `json:"messages"`
-	MaxTokens int             `json:"max_tokens,omitempty"`
+	Model       string          `json:"model"`
+	Messages    []openAIMessage `json:"messages"`
+	MaxTokens   int             `json:"max_tokens,omitempty"`
+	Temperature *float64        `json:"temperature,omitempty"`
+	Stream      bool            `json:"stream,omitempty"`
+	// StreamOptions asks for a final chunk reporting token usage.
+	StreamOptions  *openAIStreamOptions  `json:"stream_options,omitempty"`
+	Tools          []openAITool          `json:"tools,omitempty"`
+	ResponseFormat *openAIResponseFormat `json:"response_format,omitempty"`
+}
+
+// openAIResponseFormat constrains the reply to a JSON schema.
+type openAIResponseFormat struct {
+	Type       string           `json:"type"`
+	JSONSchema openAIJSONSchema `json:"json_schema"`
+}
+
+type openAIJSONSchema struct {
+	Name   string         `json:"name"`
+	Strict bool           `json:"strict"`
+	Schema map[string]any `json:"schema"`
+}
+
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// openAIUsage is the token count of a chat completion.
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
 }
 
 type openAIResponse struct {
+	Model   string `json:"model"`
 	Choices []struct {
 		Message openAIMessage `json:"message"`
 	} `json:"choices"`
+	Usage *openAIUsage `json:"usage,omitempty"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// openAIErrorResponse is the body of a failed request.
+type openAIErrorResponse struct {
+	Error *struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+		Code    any    `json:"code"` // a string, but some compatible servers send a number
+	} `json:"error"`
+}
+
+// openAIStreamChunk is a single server-sent event of a streamed chat completion.
+type openAIStreamChunk struct {
+	Model   string       `json:"model"`
+	Usage   *openAIUsage `json:"usage,omitempty"`
+	Choices []struct {
+		Delta struct {
+			Content   string `json:"content"`
+			ToolCalls []struct {
+				Index    int                `json:"index"`
+				ID       string             `json:"id"`
+				Function openAIFunctionCall `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+type openAIModelList struct {
+	Data []struct {
+		ID string `json:"id"`
+	} `json:"data"`
 	Error *struct {
 		Message string `json:"message"`
 	} `json:"error,omitempty"`
 }
 
-// Respond sends the prompt to OpenAI and returns the reply.
-func (a *OpenAIAgent) Respond(prompt string) (string, error) {
+// Respond sends the conversation to OpenAI and returns the reply.
+func (a *OpenAIAgent) Respond(ctx context.Context, messages []Message) (string, error) {
+	reply, err := a.complete(ctx, messages, nil, nil)
+	return reply.Content, err
+}
+
+// RespondStream sends the conversation with streaming enabled and calls onToken
+// for every content delta as it arrives. It returns the full reply once the stream ends.
+func (a *OpenAIAgent) RespondStream(ctx context.Context, messages []Message, onToken func(string)) (string, error) {
+	if onToken == nil {
+		onToken = func(string) {}
+	}
+	reply, err := a.complete(ctx, messages, nil, onToken)
+	return reply.Content, err
+}
+
+// LastUsage reports the tokens used by the last successful request.
+func (a *OpenAIAgent) LastUsage() Usage {
+	return a.usage
+}
+
+// RespondWithTools offers tools to the model using OpenAI function calling.
+func (a *OpenAIAgent) RespondWithTools(ctx context.Context, messages []Message, tools []Tool, onToken func(string)) (Reply, error) {
+	return a.complete(ctx, messages, tools, onToken)
+}
+
+// RespondStructured asks for a Suggestion using a JSON schema response format
+// when the model supports structured outputs, and for a plain, possibly
+// streamed, answer otherwise.
+func (a *OpenAIAgent) RespondStructured(ctx context.Context, messages []Message, tools []Tool, onToken func(string)) (Reply, error) {
+	if !a.structured() {
+		return a.complete(ctx, messages, tools, onToken)
+	}
+	payload := a.request(messages, tools, false)
+	payload.ResponseFormat = &openAIResponseFormat{
+		Type:       "json_schema",
+		JSONSchema: openAIJSONSchema{Name: "command_suggestion", Strict: true, Schema: SuggestionSchema},
+	}
+	return a.run(ctx, messages, payload, nil)
+}
+
+// structuredModels are prefixes of the models that accept a JSON schema
+// response format.
+var structuredModels = []string{"gpt-4o", "gpt-4.1", "gpt-5", "o1", "o3", "o4"}
+
+func (a *OpenAIAgent) structured() bool {
+	if a.Structured != nil {
+		return *a.Structured
+	}
+	for _, prefix := range structuredModels {
+		if strings.HasPrefix(a.Model, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// complete performs one chat completion, streaming when onToken is non-nil.
+func (a *OpenAIAgent) complete(ctx context.Context, messages []Message, tools []Tool, onToken func(string)) (Reply, error) {
+	return a.run(ctx, messages, a.request(messages, tools, onToken != nil), onToken)
+}
+
+// request builds the body of a chat completion request.
+func (a *OpenAIAgent) request(messages []Message, tools []Tool, stream bool) openAIRequest {
+	payload := openAIRequest{
+		Model:       a.Model,
+		Messages:    toOpenAIMessages(redactMessages(messages)),
+		MaxTokens:   a.MaxTokens,
+		Temperature: a.Temperature,
+		Stream:      stream,
+	}
+	if payload.Stream {
+		payload.StreamOptions = &openAIStreamOptions{IncludeUsage: true}
+	}
+	for _, t := range tools {
+		payload.Tools = append(payload.Tools, openAITool{
+			Type:     "function",
+			Function: openAIFunction{Name: t.Name, Description: t.Description, Parameters: t.Parameters},
+		})
+	}
+	return payload
+}
+
+// run sends a chat completion request, streaming when onToken is non-nil.
+// Transient failures are retried according to a.Retry.
+func (a *OpenAIAgent) run(ctx context.Context, messages []Message, payload openAIRequest, onToken func(string)) (Reply, error) {
+	var reply Reply
+	onToken, check := streamOnce(onToken)
+	a.usage = Usage{}
+	err := a.Retry.do(ctx, a.sleep, func() error {
+		var err error
+		reply, err = a.attempt(ctx, messages, payload, onToken)
+		return check(err)
+	})
+	if err == nil {
+		a.usage = reply.Usage
+	}
+	return reply, err
+}
+
+// attempt sends one chat completion request and reads its reply.
+func (a *OpenAIAgent) attempt(ctx context.Context, messages []Message, payload openAIRequest, onToken func(string)) (Reply, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeoutOr(a.Timeout))
+	defer cancel()
+	resp, err := a.send(ctx, messages, payload)
+	if err != nil {
+		return Reply{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return Reply{}, openAIStatusError(resp)
+	}
+	if onToken == nil {
+		return a.readResponse(resp.Body)
+	}
+	if !strings.Contains(resp.Header.Get("Content-Type"), "text/event-stream") {
+		// Errors (and servers that ignore "stream") come back as a plain JSON body.
+		reply, err := a.readResponse(resp.Body)
+		if err == nil && reply.Content != "" {
+			onToken(reply.Content)
+		}
+		return reply, err
+	}
+	return a.readStream(resp.Body, onToken)
+}
+
+// send encodes the chat completion request and performs it.
+func (a *OpenAIAgent) send(ctx context.Context, messages []Message, payload openAIRequest) (*http.Response, error) {
 	debug := os.Getenv("BINKS_DEBUG_AI") == "1"
 	if debug {
-		fmt.Fprintf(os.Stderr, "[OpenAIAgent] Received prompt: %q\n", prompt)
+		fmt.Fprintf(os.Stderr, "[OpenAIAgent] Received %d messages, prompt: %q\n", len(messages), Redact(LastUserMessage(messages)))
 	}
-	if a.APIKey == "" {
-		return "", errors.New("AI is not configured. Set OPENAI_API_KEY environment variable")
+	if a.APIKey == "" && !a.keyOptional {
+		return nil, errors.New("AI is not configured. Set OPENAI_API_KEY environment variable")
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
-	defer cancel()
 	url := a.BaseURL + "/chat/completions"
-	payload := openAIRequest{
-		Model:    a.Model,
-		Messages: []openAIMessage{{Role: "user", Content: prompt}},
-	}
 	body, err := json.Marshal(payload)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	if debug {
 		fmt.Fprintf(os.Stderr, "[OpenAIAgent] Sending request to %s: %s\n", url, string(body))
 	}
 	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	if a.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+a.APIKey)
 	}
-	req.Header.Set("Authorization", "Bearer "+a.APIKey)
 	req.Header.Set("Content-Type", "application/json")
+	if payload.Stream {
+		req.Header.Set("Accept", "text/event-stream")
+	}
 	resp, err := a.Client.Do(req)
 	if err != nil {
-		if errors.Is(err, context.DeadlineExceeded) || strings.Contains(err.Error(), "context deadline exceeded") {
-			return "", errors.New("AI request timed out")
+		return nil, transportError(err)
+	}
+	return resp, nil
+}
+
+// openAIStatusError builds a typed error from a failed response.
+func openAIStatusError(resp *http.Response) error {
+	apiErr := &APIError{Provider: "OpenAI", StatusCode: resp.StatusCode, RetryAfter: retryAfter(resp.Header)}
+	body, _ := io.ReadAll(resp.Body)
+	if os.Getenv("BINKS_DEBUG_AI") == "1" {
+		fmt.Fprintf(os.Stderr, "[OpenAIAgent] Error response (%d): %s\n", resp.StatusCode, string(body))
+	}
+	var envelope openAIErrorResponse
+	if err := json.Unmarshal(body, &envelope); err == nil && envelope.Error != nil {
+		apiErr.Message = envelope.Error.Message
+		apiErr.Type = envelope.Error.Type
+		if envelope.Error.Code != nil {
+			apiErr.Code = fmt.Sprint(envelope.Error.Code)
 		}
-		return "", errors.New("AI error: " + err.Error())
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = fmt.Sprintf("%d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	return apiErr.classify()
+}
+
+// ListModels returns the models the API offers, from its /models endpoint.
+func (a *OpenAIAgent) ListModels(ctx context.Context) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeoutOr(a.Timeout))
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", a.BaseURL+"/models", nil)
+	if err != nil {
+		return nil, err
+	}
+	if a.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+a.APIKey)
+	}
+	resp, err := a.Client.Do(req)
+	if err != nil {
+		return nil, requestError(err)
 	}
 	defer resp.Body.Close()
-	respBody, err := io.ReadAll(resp.Body)
+	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", err
+		return nil, requestError(err)
 	}
-	if debug {
+	var list openAIModelList
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, errors.New("AI error: failed to parse response")
+	}
+	if list.Error != nil {
+		return nil, (&APIError{Provider: "OpenAI", Message: list.Error.Message}).classify()
+	}
+	models := make([]string, 0, len(list.Data))
+	for _, m := range list.Data {
+		models = append(models, m.ID)
+	}
+	return models, nil
+}
+
+// toOpenAIMessages converts a conversation to the chat completion wire format.
+func toOpenAIMessages(messages []Message) []openAIMessage {
+	out := make([]openAIMessage, 0, len(messages))
+	for _, m := range messages {
+		om := openAIMessage{Role: m.Role, Content: m.Content, ToolCallID: m.ToolCallID}
+		for _, tc := range m.ToolCalls {
+			om.ToolCalls = append(om.ToolCalls, openAIToolCall{
+				ID:       tc.ID,
+				Type:     "function",
+				Function: openAIFunctionCall{Name: tc.Name, Arguments: tc.Arguments},
+			})
+		}
+		out = append(out, om)
+	}
+	return out
+}
+
+// readResponse parses a non-streamed chat completion body.
+func (a *OpenAIAgent) readResponse(r io.Reader) (Reply, error) {
+	respBody, err := io.ReadAll(r)
+	if err != nil {
+		return Reply{}, requestError(err)
+	}
+	if os.Getenv("BINKS_DEBUG_AI") == "1" {
 		fmt.Fprintf(os.Stderr, "[OpenAIAgent] Raw response: %s\n", string(respBody))
 	}
 	var aiResp openAIResponse
 	if err := json.Unmarshal(respBody, &aiResp); err != nil {
-		return "", errors.New("AI error: failed to parse response")
+		return Reply{}, errors.New("AI error: failed to parse response")
 	}
 	if aiResp.Error != nil {
-		return "", errors.New("OpenAI API error: " + aiResp.Error.Message)
+		return Reply{}, (&APIError{Provider: "OpenAI", Message: aiResp.Error.Message}).classify()
 	}
 	if len(aiResp.Choices) == 0 {
-		return "", errors.New("AI error: no response from model")
+		return Reply{}, errors.New("AI error: no response from model")
+	}
+	msg := aiResp.Choices[0].Message
+	reply := Reply{Content: strings.TrimRight(msg.Content, "\n\r "), Usage: aiResp.Usage.usage(aiResp.Model)}
+	for _, tc := range msg.ToolCalls {
+		reply.ToolCalls = append(reply.ToolCalls, ToolCall{ID: tc.ID, Name: tc.Function.Name, Arguments: tc.Function.Arguments})
+	}
+	return reply, nil
+}
+
+// readStream accumulates a streamed chat completion, forwarding text deltas to
+// onToken and stitching tool call fragments back together by index.
+func (a *OpenAIAgent) readStream(r io.Reader, onToken func(string)) (Reply, error) {
+	var content strings.Builder
+	var calls []ToolCall
+	var usage Usage
+	err := readSSE(r, func(data string) error {
+		if os.Getenv("BINKS_DEBUG_AI") == "1" {
+			fmt.Fprintf(os.Stderr, "[OpenAIAgent] Stream chunk: %s\n", data)
+		}
+		var chunk openAIStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return errors.New("AI error: failed to parse response")
+		}
+		if chunk.Error != nil {
+			return (&APIError{Provider: "OpenAI", Message: chunk.Error.Message}).classify()
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage.usage(chunk.Model)
+		}
+		if len(chunk.Choices) == 0 {
+			return nil
+		}
+		delta := chunk.Choices[0].Delta
+		for _, tc := range delta.ToolCalls {
+			for len(calls) <= tc.Index {
+				calls = append(calls, ToolCall{})
+			}
+			if tc.ID != "" {
+				calls[tc.Index].ID = tc.ID
+			}
+			calls[tc.Index].Name += tc.Function.Name
+			calls[tc.Index].Arguments += tc.Function.Arguments
+		}
+		if delta.Content != "" {
+			content.WriteString(delta.Content)
+			onToken(delta.Content)
+		}
+		return nil
+	})
+	if err != nil {
+		return Reply{}, requestError(err)
+	}
+	if content.Len() == 0 && len(calls) == 0 {
+		return Reply{}, errors.New("AI error: no response from model")
+	}
+	return Reply{Content: strings.TrimRight(content.String(), "\n\r "), ToolCalls: calls, Usage: usage}, nil
+}
+
+// usage converts reported token counts, which may be missing, to a Usage.
+func (u *openAIUsage) usage(model string) Usage {
+	if u == nil {
+		return Usage{}
+	}
+	return Usage{Model: model, PromptTokens: u.PromptTokens, CompletionTokens: u.CompletionTokens}
+}
+
+// timeoutOr returns the request timeout to use for an agent's Timeout setting.
+func timeoutOr(d time.Duration) time.Duration {
+	if d > 0 {
+		return d
+	}
+	return requestTimeout
+}
+
+// requestError maps transport errors to user-facing AI errors.
+func requestError(err error) error {
+	if errors.Is(err, context.Canceled) || strings.Contains(err.Error(), "context canceled") {
+		return errors.New("AI request cancelled")
+	}
+	if errors.Is(err, context.DeadlineExceeded) || strings.Contains(err.Error(), "context deadline exceeded") {
+		return errors.New("AI request timed out")
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) || strings.HasPrefix(err.Error(), "AI error") || strings.HasPrefix(err.Error(), "Ollama error") {
+		return err
+	}
+	return errors.New("AI error: " + err.Error())
+}
+
+// transportError maps a failure to send a request. Anything but a timeout
+// (e.g. a refused or dropped connection) is worth retrying.
+func transportError(err error) error {
+	err = requestError(err)
+	if err.Error() == "AI request timed out" || err.Error() == "AI request cancelled" {
+		return err
 	}
-	content := aiResp.Choices[0].Message.Content
-	return strings.TrimRight(content, "\n\r "), nil
+	return transientError{err}
 }
@@ -6,6 +6,8 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -37,7 +39,7 @@ func TestOpenAIAgent_Respond_Success(t *testing.T) {
 			}, nil
 		},
 	}
-	resp, err := agent.Respond("Hi")
+	resp, err := agent.Respond(context.Background(), UserPrompt("Hi"))
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -49,7 +51,7 @@ func TestOpenAIAgent_Respond_Success(t *testing.T) {
 func TestOpenAIAgent_Respond_NoAPIKey(t *testing.T) {
 	agent := NewOpenAIAgent()
 	agent.APIKey = ""
-	_, err := agent.Respond("Hi")
+	_, err := agent.Respond(context.Background(), UserPrompt("Hi"))
 	if err == nil || err.Error() != "AI is not configured. Set OPENAI_API_KEY environment variable" {
 		t.Errorf("expected missing key error, got %v", err)
 	}
@@ -72,7 +74,7 @@ func TestOpenAIAgent_Respond_APIError(t *testing.T) {
 			}, nil
 		},
 	}
-	_, err := agent.Respond("Hi")
+	_, err := agent.Respond(context.Background(), UserPrompt("Hi"))
 	if err == nil || err.Error() != "OpenAI API error: unauthorized" {
 		t.Errorf("expected API error, got %v", err)
 	}
@@ -87,8 +89,304 @@ func TestOpenAIAgent_Respond_Timeout(t *testing.T) {
 			return nil, context.DeadlineExceeded
 		},
 	}
-	_, err := agent.Respond("Hi")
+	_, err := agent.Respond(context.Background(), UserPrompt("Hi"))
 	if err == nil || err.Error() == "" {
 		t.Errorf("expected timeout error, got %v", err)
 	}
 }
+
+func TestOpenAIAgent_Respond_Cancelled(t *testing.T) {
+	agent := NewOpenAIAgent()
+	agent.APIKey = "test-key"
+	agent.Client = &fakeHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			<-req.Context().Done()
+			return nil, req.Context().Err()
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	_, err := agent.Respond(ctx, UserPrompt("Hi"))
+	if err == nil || err.Error() != "AI request cancelled" {
+		t.Errorf("expected cancellation error, got %v", err)
+	}
+}
+
+func sseResponse(events ...string) *http.Response {
+	var b strings.Builder
+	for _, e := range events {
+		b.WriteString("data: " + e + "\n\n")
+	}
+	return &http.Response{
+		StatusCode: 200,
+		Header:     http.Header{"Content-Type": []string{"text/event-stream"}},
+		Body:       io.NopCloser(strings.NewReader(b.String())),
+	}
+}
+
+func TestOpenAIAgent_RespondStream_Success(t *testing.T) {
+	agent := NewOpenAIAgent()
+	agent.APIKey = "test-key"
+	var sent openAIRequest
+	agent.Client = &fakeHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			_ = json.NewDecoder(req.Body).Decode(&sent)
+			return sseResponse(
+				`{"choices":[{"delta":{"role":"assistant"}}]}`,
+				`{"choices":[{"delta":{"content":"Hel"}}]}`,
+				`{"choices":[{"delta":{"content":"lo!\n"}}]}`,
+				`{"model":"gpt-4o-mini-2024-07-18","choices":[],"usage":{"prompt_tokens":12,"completion_tokens":3}}`,
+				"[DONE]",
+			), nil
+		},
+	}
+	var tokens []string
+	resp, err := agent.RespondStream(context.Background(), UserPrompt("Hi"), func(tok string) { tokens = append(tokens, tok) })
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !sent.Stream {
+		t.Error("expected request to ask for a stream")
+	}
+	if resp != "Hello!" {
+		t.Errorf("expected 'Hello!', got %q", resp)
+	}
+	if strings.Join(tokens, "|") != "Hel|lo!\n" {
+		t.Errorf("unexpected tokens %q", tokens)
+	}
+	if sent.StreamOptions == nil || !sent.StreamOptions.IncludeUsage {
+		t.Error("expected the stream to ask for usage")
+	}
+	want := Usage{Model: "gpt-4o-mini-2024-07-18", PromptTokens: 12, CompletionTokens: 3}
+	if got := agent.LastUsage(); got != want {
+		t.Errorf("expected usage %+v, got %+v", want, got)
+	}
+}
+
+func TestOpenAIAgent_Respond_Usage(t *testing.T) {
+	agent := NewOpenAIAgent()
+	agent.APIKey = "test-key"
+	body := `{"model":"gpt-4o","choices":[{"message":{"role":"assistant","content":"ok"}}],"usage":{"prompt_tokens":40,"completion_tokens":7,"total_tokens":47}}`
+	agent.Client = &fakeHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(body))}, nil
+		},
+	}
+	if _, err := agent.Respond(context.Background(), UserPrompt("Hi")); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := Usage{Model: "gpt-4o", PromptTokens: 40, CompletionTokens: 7}
+	if got := agent.LastUsage(); got != want {
+		t.Errorf("expected usage %+v, got %+v", want, got)
+	}
+
+	agent.Client = &fakeHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			return nil, context.DeadlineExceeded
+		},
+	}
+	_, _ = agent.Respond(context.Background(), UserPrompt("Hi"))
+	if got := agent.LastUsage(); got != (Usage{}) {
+		t.Errorf("expected no usage after a failed request, got %+v", got)
+	}
+}
+
+func TestOpenAIAgent_RespondStream_ErrorBody(t *testing.T) {
+	agent := NewOpenAIAgent()
+	agent.APIKey = "test-key"
+	agent.Client = &fakeHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: 401,
+				Header:     http.Header{"Content-Type": []string{"application/json"}},
+				Body:       io.NopCloser(strings.NewReader(`{"error":{"message":"unauthorized"}}`)),
+			}, nil
+		},
+	}
+	called := false
+	_, err := agent.RespondStream(context.Background(), UserPrompt("Hi"), func(string) { called = true })
+	if err == nil || err.Error() != "OpenAI API error: unauthorized" {
+		t.Errorf("expected API error, got %v", err)
+	}
+	if called {
+		t.Error("onToken should not be called for an error response")
+	}
+}
+
+func TestOpenAIAgent_RespondStream_ErrorChunk(t *testing.T) {
+	agent := NewOpenAIAgent()
+	agent.APIKey = "test-key"
+	agent.Client = &fakeHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			return sseResponse(
+				`{"choices":[{"delta":{"content":"partial"}}]}`,
+				`{"error":{"message":"overloaded"}}`,
+			), nil
+		},
+	}
+	_, err := agent.RespondStream(context.Background(), UserPrompt("Hi"), nil)
+	if err == nil || err.Error() != "OpenAI API error: overloaded" {
+		t.Errorf("expected stream error, got %v", err)
+	}
+}
+
+func TestOpenAIAgent_Respond_SendsHistory(t *testing.T) {
+	agent := NewOpenAIAgent()
+	agent.APIKey = "test-key"
+	var sent openAIRequest
+	agent.Client = &fakeHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			_ = json.NewDecoder(req.Body).Decode(&sent)
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(strings.NewReader(`{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`)),
+			}, nil
+		},
+	}
+	history := []Message{
+		{Role: RoleUser, Content: "list go files"},
+		{Role: RoleAssistant, Content: "```sh\nls *.go\n```"},
+		{Role: RoleUser, Content: "now the tests dir"},
+	}
+	_, err := agent.Respond(context.Background(), history)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := []openAIMessage{
+		{Role: "user", Content: "list go files"},
+		{Role: "assistant", Content: "```sh\nls *.go\n```"},
+		{Role: "user", Content: "now the tests dir"},
+	}
+	if len(sent.Messages) != len(want) {
+		t.Fatalf("expected %d messages, got %d", len(want), len(sent.Messages))
+	}
+	for i := range want {
+		if !reflect.DeepEqual(sent.Messages[i], want[i]) {
+			t.Errorf("message %d = %+v, want %+v", i, sent.Messages[i], want[i])
+		}
+	}
+}
+
+func TestOpenAIAgent_RespondWithTools(t *testing.T) {
+	agent := NewOpenAIAgent()
+	agent.APIKey = "test-key"
+	var sent openAIRequest
+	agent.Client = &fakeHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			_ = json.NewDecoder(req.Body).Decode(&sent)
+			body := `{"choices":[{"message":{"role":"assistant","content":null,"tool_calls":[
+				{"id":"call_1","type":"function","function":{"name":"run_command","arguments":"{\"command\":\"ls\"}"}}]}}]}`
+			return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(body))}, nil
+		},
+	}
+	tools := []Tool{{Name: "run_command", Description: "Run it", Parameters: map[string]any{"type": "object"}}}
+	history := []Message{
+		{Role: RoleUser, Content: "what is here?"},
+		{Role: RoleAssistant, ToolCalls: []ToolCall{{ID: "call_0", Name: "list_dir", Arguments: "{}"}}},
+		{Role: RoleTool, ToolCallID: "call_0", Content: "a.txt"},
+	}
+	reply, err := agent.RespondWithTools(context.Background(), history, tools, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := []ToolCall{{ID: "call_1", Name: "run_command", Arguments: `{"command":"ls"}`}}
+	if !reflect.DeepEqual(reply.ToolCalls, want) {
+		t.Errorf("tool calls = %+v, want %+v", reply.ToolCalls, want)
+	}
+	if len(sent.Tools) != 1 || sent.Tools[0].Type != "function" || sent.Tools[0].Function.Name != "run_command" {
+		t.Errorf("unexpected tools in request: %+v", sent.Tools)
+	}
+	if len(sent.Messages) != 3 || sent.Messages[1].ToolCalls[0].ID != "call_0" || sent.Messages[2].ToolCallID != "call_0" {
+		t.Errorf("tool exchange not encoded: %+v", sent.Messages)
+	}
+}
+
+func TestOpenAIAgent_RespondWithTools_Stream(t *testing.T) {
+	agent := NewOpenAIAgent()
+	agent.APIKey = "test-key"
+	agent.Client = &fakeHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			return sseResponse(
+				`{"choices":[{"delta":{"content":"Checking"}}]}`,
+				`{"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","function":{"name":"read_","arguments":"{\"pa"}}]}}]}`,
+				`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"name":"file","arguments":"th\":\"go.mod\"}"}}]}}]}`,
+				"[DONE]",
+			), nil
+		},
+	}
+	var streamed strings.Builder
+	reply, err := agent.RespondWithTools(context.Background(), UserPrompt("hi"), nil, func(tok string) { streamed.WriteString(tok) })
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if streamed.String() != "Checking" || reply.Content != "Checking" {
+		t.Errorf("unexpected content %q / %q", streamed.String(), reply.Content)
+	}
+	want := []ToolCall{{ID: "call_1", Name: "read_file", Arguments: `{"path":"go.mod"}`}}
+	if !reflect.DeepEqual(reply.ToolCalls, want) {
+		t.Errorf("tool calls = %+v, want %+v", reply.ToolCalls, want)
+	}
+}
+
+func TestOpenAIAgent_RespondStructured(t *testing.T) {
+	agent := NewOpenAIAgent()
+	agent.APIKey = "test-key"
+	agent.Model = "gpt-4o-mini"
+	var sent map[string]any
+	agent.Client = &fakeHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			_ = json.NewDecoder(req.Body).Decode(&sent)
+			body := `{"choices":[{"message":{"role":"assistant","content":"{\"explanation\":\"Lists files\",\"commands\":[{\"command\":\"ls\",\"risk\":\"low\",\"needs_tty\":false}]}"}}]}`
+			return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(body))}, nil
+		},
+	}
+	var streamed strings.Builder
+	reply, err := agent.RespondStructured(context.Background(), UserPrompt("list"), nil, func(tok string) { streamed.WriteString(tok) })
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	format, _ := sent["response_format"].(map[string]any)
+	if format["type"] != "json_schema" {
+		t.Fatalf("response_format = %v, want a json_schema", sent["response_format"])
+	}
+	if schema, _ := format["json_schema"].(map[string]any); schema["strict"] != true || schema["schema"] == nil {
+		t.Errorf("json_schema = %v, want a strict schema", schema)
+	}
+	if sent["stream"] != nil || streamed.Len() != 0 {
+		t.Errorf("structured replies should not be streamed")
+	}
+	sugg, ok := ParseSuggestion(reply.Content)
+	if !ok || sugg.Explanation != "Lists files" || len(sugg.Commands) != 1 || sugg.Commands[0].Command != "ls" {
+		t.Errorf("unexpected suggestion %+v from %q", sugg, reply.Content)
+	}
+}
+
+func TestOpenAIAgent_RespondStructured_UnsupportedModel(t *testing.T) {
+	agent := NewOpenAIAgent()
+	agent.APIKey = "test-key"
+	agent.Model = "gpt-3.5-turbo"
+	var sent map[string]any
+	agent.Client = &fakeHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			_ = json.NewDecoder(req.Body).Decode(&sent)
+			return sseResponse(`{"choices":[{"delta":{"content":"Hi"}}]}`, "[DONE]"), nil
+		},
+	}
+	var streamed strings.Builder
+	reply, err := agent.RespondStructured(context.Background(), UserPrompt("hi"), nil, func(tok string) { streamed.WriteString(tok) })
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, ok := sent["response_format"]; ok {
+		t.Errorf("gpt-3.5-turbo should not be sent a response_format")
+	}
+	if reply.Content != "Hi" || streamed.String() != "Hi" {
+		t.Errorf("unexpected content %q / %q", reply.Content, streamed.String())
+	}
+
+	on := true
+	agent.Structured = &on
+	if !agent.structured() {
+		t.Errorf("Structured should override the model check")
+	}
+}
@@ -0,0 +1,127 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Profile is a named provider configuration from ~/.binks.yaml. Empty fields
+// keep the provider's environment-based defaults.
+type Profile struct {
+	Name    string `yaml:"name"`
+	Type    string `yaml:"type"` // openai, anthropic, ollama, llamacpp or dummy
+	BaseURL string `yaml:"base_url"`
+	Model   string `yaml:"model"`
+	// The API key is read from the environment variable APIKeyEnv, or from the
+	// output of APIKeyCommand (e.g. "pass show openai"), so it never has to be
+	// written into the config file.
+	APIKeyEnv     string   `yaml:"api_key_env"`
+	APIKeyCommand string   `yaml:"api_key_command"`
+	Temperature   *float64 `yaml:"temperature"`
+	MaxTokens     int      `yaml:"max_tokens"`
+	// MaxRetries overrides how often rate-limited or failed requests to hosted
+	// providers are retried (0 disables retrying).
+	MaxRetries *int `yaml:"max_retries"`
+	// Timeout bounds each request, e.g. "90s" (0 keeps the provider default).
+	Timeout time.Duration `yaml:"timeout"`
+	// Structured turns JSON structured answers on or off for OpenAI models;
+	// unset leaves it to the model.
+	Structured *bool `yaml:"structured"`
+}
+
+// apiKey resolves the profile's key source. An empty key with no error means
+// the profile names no source and the provider default applies.
+func (p Profile) apiKey() (string, error) {
+	switch {
+	case p.APIKeyEnv != "":
+		key := os.Getenv(p.APIKeyEnv)
+		if key == "" {
+			return "", fmt.Errorf("profile %q: %s is not set", p.Name, p.APIKeyEnv)
+		}
+		return key, nil
+	case p.APIKeyCommand != "":
+		out, err := exec.Command("sh", "-c", p.APIKeyCommand).Output()
+		if err != nil {
+			return "", fmt.Errorf("profile %q: api_key_command failed: %v", p.Name, err)
+		}
+		key := strings.TrimSpace(string(out))
+		if key == "" {
+			return "", fmt.Errorf("profile %q: api_key_command printed no key", p.Name)
+		}
+		return key, nil
+	}
+	return "", nil
+}
+
+// NewAgent builds the agent a profile describes.
+func NewAgent(p Profile) (Agent, error) {
+	key, err := p.apiKey()
+	if err != nil {
+		return nil, err
+	}
+	base := strings.TrimRight(p.BaseURL, "/")
+	switch strings.ToLower(strings.TrimSpace(p.Type)) {
+	case "openai":
+		a := NewOpenAIAgent()
+		override(&a.APIKey, key)
+		override(&a.BaseURL, base)
+		override(&a.Model, p.Model)
+		a.Temperature, a.MaxTokens = p.Temperature, p.MaxTokens
+		a.Structured = p.Structured
+		overrideTimeout(&a.Timeout, p.Timeout)
+		if p.MaxRetries != nil {
+			a.Retry.MaxRetries = *p.MaxRetries
+		}
+		return a, nil
+	case "anthropic":
+		a := NewAnthropicAgent()
+		override(&a.APIKey, key)
+		override(&a.BaseURL, base)
+		override(&a.Model, p.Model)
+		a.Temperature = p.Temperature
+		overrideTimeout(&a.Timeout, p.Timeout)
+		if p.MaxTokens > 0 {
+			a.MaxTokens = p.MaxTokens
+		}
+		if p.MaxRetries != nil {
+			a.Retry.MaxRetries = *p.MaxRetries
+		}
+		return a, nil
+	case "ollama":
+		a := NewOllamaAgent()
+		override(&a.BaseURL, base)
+		override(&a.Model, p.Model)
+		a.Temperature, a.MaxTokens = p.Temperature, p.MaxTokens
+		overrideTimeout(&a.Timeout, p.Timeout)
+		return a, nil
+	case "llamacpp", "llama.cpp":
+		a := NewLlamaCppAgent()
+		override(&a.APIKey, key)
+		override(&a.BaseURL, base)
+		override(&a.Model, p.Model)
+		a.Temperature, a.MaxTokens = p.Temperature, p.MaxTokens
+		overrideTimeout(&a.Timeout, p.Timeout)
+		return a, nil
+	case "dummy":
+		return &DummyAgent{}, nil
+	case "":
+		return nil, errors.New("AI provider type is required (openai, anthropic, ollama, llamacpp or dummy)")
+	}
+	return nil, fmt.Errorf("unknown AI provider %q (want openai, anthropic, ollama, llamacpp or dummy)", p.Type)
+}
+
+func override(field *string, value string) {
+	if value != "" {
+		*field = value
+	}
+}
+
+func overrideTimeout(field *time.Duration, value time.Duration) {
+	if value > 0 {
+		*field = value
+	}
+}
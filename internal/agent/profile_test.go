@@ -0,0 +1,65 @@
+package agent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAgent_ProfileOverridesDefaults(t *testing.T) {
+	t.Setenv("WORK_KEY", "sk-work")
+	temp := 0.2
+	ag, err := NewAgent(Profile{
+		Name:        "work",
+		Type:        "openai",
+		BaseURL:     "https://proxy.example/v1/",
+		Model:       "gpt-4o",
+		APIKeyEnv:   "WORK_KEY",
+		Temperature: &temp,
+		MaxTokens:   800,
+		Timeout:     90 * time.Second,
+	})
+	require.NoError(t, err)
+	oa, ok := ag.(*OpenAIAgent)
+	require.True(t, ok)
+	assert.Equal(t, "sk-work", oa.APIKey)
+	assert.Equal(t, "https://proxy.example/v1", oa.BaseURL)
+	assert.Equal(t, "gpt-4o", oa.Model)
+	assert.Equal(t, &temp, oa.Temperature)
+	assert.Equal(t, 800, oa.MaxTokens)
+	assert.Equal(t, 90*time.Second, oa.Timeout)
+}
+
+func TestNewAgent_KeepsEnvDefaults(t *testing.T) {
+	t.Setenv("ANTHROPIC_API_KEY", "env-key")
+	t.Setenv("ANTHROPIC_MODEL", "")
+	ag, err := NewAgent(Profile{Type: "Anthropic"})
+	require.NoError(t, err)
+	a := ag.(*AnthropicAgent)
+	assert.Equal(t, "env-key", a.APIKey)
+	assert.Equal(t, "claude-3-5-haiku-latest", a.Model)
+	assert.Equal(t, anthropicMaxTokens, a.MaxTokens)
+}
+
+func TestNewAgent_APIKeyCommand(t *testing.T) {
+	ag, err := NewAgent(Profile{Name: "local", Type: "llamacpp", APIKeyCommand: "echo ' from-cmd '"})
+	require.NoError(t, err)
+	assert.Equal(t, "from-cmd", ag.(*LlamaCppAgent).APIKey)
+
+	_, err = NewAgent(Profile{Name: "broken", Type: "openai", APIKeyCommand: "exit 1"})
+	assert.EqualError(t, err, `profile "broken": api_key_command failed: exit status 1`)
+	_, err = NewAgent(Profile{Name: "empty", Type: "openai", APIKeyCommand: "true"})
+	assert.EqualError(t, err, `profile "empty": api_key_command printed no key`)
+}
+
+func TestNewAgent_Errors(t *testing.T) {
+	t.Setenv("MISSING_KEY", "")
+	_, err := NewAgent(Profile{Name: "work", Type: "openai", APIKeyEnv: "MISSING_KEY"})
+	assert.EqualError(t, err, `profile "work": MISSING_KEY is not set`)
+	_, err = NewAgent(Profile{Name: "x"})
+	assert.EqualError(t, err, "AI provider type is required (openai, anthropic, ollama, llamacpp or dummy)")
+	_, err = NewAgent(Profile{Type: "bard"})
+	assert.EqualError(t, err, `unknown AI provider "bard" (want openai, anthropic, ollama, llamacpp or dummy)`)
+}
@@ -0,0 +1,211 @@
+package agent
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// detector finds one kind of secret. When its pattern has a capture group,
+// only the first group is replaced, so that e.g. the name in PASSWORD=... stays.
+type detector struct {
+	kind string
+	re   *regexp.Regexp
+	// check, when set, rejects matches that are not secrets after all.
+	check func(string) bool
+}
+
+// builtinDetectors are always applied, in order.
+var builtinDetectors = []detector{
+	{kind: "PRIVATE_KEY", re: regexp.MustCompile(`(?s)-----BEGIN [A-Z ]*PRIVATE KEY-----.*?-----END [A-Z ]*PRIVATE KEY-----`)},
+	{kind: "AWS_KEY", re: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{kind: "GITHUB_TOKEN", re: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	{kind: "JWT", re: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`)},
+	{
+		kind:  "SECRET",
+		re:    regexp.MustCompile(`(?i)\b[A-Z0-9_]*(?:SECRET|TOKEN|PASSWORD|PASSWD|API_?KEY|ACCESS_KEY|PRIVATE_KEY|CREDENTIALS?)[A-Z0-9_]*["']?[ \t]*[=:][ \t]*("[^"\n]*"|'[^'\n]*'|[^\s"',;]+)`),
+		check: isAssignedSecret,
+	},
+	{kind: "HIGH_ENTROPY", re: regexp.MustCompile(`[A-Za-z0-9+/_=-]{32,}`), check: isHighEntropy},
+}
+
+// Redactor replaces secrets in text sent to AI providers with placeholders
+// such as [REDACTED_AWS_KEY_1]. A secret gets the same placeholder every time
+// it is seen, so the model can still tell secrets apart across a conversation.
+type Redactor struct {
+	detectors []detector
+
+	mu           sync.Mutex
+	placeholders map[string]string // secret -> placeholder
+	counts       map[string]int    // placeholders handed out per kind
+}
+
+// NewRedactor returns a Redactor using the built-in detectors plus the given
+// regular expressions, whose matches are reported as CUSTOM.
+func NewRedactor(patterns []string) (*Redactor, error) {
+	r := &Redactor{
+		detectors:    append([]detector(nil), builtinDetectors...),
+		placeholders: map[string]string{},
+		counts:       map[string]int{},
+	}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("redact pattern %q: %w", p, err)
+		}
+		r.detectors = append(r.detectors, detector{kind: "CUSTOM", re: re})
+	}
+	return r, nil
+}
+
+// Redact returns text with every secret replaced by its placeholder.
+func (r *Redactor) Redact(text string) string {
+	if text == "" {
+		return text
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, d := range r.detectors {
+		text = r.replace(d, text)
+	}
+	return text
+}
+
+func (r *Redactor) replace(d detector, text string) string {
+	matches := d.re.FindAllStringSubmatchIndex(text, -1)
+	if len(matches) == 0 {
+		return text
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		if len(m) >= 4 && m[2] >= 0 {
+			start, end = m[2], m[3]
+		}
+		secret := text[start:end]
+		if secret == "" || isPlaceholder(secret) || (d.check != nil && !d.check(secret)) {
+			continue
+		}
+		b.WriteString(text[last:start])
+		b.WriteString(r.placeholder(d.kind, secret))
+		last = end
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+func (r *Redactor) placeholder(kind, secret string) string {
+	if p, ok := r.placeholders[secret]; ok {
+		return p
+	}
+	r.counts[kind]++
+	p := fmt.Sprintf("[REDACTED_%s_%d]", kind, r.counts[kind])
+	r.placeholders[secret] = p
+	return p
+}
+
+var placeholderRe = regexp.MustCompile(`^\[REDACTED_[A-Z_]+_\d+\]$`)
+
+func isPlaceholder(s string) bool {
+	return strings.HasPrefix(s, "[REDACTED_") && placeholderRe.MatchString(s)
+}
+
+// secretPrefixes start API keys that are secret whatever they are assigned to.
+var secretPrefixes = []string{"sk-", "sk_live_", "sk_test_", "rk_live_", "xoxb-", "xoxp-", "glpat-", "AIza", "hf_", "npm_"}
+
+// isAssignedSecret reports whether a value assigned to a secret-sounding name
+// looks like a secret: a known API key, or at least 6 characters mixing
+// letters with digits or symbols. Numbers and words, as in max_tokens: 1500
+// or token_type: bearer, are config rather than secrets, and variable
+// references and placeholders are kept.
+func isAssignedSecret(value string) bool {
+	v := strings.Trim(value, `"'`)
+	if len(v) < 6 || strings.HasPrefix(v, "$") || strings.Contains(v, "[REDACTED_") {
+		return false
+	}
+	for _, p := range secretPrefixes {
+		if strings.HasPrefix(v, p) {
+			return true
+		}
+	}
+	letters, others := false, false
+	for _, c := range v {
+		switch {
+		case unicode.IsLetter(c):
+			letters = true
+		case unicode.IsDigit(c), !unicode.IsSpace(c) && !strings.ContainsRune("_-.", c):
+			others = true
+		}
+	}
+	return letters && others && entropy(v) >= 2.5
+}
+
+// isHighEntropy reports whether s looks like a random token: letters mixed
+// with digits, and more than 4 bits of entropy per character, which hex
+// strings such as git hashes never reach.
+func isHighEntropy(s string) bool {
+	if !strings.ContainsAny(s, "0123456789") || !strings.ContainsAny(strings.ToLower(s), "abcdefghijklmnopqrstuvwxyz") {
+		return false
+	}
+	return entropy(s) > 4.2
+}
+
+// entropy returns the Shannon entropy of s in bits per character.
+func entropy(s string) float64 {
+	freq := map[rune]float64{}
+	for _, c := range s {
+		freq[c]++
+	}
+	bits := 0.0
+	n := float64(len(s))
+	for _, f := range freq {
+		p := f / n
+		bits -= p * math.Log2(p)
+	}
+	return bits
+}
+
+var (
+	redactorMu sync.RWMutex
+	redactor   = mustRedactor()
+)
+
+func mustRedactor() *Redactor {
+	r, err := NewRedactor(nil)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// SetRedactor replaces the Redactor applied to every prompt sent by the
+// agents in this package; binks installs one with the patterns from
+// ~/.binks.yaml at startup.
+func SetRedactor(r *Redactor) {
+	redactorMu.Lock()
+	defer redactorMu.Unlock()
+	redactor = r
+}
+
+// Redact removes secrets from text with the current Redactor.
+func Redact(text string) string {
+	redactorMu.RLock()
+	r := redactor
+	redactorMu.RUnlock()
+	return r.Redact(text)
+}
+
+// redactMessages returns a copy of messages with secrets removed from their
+// content. Agents call it on every conversation before encoding a request.
+func redactMessages(messages []Message) []Message {
+	out := make([]Message, len(messages))
+	for i, m := range messages {
+		m.Content = Redact(m.Content)
+		out[i] = m
+	}
+	return out
+}
@@ -0,0 +1,105 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy controls how requests that failed transiently (rate limits,
+// overloaded or unreachable servers) are retried.
+type RetryPolicy struct {
+	MaxRetries int           // retries after the first attempt; 0 disables retrying
+	BaseDelay  time.Duration // first backoff, doubled on every retry
+	MaxDelay   time.Duration // longest single wait; a longer Retry-After is not waited out
+}
+
+// DefaultRetryPolicy is used by agents for hosted providers.
+var DefaultRetryPolicy = RetryPolicy{MaxRetries: 2, BaseDelay: 500 * time.Millisecond, MaxDelay: 20 * time.Second}
+
+// do runs attempt until it succeeds, fails permanently, the retry budget is
+// spent or ctx is cancelled. sleep waits between attempts; when nil, the wait
+// is cut short by cancellation.
+func (p RetryPolicy) do(ctx context.Context, sleep func(time.Duration), attempt func() error) error {
+	if sleep == nil {
+		sleep = func(d time.Duration) {
+			t := time.NewTimer(d)
+			defer t.Stop()
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+			}
+		}
+	}
+	for n := 0; ; n++ {
+		err := attempt()
+		var final permanentError
+		if errors.As(err, &final) {
+			return final.error
+		}
+		if err == nil || n >= p.MaxRetries {
+			return err
+		}
+		wait, ok := p.backoff(n, err)
+		if !ok || ctx.Err() != nil {
+			return err
+		}
+		sleep(wait)
+		if ctx.Err() != nil {
+			return requestError(ctx.Err())
+		}
+	}
+}
+
+// permanentError marks an error as not worth retrying, e.g. because part of
+// the reply was already shown to the user.
+type permanentError struct{ error }
+
+func (e permanentError) Unwrap() error { return e.error }
+
+// streamOnce wraps onToken so that a failure after the first token is not
+// retried, which would show the start of the reply twice. check wraps the
+// attempt's error accordingly.
+func streamOnce(onToken func(string)) (wrapped func(string), check func(error) error) {
+	if onToken == nil {
+		return nil, func(err error) error { return err }
+	}
+	streamed := false
+	wrapped = func(tok string) {
+		streamed = true
+		onToken(tok)
+	}
+	check = func(err error) error {
+		if err != nil && streamed {
+			return permanentError{err}
+		}
+		return err
+	}
+	return wrapped, check
+}
+
+// backoff returns how long to wait before retry n+1, and whether err is worth
+// retrying at all.
+func (p RetryPolicy) backoff(n int, err error) (time.Duration, bool) {
+	if !errors.Is(err, ErrRateLimited) && !errors.Is(err, ErrUnavailable) {
+		return 0, false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		if p.MaxDelay > 0 && apiErr.RetryAfter > p.MaxDelay {
+			return 0, false
+		}
+		return apiErr.RetryAfter, true
+	}
+	delay := p.BaseDelay << n
+	if p.MaxDelay > 0 && (delay > p.MaxDelay || delay <= 0) {
+		delay = p.MaxDelay
+	}
+	// Equal jitter: wait between half and all of the exponential delay.
+	half := delay / 2
+	if half > 0 {
+		delay = half + rand.N(half+1)
+	}
+	return delay, true
+}
@@ -0,0 +1,197 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scriptedAgent returns an agent whose requests are answered with responses in
+// order (nil stands for a dropped connection), and records its retry sleeps.
+func scriptedAgent(t *testing.T, responses ...*http.Response) (*OpenAIAgent, *[]time.Duration, *int) {
+	t.Helper()
+	calls := 0
+	var sleeps []time.Duration
+	a := NewOpenAIAgent()
+	a.APIKey = "test-key"
+	a.sleep = func(d time.Duration) { sleeps = append(sleeps, d) }
+	a.Client = &fakeHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			require.Less(t, calls, len(responses), "unexpected extra request")
+			resp := responses[calls]
+			calls++
+			if resp == nil {
+				return nil, errors.New("connection reset by peer")
+			}
+			return resp, nil
+		},
+	}
+	return a, &sleeps, &calls
+}
+
+func jsonResponse(status int, body string, header ...string) *http.Response {
+	h := http.Header{"Content-Type": []string{"application/json"}}
+	for i := 0; i+1 < len(header); i += 2 {
+		h.Set(header[i], header[i+1])
+	}
+	return &http.Response{StatusCode: status, Header: h, Body: io.NopCloser(strings.NewReader(body))}
+}
+
+const okBody = `{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`
+
+func TestOpenAIAgent_RetriesRateLimitWithRetryAfter(t *testing.T) {
+	a, sleeps, calls := scriptedAgent(t,
+		jsonResponse(429, `{"error":{"message":"Rate limit reached","type":"requests","code":"rate_limit_exceeded"}}`, "Retry-After", "2"),
+		jsonResponse(200, okBody),
+	)
+	resp, err := a.Respond(context.Background(), UserPrompt("hi"))
+	require.NoError(t, err)
+	assert.Equal(t, "ok", resp)
+	assert.Equal(t, 2, *calls)
+	assert.Equal(t, []time.Duration{2 * time.Second}, *sleeps)
+}
+
+func TestOpenAIAgent_RetriesServerErrorsWithBackoff(t *testing.T) {
+	a, sleeps, calls := scriptedAgent(t,
+		jsonResponse(503, `<html>busy</html>`),
+		nil,
+		jsonResponse(200, okBody),
+	)
+	a.Retry = RetryPolicy{MaxRetries: 3, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	_, err := a.Respond(context.Background(), UserPrompt("hi"))
+	require.NoError(t, err)
+	assert.Equal(t, 3, *calls)
+	require.Len(t, *sleeps, 2)
+	assert.GreaterOrEqual(t, (*sleeps)[0], 50*time.Millisecond)
+	assert.LessOrEqual(t, (*sleeps)[0], 100*time.Millisecond)
+	assert.GreaterOrEqual(t, (*sleeps)[1], 100*time.Millisecond)
+	assert.LessOrEqual(t, (*sleeps)[1], 200*time.Millisecond)
+}
+
+func TestOpenAIAgent_RetryBudgetExhausted(t *testing.T) {
+	a, _, calls := scriptedAgent(t,
+		jsonResponse(500, `{"error":{"message":"boom"}}`),
+		jsonResponse(502, `{"error":{"message":"boom"}}`),
+		jsonResponse(503, `{"error":{"message":"still down"}}`),
+	)
+	_, err := a.Respond(context.Background(), UserPrompt("hi"))
+	assert.EqualError(t, err, "OpenAI API error: still down")
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.Equal(t, DefaultRetryPolicy.MaxRetries+1, *calls)
+}
+
+func TestOpenAIAgent_PermanentErrorsAreNotRetried(t *testing.T) {
+	cases := []struct {
+		name string
+		resp *http.Response
+		kind error
+	}{
+		{"auth", jsonResponse(401, `{"error":{"message":"Incorrect API key provided","code":"invalid_api_key"}}`), ErrAuthFailed},
+		{"quota", jsonResponse(429, `{"error":{"message":"You exceeded your current quota","code":"insufficient_quota"}}`), ErrQuotaExceeded},
+		{"context", jsonResponse(400, `{"error":{"message":"This model's maximum context length is 4097 tokens","code":"context_length_exceeded"}}`), ErrContextTooLong},
+		{"long wait", jsonResponse(429, `{"error":{"message":"slow down"}}`, "Retry-After", "3600"), ErrRateLimited},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			a, sleeps, calls := scriptedAgent(t, tc.resp)
+			_, err := a.Respond(context.Background(), UserPrompt("hi"))
+			assert.ErrorIs(t, err, tc.kind)
+			assert.Equal(t, 1, *calls)
+			assert.Empty(t, *sleeps)
+		})
+	}
+}
+
+func TestOpenAIAgent_APIErrorDetails(t *testing.T) {
+	a, _, _ := scriptedAgent(t, jsonResponse(429, `{"error":{"message":"slow down","type":"tokens","code":null}}`, "Retry-After", "90"))
+	_, err := a.Respond(context.Background(), UserPrompt("hi"))
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, 429, apiErr.StatusCode)
+	assert.Equal(t, "tokens", apiErr.Type)
+	assert.Empty(t, apiErr.Code)
+	assert.Equal(t, 90*time.Second, apiErr.RetryAfter)
+}
+
+func TestOpenAIAgent_StreamNotRetriedAfterOutput(t *testing.T) {
+	a, _, calls := scriptedAgent(t, sseResponse(
+		`{"choices":[{"delta":{"content":"partial"}}]}`,
+		`{"error":{"message":"The server is overloaded"}}`,
+	))
+	var tokens []string
+	_, err := a.RespondStream(context.Background(), UserPrompt("hi"), func(tok string) { tokens = append(tokens, tok) })
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.Equal(t, 1, *calls)
+	assert.Equal(t, []string{"partial"}, tokens)
+}
+
+func TestOpenAIAgent_TimeoutIsNotRetried(t *testing.T) {
+	a, _, _ := scriptedAgent(t)
+	calls := 0
+	a.Client = &fakeHTTPClient{DoFunc: func(req *http.Request) (*http.Response, error) {
+		calls++
+		return nil, errors.New("context deadline exceeded")
+	}}
+	_, err := a.Respond(context.Background(), UserPrompt("hi"))
+	assert.EqualError(t, err, "AI request timed out")
+	assert.Equal(t, 1, calls)
+}
+
+func TestRetryAfter(t *testing.T) {
+	h := http.Header{}
+	assert.Zero(t, retryAfter(h))
+	h.Set("Retry-After", "7")
+	assert.Equal(t, 7*time.Second, retryAfter(h))
+	h.Set("retry-after-ms", "1500")
+	assert.Equal(t, 1500*time.Millisecond, retryAfter(h))
+	h = http.Header{}
+	h.Set("Retry-After", time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+	assert.InDelta(t, float64(time.Minute), float64(retryAfter(h)), float64(2*time.Second))
+}
+
+func TestAnthropicAgent_RetriesOverloaded(t *testing.T) {
+	calls := 0
+	a := anthropicStandIn(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(529)
+			_, _ = io.WriteString(w, `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{"type":"message","content":[{"type":"text","text":"ok"}]}`)
+	})
+	resp, err := a.Respond(context.Background(), UserPrompt("hi"))
+	require.NoError(t, err)
+	assert.Equal(t, "ok", resp)
+	assert.Equal(t, 2, calls)
+}
+
+func TestAnthropicAgent_TypedErrors(t *testing.T) {
+	a := anthropicStandIn(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = io.WriteString(w, `{"type":"error","error":{"type":"invalid_request_error","message":"prompt is too long: 210000 tokens > 200000 maximum"}}`)
+	})
+	_, err := a.Respond(context.Background(), UserPrompt("hi"))
+	assert.ErrorIs(t, err, ErrContextTooLong)
+	assert.EqualError(t, err, "Anthropic API error: prompt is too long: 210000 tokens > 200000 maximum")
+}
+
+func TestOpenAIAgent_StopsRetryingWhenCancelled(t *testing.T) {
+	a, _, calls := scriptedAgent(t,
+		jsonResponse(503, `{"error":{"message":"overloaded"}}`),
+		jsonResponse(200, okBody),
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	a.sleep = func(time.Duration) { cancel() }
+	_, err := a.Respond(ctx, UserPrompt("hi"))
+	require.Error(t, err)
+	assert.Equal(t, "AI request cancelled", err.Error())
+	assert.Equal(t, 1, *calls)
+}
@@ -0,0 +1,34 @@
+package agent
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// sseDone is the sentinel payload OpenAI-compatible servers send to end a stream.
+const sseDone = "[DONE]"
+
+// readSSE reads a server-sent event stream and calls onData with the payload of
+// every "data:" line. It stops at the end of the stream or at the [DONE] sentinel.
+func readSSE(r io.Reader, onData func(data string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if !strings.HasPrefix(line, "data:") {
+			continue // comments, event names, ids and blank separators
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == sseDone {
+			return nil
+		}
+		if data == "" {
+			continue
+		}
+		if err := onData(data); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
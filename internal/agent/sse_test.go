@@ -0,0 +1,32 @@
+package agent
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadSSE(t *testing.T) {
+	stream := ": keep-alive\r\n" +
+		"event: message\n" +
+		"data: one\n\n" +
+		"data:two\r\n\r\n" +
+		"data: \n\n" +
+		"data: [DONE]\n\n" +
+		"data: after-done\n\n"
+	var got []string
+	err := readSSE(strings.NewReader(stream), func(data string) error {
+		got = append(got, data)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"one", "two"}, got)
+}
+
+func TestReadSSE_CallbackError(t *testing.T) {
+	boom := errors.New("boom")
+	err := readSSE(strings.NewReader("data: one\n\ndata: two\n\n"), func(string) error { return boom })
+	assert.Equal(t, boom, err)
+}
@@ -0,0 +1,102 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+)
+
+// Suggestion is an answer in structured form: what the model has to say and
+// the commands it proposes, in the order they should run.
+type Suggestion struct {
+	Explanation string             `json:"explanation"`
+	Commands    []SuggestedCommand `json:"commands"`
+}
+
+// SuggestedCommand is one shell command of a Suggestion.
+type SuggestedCommand struct {
+	Command string `json:"command"`
+	// Risk is the model's own rating: low, medium or high.
+	Risk string `json:"risk"`
+	// NeedsTTY is set for commands that must run attached to the terminal,
+	// such as editors and pagers.
+	NeedsTTY bool `json:"needs_tty"`
+}
+
+// StructuredAgent is an optional interface for agents that can be asked to
+// answer with a Suggestion encoded as JSON that follows SuggestionSchema.
+// Agents that cannot honour the schema for the current model answer as usual,
+// so callers must be ready for Content that ParseSuggestion rejects.
+type StructuredAgent interface {
+	Agent
+	// RespondStructured asks for a Suggestion, offering tools like
+	// RespondWithTools when tools is non-empty. A structured reply is not
+	// streamed; a plain one is streamed to onToken when it is non-nil.
+	RespondStructured(ctx context.Context, messages []Message, tools []Tool, onToken func(string)) (Reply, error)
+}
+
+// SuggestionSchema is the JSON schema of a Suggestion, in the strict form
+// accepted by OpenAI structured outputs.
+var SuggestionSchema = map[string]any{
+	"type":                 "object",
+	"additionalProperties": false,
+	"required":             []string{"explanation", "commands"},
+	"properties": map[string]any{
+		"explanation": map[string]any{
+			"type":        "string",
+			"description": "The answer to the user, without the commands.",
+		},
+		"commands": map[string]any{
+			"type":        "array",
+			"description": "Shell commands to run, in order; empty when no command is needed.",
+			"items": map[string]any{
+				"type":                 "object",
+				"additionalProperties": false,
+				"required":             []string{"command", "risk", "needs_tty"},
+				"properties": map[string]any{
+					"command":   map[string]any{"type": "string"},
+					"risk":      map[string]any{"type": "string", "enum": []string{"low", "medium", "high"}},
+					"needs_tty": map[string]any{"type": "boolean", "description": "Whether the command is interactive and needs a terminal."},
+				},
+			},
+		},
+	},
+}
+
+// ParseSuggestion decodes a structured answer. It reports false for anything
+// else, such as the markdown a model writes without a schema.
+func ParseSuggestion(content string) (Suggestion, bool) {
+	content = strings.TrimSpace(content)
+	if !strings.HasPrefix(content, "{") {
+		return Suggestion{}, false
+	}
+	var s Suggestion
+	if err := json.Unmarshal([]byte(content), &s); err != nil {
+		return Suggestion{}, false
+	}
+	commands := s.Commands[:0]
+	for _, c := range s.Commands {
+		if c.Command = strings.TrimSpace(c.Command); c.Command != "" {
+			commands = append(commands, c)
+		}
+	}
+	s.Commands = commands
+	if s.Explanation == "" && len(s.Commands) == 0 {
+		return Suggestion{}, false
+	}
+	return s, true
+}
+
+// Markdown renders a Suggestion the way a model would write it, with each
+// command in a fenced block.
+func (s Suggestion) Markdown() string {
+	var b strings.Builder
+	b.WriteString(s.Explanation)
+	for _, c := range s.Commands {
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString("```sh\n" + c.Command + "\n```")
+	}
+	return b.String()
+}
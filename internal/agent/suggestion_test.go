@@ -0,0 +1,33 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSuggestion(t *testing.T) {
+	sugg, ok := ParseSuggestion(` {"explanation":"Two steps","commands":[
+		{"command":" git pull ","risk":"low","needs_tty":false},
+		{"command":"","risk":"low","needs_tty":false},
+		{"command":"vim go.mod","risk":"low","needs_tty":true}]}`)
+	assert.True(t, ok)
+	assert.Equal(t, Suggestion{
+		Explanation: "Two steps",
+		Commands: []SuggestedCommand{
+			{Command: "git pull", Risk: "low"},
+			{Command: "vim go.mod", Risk: "low", NeedsTTY: true},
+		},
+	}, sugg)
+	assert.Equal(t, "Two steps\n```sh\ngit pull\n```\n```sh\nvim go.mod\n```", sugg.Markdown())
+
+	for _, content := range []string{
+		"Run:\n```sh\nls\n```",
+		`{"explanation":`,
+		`{"answer":"something else"}`,
+		"",
+	} {
+		_, ok := ParseSuggestion(content)
+		assert.False(t, ok, content)
+	}
+}
@@ -0,0 +1,247 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// maxToolOutput bounds how much text a built-in tool returns to the model.
+const maxToolOutput = 64 * 1024
+
+// Tool is a capability the model may ask binks to use on its behalf.
+type Tool struct {
+	Name        string
+	Description string
+	// Parameters is the JSON schema of the arguments object.
+	Parameters map[string]any
+	// Run executes the tool with the decoded arguments.
+	Run func(ctx context.Context, args map[string]any) (string, error)
+	// Describe summarises a call for the confirmation prompt. Optional.
+	Describe func(args map[string]any) string
+}
+
+// ToolRegistry holds the tools offered to a tool-calling agent.
+type ToolRegistry struct {
+	tools map[string]Tool
+}
+
+// NewToolRegistry creates a registry holding the given tools.
+func NewToolRegistry(tools ...Tool) *ToolRegistry {
+	r := &ToolRegistry{tools: make(map[string]Tool)}
+	for _, t := range tools {
+		r.Register(t)
+	}
+	return r
+}
+
+// Register adds a tool, replacing any tool with the same name.
+func (r *ToolRegistry) Register(t Tool) {
+	r.tools[t.Name] = t
+}
+
+// Lookup returns the tool with the given name.
+func (r *ToolRegistry) Lookup(name string) (Tool, bool) {
+	t, ok := r.tools[name]
+	return t, ok
+}
+
+// Tools returns all registered tools sorted by name.
+func (r *ToolRegistry) Tools() []Tool {
+	out := make([]Tool, 0, len(r.tools))
+	for _, t := range r.tools {
+		out = append(out, t)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Describe returns a one-line summary of a tool call for display.
+func (r *ToolRegistry) Describe(call ToolCall) string {
+	t, ok := r.tools[call.Name]
+	args, err := decodeArgs(call.Arguments)
+	if !ok || err != nil || t.Describe == nil {
+		return call.Name + " " + call.Arguments
+	}
+	return t.Describe(args)
+}
+
+// Call runs the tool named by call.
+func (r *ToolRegistry) Call(ctx context.Context, call ToolCall) (string, error) {
+	t, ok := r.tools[call.Name]
+	if !ok {
+		return "", fmt.Errorf("unknown tool %q", call.Name)
+	}
+	args, err := decodeArgs(call.Arguments)
+	if err != nil {
+		return "", fmt.Errorf("invalid arguments for %s: %w", call.Name, err)
+	}
+	return t.Run(ctx, args)
+}
+
+func decodeArgs(raw string) (map[string]any, error) {
+	args := map[string]any{}
+	if strings.TrimSpace(raw) == "" {
+		return args, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &args); err != nil {
+		return nil, err
+	}
+	return args, nil
+}
+
+// stringArg returns a string argument, or an error if it is missing and required.
+func stringArg(args map[string]any, name string, required bool) (string, error) {
+	v, ok := args[name]
+	if !ok || v == nil {
+		if required {
+			return "", fmt.Errorf("missing argument %q", name)
+		}
+		return "", nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("argument %q must be a string", name)
+	}
+	return s, nil
+}
+
+// ToolEnv connects the built-in tools to the shell session they act on.
+type ToolEnv struct {
+	// RunCommand runs a shell command in the session.
+	RunCommand func(ctx context.Context, cmd string) (string, error)
+	// Cwd returns the session's working directory; relative paths resolve against it.
+	Cwd func() string
+}
+
+func (env ToolEnv) resolve(path string) string {
+	if path == "" {
+		path = "."
+	}
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(env.Cwd(), path)
+}
+
+func stringSchema(props map[string]string, required ...string) map[string]any {
+	properties := map[string]any{}
+	for name, desc := range props {
+		properties[name] = map[string]any{"type": "string", "description": desc}
+	}
+	if required == nil {
+		required = []string{}
+	}
+	return map[string]any{"type": "object", "properties": properties, "required": required}
+}
+
+// BuiltinTools returns the standard tools: run_command, read_file, list_dir and write_file.
+func BuiltinTools(env ToolEnv) *ToolRegistry {
+	return NewToolRegistry(
+		Tool{
+			Name:        "run_command",
+			Description: "Run a shell command in the user's current directory and return its combined output.",
+			Parameters:  stringSchema(map[string]string{"command": "The shell command to run"}, "command"),
+			Run: func(ctx context.Context, args map[string]any) (string, error) {
+				cmd, err := stringArg(args, "command", true)
+				if err != nil {
+					return "", err
+				}
+				return env.RunCommand(ctx, cmd)
+			},
+			Describe: func(args map[string]any) string {
+				cmd, _ := stringArg(args, "command", false)
+				return cmd
+			},
+		},
+		Tool{
+			Name:        "read_file",
+			Description: "Read a text file. Relative paths are resolved against the current directory.",
+			Parameters:  stringSchema(map[string]string{"path": "Path of the file to read"}, "path"),
+			Run: func(_ context.Context, args map[string]any) (string, error) {
+				path, err := stringArg(args, "path", true)
+				if err != nil {
+					return "", err
+				}
+				data, err := os.ReadFile(env.resolve(path))
+				if err != nil {
+					return "", err
+				}
+				if len(data) > maxToolOutput {
+					return string(data[:maxToolOutput]) + "\n[file truncated]", nil
+				}
+				return string(data), nil
+			},
+			Describe: func(args map[string]any) string {
+				path, _ := stringArg(args, "path", false)
+				return "read_file " + path
+			},
+		},
+		Tool{
+			Name:        "list_dir",
+			Description: "List the entries of a directory. Directories are marked with a trailing slash.",
+			Parameters:  stringSchema(map[string]string{"path": "Directory to list (default: current directory)"}),
+			Run: func(_ context.Context, args map[string]any) (string, error) {
+				path, err := stringArg(args, "path", false)
+				if err != nil {
+					return "", err
+				}
+				entries, err := os.ReadDir(env.resolve(path))
+				if err != nil {
+					return "", err
+				}
+				var b strings.Builder
+				for _, e := range entries {
+					b.WriteString(e.Name())
+					if e.IsDir() {
+						b.WriteString("/")
+					}
+					b.WriteString("\n")
+				}
+				return b.String(), nil
+			},
+			Describe: func(args map[string]any) string {
+				path, _ := stringArg(args, "path", false)
+				if path == "" {
+					path = "."
+				}
+				return "list_dir " + path
+			},
+		},
+		Tool{
+			Name:        "write_file",
+			Description: "Create or overwrite a text file with the given content.",
+			Parameters: stringSchema(map[string]string{
+				"path":    "Path of the file to write",
+				"content": "Full content of the file",
+			}, "path", "content"),
+			Run: func(_ context.Context, args map[string]any) (string, error) {
+				path, err := stringArg(args, "path", true)
+				if err != nil {
+					return "", err
+				}
+				content, err := stringArg(args, "content", true)
+				if err != nil {
+					return "", err
+				}
+				if path == "" {
+					return "", errors.New("path must not be empty")
+				}
+				if err := os.WriteFile(env.resolve(path), []byte(content), 0o644); err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("wrote %d bytes to %s", len(content), path), nil
+			},
+			Describe: func(args map[string]any) string {
+				path, _ := stringArg(args, "path", false)
+				content, _ := stringArg(args, "content", false)
+				return fmt.Sprintf("write_file %s (%d bytes)", path, len(content))
+			},
+		},
+	)
+}
@@ -0,0 +1,91 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testToolEnv(t *testing.T) (ToolEnv, string, *[]string) {
+	dir := t.TempDir()
+	var ran []string
+	env := ToolEnv{
+		RunCommand: func(_ context.Context, cmd string) (string, error) {
+			ran = append(ran, cmd)
+			if cmd == "false" {
+				return "", errors.New("exit status 1")
+			}
+			return "ran: " + cmd, nil
+		},
+		Cwd: func() string { return dir },
+	}
+	return env, dir, &ran
+}
+
+func TestBuiltinTools_Registry(t *testing.T) {
+	env, _, _ := testToolEnv(t)
+	reg := BuiltinTools(env)
+	var names []string
+	for _, tool := range reg.Tools() {
+		names = append(names, tool.Name)
+		assert.Equal(t, "object", tool.Parameters["type"])
+	}
+	assert.Equal(t, []string{"list_dir", "read_file", "run_command", "write_file"}, names)
+
+	_, ok := reg.Lookup("run_command")
+	assert.True(t, ok)
+	_, err := reg.Call(context.Background(), ToolCall{Name: "nope"})
+	assert.EqualError(t, err, `unknown tool "nope"`)
+	_, err = reg.Call(context.Background(), ToolCall{Name: "run_command", Arguments: "{not json"})
+	assert.ErrorContains(t, err, "invalid arguments for run_command")
+}
+
+func TestBuiltinTools_RunCommand(t *testing.T) {
+	env, _, ran := testToolEnv(t)
+	reg := BuiltinTools(env)
+
+	out, err := reg.Call(context.Background(), ToolCall{Name: "run_command", Arguments: `{"command":"ls -la"}`})
+	require.NoError(t, err)
+	assert.Equal(t, "ran: ls -la", out)
+	assert.Equal(t, []string{"ls -la"}, *ran)
+
+	_, err = reg.Call(context.Background(), ToolCall{Name: "run_command", Arguments: `{}`})
+	assert.EqualError(t, err, `missing argument "command"`)
+	_, err = reg.Call(context.Background(), ToolCall{Name: "run_command", Arguments: `{"command":42}`})
+	assert.EqualError(t, err, `argument "command" must be a string`)
+
+	assert.Equal(t, "ls -la", reg.Describe(ToolCall{Name: "run_command", Arguments: `{"command":"ls -la"}`}))
+}
+
+func TestBuiltinTools_Files(t *testing.T) {
+	env, dir, _ := testToolEnv(t)
+	reg := BuiltinTools(env)
+
+	out, err := reg.Call(context.Background(), ToolCall{Name: "write_file", Arguments: `{"path":"notes.txt","content":"hello"}`})
+	require.NoError(t, err)
+	assert.Equal(t, "wrote 5 bytes to notes.txt", out)
+	data, err := os.ReadFile(filepath.Join(dir, "notes.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+
+	out, err = reg.Call(context.Background(), ToolCall{Name: "read_file", Arguments: `{"path":"notes.txt"}`})
+	require.NoError(t, err)
+	assert.Equal(t, "hello", out)
+
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0o755))
+	out, err = reg.Call(context.Background(), ToolCall{Name: "list_dir", Arguments: ``})
+	require.NoError(t, err)
+	assert.Equal(t, "notes.txt\nsub/\n", out)
+
+	_, err = reg.Call(context.Background(), ToolCall{Name: "read_file", Arguments: `{"path":"missing.txt"}`})
+	assert.Error(t, err)
+
+	assert.Equal(t, "write_file a.txt (3 bytes)", reg.Describe(ToolCall{Name: "write_file", Arguments: `{"path":"a.txt","content":"abc"}`}))
+	assert.Equal(t, "list_dir .", reg.Describe(ToolCall{Name: "list_dir", Arguments: `{}`}))
+	assert.Equal(t, "mystery {}", reg.Describe(ToolCall{Name: "mystery", Arguments: `{}`}))
+}
@@ -0,0 +1,39 @@
+package agent
+
+// Transcript is the running conversation between the user and an agent.
+// The zero value is an empty transcript ready to use.
+type Transcript struct {
+	messages []Message
+}
+
+// Append adds a message to the end of the transcript.
+func (t *Transcript) Append(role, content string) {
+	t.messages = append(t.messages, Message{Role: role, Content: content})
+}
+
+// Messages returns a copy of the transcript's messages, oldest first.
+func (t *Transcript) Messages() []Message {
+	out := make([]Message, len(t.messages))
+	copy(out, t.messages)
+	return out
+}
+
+// Len returns the number of messages in the transcript.
+func (t *Transcript) Len() int {
+	return len(t.messages)
+}
+
+// Reset forgets all messages.
+func (t *Transcript) Reset() {
+	t.messages = nil
+}
+
+// Trim keeps only the n most recent messages. A negative n is treated as zero.
+func (t *Transcript) Trim(n int) {
+	if n < 0 {
+		n = 0
+	}
+	if len(t.messages) > n {
+		t.messages = append([]Message(nil), t.messages[len(t.messages)-n:]...)
+	}
+}
@@ -0,0 +1,47 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTranscript_AppendTrimReset(t *testing.T) {
+	var tr Transcript
+	assert.Equal(t, 0, tr.Len())
+
+	tr.Append(RoleUser, "one")
+	tr.Append(RoleAssistant, "two")
+	tr.Append(RoleUser, "three")
+	assert.Equal(t, 3, tr.Len())
+
+	msgs := tr.Messages()
+	msgs[0].Content = "changed"
+	assert.Equal(t, "one", tr.Messages()[0].Content, "Messages should return a copy")
+
+	tr.Trim(2)
+	assert.Equal(t, []Message{{Role: RoleAssistant, Content: "two"}, {Role: RoleUser, Content: "three"}}, tr.Messages())
+
+	tr.Trim(5)
+	assert.Equal(t, 2, tr.Len(), "trimming to more than Len is a no-op")
+
+	tr.Trim(-1)
+	assert.Equal(t, 0, tr.Len())
+
+	tr.Append(RoleUser, "again")
+	tr.Reset()
+	assert.Equal(t, 0, tr.Len())
+}
+
+func TestLastUserMessage(t *testing.T) {
+	assert.Equal(t, "", LastUserMessage(nil))
+	msgs := []Message{
+		{Role: RoleSystem, Content: "sys"},
+		{Role: RoleUser, Content: "first"},
+		{Role: RoleAssistant, Content: "reply"},
+		{Role: RoleUser, Content: "second"},
+		{Role: RoleAssistant, Content: "reply"},
+	}
+	assert.Equal(t, "second", LastUserMessage(msgs))
+	assert.Equal(t, []Message{{Role: RoleUser, Content: "hi"}}, UserPrompt("hi"))
+}
@@ -0,0 +1,266 @@
+// Package cassette records HTTP exchanges with AI providers to files and
+// replays them, so agents can be tested end to end and demoed offline.
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Mode selects whether a Recorder talks to the network or serves a cassette.
+type Mode int
+
+const (
+	// Replay serves recorded responses and never touches the network.
+	Replay Mode = iota
+	// Record performs real requests and saves every exchange.
+	Record
+)
+
+// ParseMode parses "replay" or "record"; "" means Replay.
+func ParseMode(s string) (Mode, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "replay":
+		return Replay, nil
+	case "record":
+		return Record, nil
+	}
+	return Replay, fmt.Errorf("unknown cassette mode %q (want record or replay)", s)
+}
+
+// Redacted replaces secrets in saved cassettes.
+const Redacted = "REDACTED"
+
+// RedactedHeaders are request and response headers whose values are never
+// written to a cassette.
+var RedactedHeaders = []string{"Authorization", "X-Api-Key", "Api-Key", "Cookie", "Set-Cookie"}
+
+// redactedParams are query parameters holding API keys.
+var redactedParams = []string{"key", "api_key", "apikey"}
+
+// Cassette is the file format: the exchanges in the order they happened.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is one request and the response it got.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is a recorded HTTP request.
+type Request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// Response is a recorded HTTP response.
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body"`
+}
+
+// Recorder is an http.RoundTripper that records exchanges to a cassette file
+// or replays them from it. Use it as the Transport of an agent's http.Client.
+type Recorder struct {
+	path string
+	mode Mode
+	// Transport performs real requests in Record mode (http.DefaultTransport when nil).
+	Transport http.RoundTripper
+
+	mu       sync.Mutex
+	cassette Cassette
+	used     []bool // interactions already replayed
+}
+
+// New opens a cassette. Replay loads an existing file; Record starts a new one,
+// replacing any file at path once the first exchange is saved.
+func New(path string, mode Mode) (*Recorder, error) {
+	r := &Recorder{path: path, mode: mode}
+	if mode == Record {
+		return r, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cassette: %w", err)
+	}
+	if err := json.Unmarshal(data, &r.cassette); err != nil {
+		return nil, fmt.Errorf("cassette: %s: %w", path, err)
+	}
+	r.used = make([]bool, len(r.cassette.Interactions))
+	return r, nil
+}
+
+// Client returns an http.Client that sends its requests through r.
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	recorded := Request{Method: req.Method, URL: redactURL(req.URL), Header: redactHeader(req.Header), Body: body}
+	if r.mode == Replay {
+		return r.replay(req, recorded)
+	}
+	return r.record(req, recorded)
+}
+
+// replay serves the first unused interaction with the same method, URL and
+// body, or failing that the first unused one with the same method and URL, so
+// that requests whose body varies between runs (e.g. the described working
+// directory) still replay in order.
+func (r *Recorder) replay(req *http.Request, recorded Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	match := -1
+	for i, in := range r.cassette.Interactions {
+		if r.used[i] || in.Request.Method != recorded.Method || in.Request.URL != recorded.URL {
+			continue
+		}
+		if in.Request.Body == recorded.Body {
+			match = i
+			break
+		}
+		if match < 0 {
+			match = i
+		}
+	}
+	if match < 0 {
+		return nil, fmt.Errorf("cassette: no recorded response for %s %s", recorded.Method, recorded.URL)
+	}
+	r.used[match] = true
+	return r.cassette.Interactions[match].Response.httpResponse(req), nil
+}
+
+func (r *Recorder) record(req *http.Request, recorded Request) (*http.Response, error) {
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	// The whole body is read before it is handed on, so a streamed reply
+	// arrives at once while recording.
+	data, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+		Request:  recorded,
+		Response: Response{StatusCode: resp.StatusCode, Header: redactHeader(resp.Header), Body: string(data)},
+	})
+	if err := r.save(); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (r *Recorder) save() error {
+	data, err := json.MarshalIndent(r.cassette, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(r.path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("cassette: %w", err)
+	}
+	return nil
+}
+
+// readBody returns the request body and restores it for the real transport.
+func readBody(req *http.Request) (string, error) {
+	if req.Body == nil {
+		return "", nil
+	}
+	data, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return "", err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	return string(data), nil
+}
+
+func (r Response) httpResponse(req *http.Request) *http.Response {
+	header := r.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
+
+func redactHeader(h http.Header) http.Header {
+	if len(h) == 0 {
+		return nil
+	}
+	out := h.Clone()
+	for _, name := range RedactedHeaders {
+		if out.Get(name) != "" {
+			out.Set(name, Redacted)
+		}
+	}
+	return out
+}
+
+func redactURL(u *url.URL) string {
+	q := u.Query()
+	changed := false
+	for _, p := range redactedParams {
+		if q.Has(p) {
+			q.Set(p, Redacted)
+			changed = true
+		}
+	}
+	if !changed {
+		return u.String()
+	}
+	c := *u
+	c.RawQuery = q.Encode()
+	return c.String()
+}
+
+// FromEnv opens the cassette named by BINKS_CASSETTE in the mode given by
+// BINKS_CASSETTE_MODE (replay by default). It returns nil when
+// BINKS_CASSETTE is unset.
+func FromEnv() (*Recorder, error) {
+	path := os.Getenv("BINKS_CASSETTE")
+	if path == "" {
+		return nil, nil
+	}
+	mode, err := ParseMode(os.Getenv("BINKS_CASSETTE_MODE"))
+	if err != nil {
+		return nil, err
+	}
+	return New(path, mode)
+}
@@ -0,0 +1,101 @@
+package cassette_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/binks-cli/binks/internal/agent"
+	"github.com/binks-cli/binks/internal/cassette"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorder_RecordThenReplayOpenAIAgent(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer sk-secret", r.Header.Get("Authorization"))
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		if strings.Contains(string(body), "second") {
+			_, _ = io.WriteString(w, `{"choices":[{"message":{"role":"assistant","content":"two"}}]}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{"choices":[{"message":{"role":"assistant","content":"one"}}],"usage":{"prompt_tokens":5,"completion_tokens":1}}`)
+	}))
+	path := filepath.Join(t.TempDir(), "chat.json")
+
+	rec, err := cassette.New(path, cassette.Record)
+	require.NoError(t, err)
+	a := agent.NewOpenAIAgent()
+	a.APIKey = "sk-secret"
+	a.BaseURL = upstream.URL
+	a.Client = rec.Client()
+	resp, err := a.Respond(context.Background(), agent.UserPrompt("first"))
+	require.NoError(t, err)
+	assert.Equal(t, "one", resp)
+	_, err = a.Respond(context.Background(), agent.UserPrompt("second"))
+	require.NoError(t, err)
+	upstream.Close()
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "sk-secret")
+	assert.Contains(t, string(data), cassette.Redacted)
+
+	// Replay serves the exchanges without the network, matching on the body.
+	replay, err := cassette.New(path, cassette.Replay)
+	require.NoError(t, err)
+	a.Client = replay.Client()
+	resp, err = a.Respond(context.Background(), agent.UserPrompt("second"))
+	require.NoError(t, err)
+	assert.Equal(t, "two", resp)
+	resp, err = a.Respond(context.Background(), agent.UserPrompt("first"))
+	require.NoError(t, err)
+	assert.Equal(t, "one", resp)
+	assert.Equal(t, 5, a.LastUsage().PromptTokens)
+}
+
+func TestRecorder_ReplayInOrder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "c.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"interactions":[
+		{"request":{"method":"GET","url":"http://x/models?key=REDACTED","body":"a"},"response":{"status_code":200,"body":"first"}},
+		{"request":{"method":"GET","url":"http://x/models?key=REDACTED","body":"b"},"response":{"status_code":503,"body":"second"}}
+	]}`), 0o644))
+	rec, err := cassette.New(path, cassette.Replay)
+	require.NoError(t, err)
+	client := rec.Client()
+
+	get := func() (int, string) {
+		resp, err := client.Get("http://x/models?key=my-key")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+	code, body := get()
+	assert.Equal(t, 200, code)
+	assert.Equal(t, "first", body)
+	code, body = get()
+	assert.Equal(t, 503, code)
+	assert.Equal(t, "second", body)
+
+	_, err = client.Get("http://x/models?key=my-key")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no recorded response for GET http://x/models?key=REDACTED")
+}
+
+func TestNew_Errors(t *testing.T) {
+	_, err := cassette.New(filepath.Join(t.TempDir(), "missing.json"), cassette.Replay)
+	assert.Error(t, err)
+
+	_, err = cassette.ParseMode("rewind")
+	assert.EqualError(t, err, `unknown cassette mode "rewind" (want record or replay)`)
+	mode, err := cassette.ParseMode("")
+	require.NoError(t, err)
+	assert.Equal(t, cassette.Replay, mode)
+}
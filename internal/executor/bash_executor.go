@@ -1,6 +1,8 @@
 package executor
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -8,19 +10,29 @@ import (
 	"os/signal"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/creack/pty"
 	"golang.org/x/term"
 )
 
 // BashExecutor implements the Executor interface using bash shell
-type BashExecutor struct{}
+type BashExecutor struct {
+	// NoTTY captures the output of interactive commands instead of attaching
+	// them to the terminal, for when stdout carries something else (e.g. MCP).
+	NoTTY bool
+}
 
 // NewBashExecutor creates a new BashExecutor
 func NewBashExecutor() *BashExecutor {
 	return &BashExecutor{}
 }
 
+// Shell returns the name of the shell commands run in
+func (e *BashExecutor) Shell() string {
+	return "bash"
+}
+
 // isAsyncCommand returns true if the command should be run asynchronously (non-blocking)
 func isAsyncCommand(cmd string) (string, bool) {
 	fields := strings.Fields(cmd)
@@ -36,68 +48,139 @@ func isAsyncCommand(cmd string) (string, bool) {
 }
 
 // RunCommandAsyncWithDir launches a command asynchronously (non-blocking)
-func (e *BashExecutor) RunCommandAsyncWithDir(cmd string, dir string) (string, error) {
-	execCmd := exec.Command("bash", "-c", cmd)
+// and returns it as a job without a terminal.
+func (e *BashExecutor) RunCommandAsyncWithDir(cmd string, dir string) (*Job, error) {
+	return runAsync(Bash, cmd, dir)
+}
+
+// Start starts a command using bash as a background job.
+func (e *BashExecutor) Start(cmd, dir string) (*Job, error) {
+	return startInShell(Bash, cmd, dir)
+}
+
+// startInShell starts cmd in a new process of sh as a job with a terminal.
+func startInShell(sh Shell, cmd, dir string) (*Job, error) {
+	execCmd := sh.Command(context.Background(), cmd)
 	if dir != "" {
 		execCmd.Dir = dir
 	}
-	err := execCmd.Start()
-	if err != nil {
-		return "", err
+	return startJob(execCmd, cmd, true)
+}
+
+// runAsync launches cmd in a new process of sh without waiting for it.
+func runAsync(sh Shell, cmd, dir string) (*Job, error) {
+	execCmd := sh.Command(context.Background(), cmd)
+	if dir != "" {
+		execCmd.Dir = dir
 	}
-	// Optionally include PID: fmt.Sprintf("[launched %s (PID %d)]\n", ...)
-	return fmt.Sprintf("[launched %s]\n", strings.Fields(cmd)[0]), nil
+	return startJob(execCmd, cmd, false)
+}
+
+// launched records in res that job was launched for it, and notes it in w.
+func launched(res *CommandResult, job *Job, w io.Writer) {
+	_, _ = fmt.Fprintf(w, "[launched %s]\n", strings.Fields(res.Command)[0])
+	res.Job = job
+	res.ExitCode = 0 // launched; it is not waited for
+}
+
+// waitDelay is how long a cancelled command, or one that left a background
+// process holding its output open, is waited for before giving up on it.
+const waitDelay = time.Second
+
+// RunCommandWithDir executes a command using bash in the specified directory and returns the combined output.
+// The command is killed when ctx is cancelled or its deadline passes.
+func (e *BashExecutor) RunCommandWithDir(ctx context.Context, cmd string, dir string) (string, error) {
+	res, err := e.Run(ctx, cmd, RunOptions{Dir: dir})
+	return res.Combined, err
 }
 
-// RunCommandWithDir executes a command using bash in the specified directory and returns the combined output
-func (e *BashExecutor) RunCommandWithDir(cmd string, dir string) (string, error) {
+// Run runs a command using bash and reports its result.
+func (e *BashExecutor) Run(ctx context.Context, cmd string, opts RunOptions) (*CommandResult, error) {
+	return runCommand(ctx, Bash, e.NoTTY, cmd, opts)
+}
+
+// runCommand runs cmd in a new process of sh: launched in the background for
+// GUI apps, attached to the terminal for interactive programs (unless noTTY),
+// and otherwise with its output recorded and passed on to opts' writers.
+func runCommand(ctx context.Context, sh Shell, noTTY bool, cmd string, opts RunOptions) (*CommandResult, error) {
+	res := newResult(cmd, opts.Dir)
+	rec := newRecorder(opts)
+	defer rec.fill(res)
+	stdout, stderr := rec.writers()
 	if _, ok := isAsyncCommand(cmd); ok {
-		return e.RunCommandAsyncWithDir(cmd, dir)
-	}
-	if isInteractiveCommand(cmd) {
-		execCmd := exec.Command("bash", "-c", cmd)
-		if dir != "" {
-			execCmd.Dir = dir
+		job, err := runAsync(sh, cmd, opts.Dir)
+		res.Ended = time.Now()
+		if err == nil {
+			launched(res, job, stdout)
 		}
-		ptmx, err := pty.Start(execCmd)
-		if err != nil {
-			return "", err
-		}
-		defer func() { _ = ptmx.Close() }()
+		return res, err
+	}
+	execCmd := sh.Command(ctx, cmd)
+	if opts.Dir != "" {
+		execCmd.Dir = opts.Dir
+	}
+	if (isInteractiveCommand(cmd) || WantsTTY(ctx)) && !noTTY {
+		err := attach(ctx, execCmd)
+		res.exited(ctx, execCmd.ProcessState)
+		return res, err
+	}
+	execCmd.Stdout, execCmd.Stderr = stdout, stderr
+	execCmd.WaitDelay = waitDelay
+	err := execCmd.Run()
+	res.exited(ctx, execCmd.ProcessState)
+	return res, contextError(ctx, err)
+}
 
-		// Save the current terminal state
-		oldState, err := term.MakeRaw(int(os.Stdin.Fd()))
-		if err != nil {
-			return "", err
-		}
-		defer func() { _ = term.Restore(int(os.Stdin.Fd()), oldState) }()
-
-		// Handle terminal resize
-		ch := make(chan os.Signal, 1)
-		signal.Notify(ch, syscall.SIGWINCH)
-		go func() {
-			for range ch {
-				_ = pty.InheritSize(os.Stdin, ptmx)
-			}
-		}()
-		ch <- syscall.SIGWINCH // Initial resize
-		defer signal.Stop(ch)
-
-		// Copy stdin/stdout
-		go func() { _, _ = io.Copy(ptmx, os.Stdin) }()
-		_, _ = io.Copy(os.Stdout, ptmx)
-
-		return "", execCmd.Wait()
+// attach runs execCmd attached to the terminal through a pty and waits for it.
+func attach(ctx context.Context, execCmd *exec.Cmd) error {
+	ptmx, err := pty.Start(execCmd)
+	if err != nil {
+		return err
 	}
-	execCmd := exec.Command("bash", "-c", cmd)
-	if dir != "" {
-		execCmd.Dir = dir
+	defer func() { _ = ptmx.Close() }()
+
+	// Save the current terminal state
+	oldState, err := term.MakeRaw(int(os.Stdin.Fd()))
+	if err != nil {
+		return err
 	}
-	output, err := execCmd.CombinedOutput()
-	return string(output), err // Preserve shell output including trailing newlines
+	defer func() { _ = term.Restore(int(os.Stdin.Fd()), oldState) }()
+
+	// Handle terminal resize
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGWINCH)
+	go func() {
+		for range ch {
+			_ = pty.InheritSize(os.Stdin, ptmx)
+		}
+	}()
+	ch <- syscall.SIGWINCH // Initial resize
+	defer signal.Stop(ch)
+
+	// Copy stdin/stdout
+	go func() { _, _ = io.Copy(ptmx, os.Stdin) }()
+	_, _ = io.Copy(os.Stdout, ptmx)
+
+	return contextError(ctx, execCmd.Wait())
 }
 
 // RunCommand executes a command using bash and returns the combined output
-func (e *BashExecutor) RunCommand(cmd string) (string, error) {
-	return e.RunCommandWithDir(cmd, "")
+func (e *BashExecutor) RunCommand(ctx context.Context, cmd string) (string, error) {
+	return e.RunCommandWithDir(ctx, cmd, "")
+}
+
+// contextError reports why a command stopped early: the context's error when it
+// was cancelled or timed out, and no error when the command succeeded but a
+// background process it started kept its output open.
+func contextError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	if errors.Is(err, exec.ErrWaitDelay) {
+		return nil
+	}
+	return err
 }
@@ -1,10 +1,12 @@
 package executor
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -63,7 +65,7 @@ func TestBashExecutor_RunCommand_TableDriven(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			output, err := executor.RunCommand(tc.command)
+			output, err := executor.RunCommand(context.Background(), tc.command)
 			if tc.expectError {
 				if tc.errCheck != nil {
 					tc.errCheck(t, err)
@@ -103,7 +105,7 @@ func TestBashExecutor_RunCommand_MultiLineOutput(t *testing.T) {
 	}
 
 	// List files in the temp directory
-	output, err := executor.RunCommand("ls " + tempDir)
+	output, err := executor.RunCommand(context.Background(), "ls "+tempDir)
 
 	require.NoError(t, err, "Expected no error")
 	// Check that all test files are in the output
@@ -119,13 +121,13 @@ func TestBashExecutor_RunCommand_MultiLineOutput(t *testing.T) {
 func TestBashExecutor_RunCommand_AsyncBackground(t *testing.T) {
 	executor := NewBashExecutor()
 	// Use a command from AsyncCommands (e.g., 'open' or 'sleep' as a dummy)
-	output, err := executor.RunCommand("open /tmp")
+	output, err := executor.RunCommand(context.Background(), "open /tmp")
 	// On macOS, 'open' should exist; on Linux/Windows, may not, so allow error but check output
 	if err == nil {
 		assert.Contains(t, output, "[launched open]", "Expected async launch message")
 	}
 	// Also test a non-async command to ensure it does not return async message
-	output, err = executor.RunCommand("echo async-test")
+	output, err = executor.RunCommand(context.Background(), "echo async-test")
 	assert.NoError(t, err)
 	assert.Equal(t, "async-test\n", output)
 }
@@ -156,21 +158,55 @@ func TestBashExecutor_RunCommandWithDir_InteractiveAndAsync(t *testing.T) {
 	dir := t.TempDir()
 
 	// Async command (should return launch message)
-	output, err := executor.RunCommandWithDir("open /tmp", dir)
+	output, err := executor.RunCommandWithDir(context.Background(), "open /tmp", dir)
 	if err == nil {
 		assert.Contains(t, output, "[launched open]")
 	}
 
 	// Interactive command (simulate with a non-blocking command)
 	// We can't fully test PTY without a TTY, but we can check for error or empty output
-	output, err = executor.RunCommandWithDir("vim --version", dir)
+	output, err = executor.RunCommandWithDir(context.Background(), "vim --version", dir)
 	// Accept error or empty output (since PTY may not work in test env)
 	if err != nil {
 		assert.Empty(t, output)
 	}
 
 	// Normal command
-	output, err = executor.RunCommandWithDir("echo hi", dir)
+	output, err = executor.RunCommandWithDir(context.Background(), "echo hi", dir)
 	assert.NoError(t, err)
 	assert.Equal(t, "hi\n", output)
 }
+
+func TestBashExecutor_NoTTYCapturesInteractiveCommands(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("paged\n"), 0o644))
+	executor := &BashExecutor{NoTTY: true}
+	output, err := executor.RunCommandWithDir(context.Background(), "less notes.txt", dir)
+	if err != nil {
+		t.Skip("less not available:", err)
+	}
+	assert.Equal(t, "paged\n", output)
+}
+
+func TestBashExecutor_RunCommandCancelled(t *testing.T) {
+	executor := NewBashExecutor()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := executor.RunCommand(ctx, "sleep 5")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 3*time.Second)
+}
+
+func TestBashExecutor_BackgroundProcessDoesNotBlock(t *testing.T) {
+	executor := NewBashExecutor()
+	start := time.Now()
+	output, err := executor.RunCommand(context.Background(), "sleep 5 & echo started")
+	assert.NoError(t, err)
+	assert.Equal(t, "started\n", output)
+	assert.Less(t, time.Since(start), 4*time.Second)
+}
+
+func TestBashExecutor_Run(t *testing.T) {
+	testRunner(t, NewBashExecutor())
+}
@@ -0,0 +1,40 @@
+package executor
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// MaxCapture is how much of a streamed command's output is kept for its
+// caller. The output itself is streamed in full; only the copy is cut.
+const MaxCapture = 64 * 1024
+
+// capture keeps what is written to it, or only the last max bytes when max is
+// set.
+type capture struct {
+	max     int
+	buf     []byte
+	dropped int
+}
+
+func (c *capture) Write(p []byte) (int, error) {
+	c.buf = append(c.buf, p...)
+	if over := len(c.buf) - c.max; c.max > 0 && over > 0 {
+		// Cut at a line boundary when there is one close by, so the copy does
+		// not start mid-line.
+		if i := bytes.IndexByte(c.buf[over:], '\n'); i >= 0 && i < 1024 {
+			over += i + 1
+		}
+		c.dropped += over
+		c.buf = append(c.buf[:0], c.buf[over:]...)
+	}
+	return len(p), nil
+}
+
+// String returns the output kept, after a note of how much was left out.
+func (c *capture) String() string {
+	if c.dropped > 0 {
+		return fmt.Sprintf("[... %d bytes of output omitted ...]\n", c.dropped) + string(c.buf)
+	}
+	return string(c.buf)
+}
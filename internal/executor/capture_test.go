@@ -0,0 +1,27 @@
+package executor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCapture(t *testing.T) {
+	c := &capture{max: MaxCapture}
+	_, _ = c.Write([]byte("short\n"))
+	assert.Equal(t, "short\n", c.String())
+
+	line := strings.Repeat("x", 99) + "\n"
+	for i := 0; i < MaxCapture/100+10; i++ {
+		n, err := c.Write([]byte(line))
+		assert.NoError(t, err)
+		assert.Equal(t, 100, n)
+	}
+	output := c.String()
+	note, kept, _ := strings.Cut(output, "\n")
+	assert.Regexp(t, `^\[\.\.\. \d+ bytes of output omitted \.\.\.\]$`, note)
+	assert.LessOrEqual(t, len(kept), MaxCapture)
+	assert.True(t, strings.HasPrefix(kept, line), "the copy starts at a line")
+	assert.Equal(t, strings.Repeat(line, len(kept)/100), kept)
+}
@@ -1,6 +1,43 @@
 package executor
 
-// Executor defines the interface for command execution
+import (
+	"context"
+	"fmt"
+)
+
+// Executor defines the interface for command execution. Cancelling ctx stops
+// the running command.
 type Executor interface {
-	RunCommand(cmd string) (string, error)
+	RunCommand(ctx context.Context, cmd string) (string, error)
+}
+
+// ShellNamer is implemented by executors that run commands through a named shell.
+type ShellNamer interface {
+	Shell() string
+}
+
+// DirRunner is implemented by executors that can run a command in a given
+// working directory.
+type DirRunner interface {
+	RunCommandWithDir(ctx context.Context, cmd, dir string) (string, error)
+}
+
+// DirTracker is implemented by executors whose commands can change the
+// working directory. Dir returns it as of the last command, or "" if unknown.
+type DirTracker interface {
+	Dir() string
+}
+
+// ExitError reports that a command ran but exited with a non-zero status.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// ExitCode returns the command's exit status, like exec.ExitError does.
+func (e *ExitError) ExitCode() int {
+	return e.Code
 }
@@ -1,5 +1,22 @@
 package executor
 
+import "context"
+
+type ttyKey struct{}
+
+// WithTTY marks ctx so that a command run with it is attached to the terminal
+// like the known interactive programs, e.g. when the AI says it needs one.
+func WithTTY(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ttyKey{}, true)
+}
+
+// WantsTTY reports whether ctx was marked by WithTTY. Executors that can
+// attach a command to the terminal should do so when it is.
+func WantsTTY(ctx context.Context) bool {
+	on, _ := ctx.Value(ttyKey{}).(bool)
+	return on
+}
+
 // isInteractiveCommand returns true if the command is known to require an attached terminal (interactive mode)
 func isInteractiveCommand(cmd string) bool {
 	// List of common interactive commands
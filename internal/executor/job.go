@@ -0,0 +1,309 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/creack/pty"
+	"golang.org/x/sys/unix"
+	"golang.org/x/term"
+)
+
+// Job is a command left running in the background. Its output is kept until
+// it is read, and it can be signalled, stopped, continued and brought to the
+// foreground.
+//
+// A job started with a terminal runs in a pseudo-terminal of its own, so that
+// it can be attached to the real one later; a job that reads input waits
+// for it until then. GUI apps get no terminal, and keep running after binks exits.
+type Job struct {
+	Command string
+
+	cmd     *exec.Cmd
+	pty     *os.File      // the job's terminal, or nil
+	done    chan struct{} // closed when the job has exited
+	mu      sync.Mutex
+	res     *CommandResult
+	pending capture   // output not yet read
+	fg      io.Writer // receives the output while the job is in the foreground
+	stopped bool
+}
+
+// inputPoll is how long a job in the foreground waits for a key before
+// checking whether it still has the terminal.
+const inputPoll = 50 * time.Millisecond
+
+// startJob starts execCmd as a job, in a terminal of its own if tty is set.
+func startJob(execCmd *exec.Cmd, cmd string, tty bool) (*Job, error) {
+	j := &Job{Command: cmd, cmd: execCmd, done: make(chan struct{}), pending: capture{max: MaxCapture}}
+	j.res = newResult(cmd, execCmd.Dir)
+	var read chan struct{}
+	if tty {
+		ptmx, err := pty.Start(execCmd)
+		if err != nil {
+			return nil, err
+		}
+		if pty.InheritSize(os.Stdin, ptmx) != nil {
+			_ = pty.Setsize(ptmx, &pty.Winsize{Rows: 24, Cols: 80})
+		}
+		j.pty, read = ptmx, make(chan struct{})
+		go func() {
+			defer close(read)
+			b := make([]byte, 32*1024)
+			for {
+				n, err := ptmx.Read(b)
+				if n > 0 {
+					j.write(b[:n])
+				}
+				if err != nil {
+					return
+				}
+			}
+		}()
+	} else {
+		// A group of its own, so that signals reach what the app started.
+		execCmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+		if err := execCmd.Start(); err != nil {
+			return nil, err
+		}
+	}
+	go func() {
+		_ = execCmd.Wait()
+		if read != nil {
+			// Output still in the terminal is read unless something the job
+			// started holds it open.
+			select {
+			case <-read:
+			case <-time.After(waitDelay):
+			}
+			_ = j.pty.Close()
+		}
+		j.mu.Lock()
+		j.res.exited(context.Background(), execCmd.ProcessState)
+		j.stopped = false
+		j.mu.Unlock()
+		close(j.done)
+	}()
+	return j, nil
+}
+
+// write passes output from the job on to the foreground, or keeps it.
+func (j *Job) write(p []byte) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.fg != nil {
+		_, _ = j.fg.Write(p)
+		return
+	}
+	_, _ = j.pending.Write(p)
+}
+
+// Pid returns the job's process ID.
+func (j *Job) Pid() int {
+	return j.cmd.Process.Pid
+}
+
+// Done returns a channel that is closed when the job has exited.
+func (j *Job) Done() <-chan struct{} {
+	return j.done
+}
+
+// Result returns how the job ended, or nil while it is running. The result
+// holds no output; that is read with Output.
+func (j *Job) Result() *CommandResult {
+	select {
+	case <-j.done:
+		return j.res
+	default:
+		return nil
+	}
+}
+
+// Output returns the output the job wrote since it was last read, up to the
+// last MaxCapture bytes.
+func (j *Job) Output() string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.takeOutput()
+}
+
+// takeOutput returns and clears the pending output, with the line endings the
+// job's terminal added made plain. j.mu must be held.
+func (j *Job) takeOutput() string {
+	output := strings.ReplaceAll(j.pending.String(), "\r\n", "\n")
+	j.pending = capture{max: MaxCapture}
+	return output
+}
+
+// Signal sends sig to the job and everything it started.
+func (j *Job) Signal(sig syscall.Signal) error {
+	if j.Result() != nil {
+		return errors.New("the job has finished")
+	}
+	return syscall.Kill(-j.Pid(), sig)
+}
+
+// Stop suspends the job.
+func (j *Job) Stop() error {
+	if err := j.Signal(syscall.SIGSTOP); err != nil {
+		return err
+	}
+	j.mu.Lock()
+	j.stopped = true
+	j.mu.Unlock()
+	return nil
+}
+
+// Continue resumes a stopped job in the background.
+func (j *Job) Continue() error {
+	if err := j.Signal(syscall.SIGCONT); err != nil {
+		return err
+	}
+	j.mu.Lock()
+	j.stopped = false
+	j.mu.Unlock()
+	return nil
+}
+
+// Stopped reports whether the job is suspended.
+func (j *Job) Stopped() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.stopped
+}
+
+// Foreground resumes the job if it is stopped and writes its output to out,
+// starting with what it wrote in the background, until it exits.
+//
+// When in is a terminal and the job has one, the job is attached to it: keys
+// go to the job, and Ctrl+Z stops it and hands the terminal back, which
+// Foreground reports as detached. Otherwise cancelling ctx interrupts the
+// job and leaves it in the background.
+func (j *Job) Foreground(ctx context.Context, in *os.File, out io.Writer) (detached bool, err error) {
+	if j.Stopped() {
+		if err := j.Continue(); err != nil {
+			return false, err
+		}
+	}
+	attach := j.pty != nil && in != nil && term.IsTerminal(int(in.Fd()))
+	j.mu.Lock()
+	if attach {
+		_, _ = out.Write(j.pending.buf) // the terminal takes the job's line endings as they are
+		j.pending = capture{max: MaxCapture}
+		j.fg = out
+	} else {
+		_, _ = io.WriteString(out, j.takeOutput())
+		j.fg = plainLines{out}
+	}
+	j.mu.Unlock()
+	defer func() {
+		j.mu.Lock()
+		j.fg = nil
+		j.mu.Unlock()
+	}()
+	if !attach {
+		select {
+		case <-j.done:
+			return false, nil
+		case <-ctx.Done():
+			_ = j.Signal(syscall.SIGINT)
+			return true, ctx.Err()
+		}
+	}
+
+	oldState, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = term.Restore(int(in.Fd()), oldState) }()
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGWINCH)
+	go func() {
+		for range ch {
+			_ = pty.InheritSize(in, j.pty)
+		}
+	}()
+	ch <- syscall.SIGWINCH
+	defer func() {
+		signal.Stop(ch)
+		close(ch)
+	}()
+
+	suspend, stop := make(chan struct{}), make(chan struct{})
+	var reading sync.WaitGroup
+	reading.Add(1)
+	go func() {
+		defer reading.Done()
+		j.forwardInput(int(in.Fd()), stop, suspend)
+	}()
+	// The reader is stopped before the terminal goes back to the REPL, so
+	// that it does not take keys meant for it.
+	defer func() {
+		close(stop)
+		reading.Wait()
+	}()
+	select {
+	case <-j.done:
+		return false, nil
+	case <-suspend:
+		return true, j.Stop()
+	}
+}
+
+// forwardInput copies keys from the terminal fd to the job's terminal until
+// stop is closed, closing suspend when Ctrl+Z is pressed. It only reads once
+// input is waiting, so that it never blocks past stop.
+func (j *Job) forwardInput(fd int, stop <-chan struct{}, suspend chan<- struct{}) {
+	fds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}
+	b := make([]byte, 1024)
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		ready, err := unix.Poll(fds, int(inputPoll/time.Millisecond))
+		if err == unix.EINTR || (err == nil && ready == 0) {
+			continue
+		}
+		if err != nil {
+			return
+		}
+		n, err := unix.Read(fd, b)
+		if err == unix.EINTR || err == unix.EAGAIN {
+			continue
+		}
+		if err != nil || n == 0 {
+			return
+		}
+		if i := strings.IndexByte(string(b[:n]), 0x1a); i >= 0 {
+			_, _ = j.pty.Write(b[:i])
+			close(suspend)
+			return
+		}
+		if _, err := j.pty.Write(b[:n]); err != nil {
+			return
+		}
+	}
+}
+
+// plainLines turns the CRLF line endings of a job's terminal into plain
+// newlines, for output that is not going to a terminal.
+type plainLines struct {
+	w io.Writer
+}
+
+func (p plainLines) Write(b []byte) (int, error) {
+	if _, err := io.WriteString(p.w, strings.ReplaceAll(string(b), "\r\n", "\n")); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
@@ -0,0 +1,169 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/creack/pty"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// waitJob waits for j to exit and returns its result.
+func waitJob(t *testing.T, j *Job) *CommandResult {
+	t.Helper()
+	select {
+	case <-j.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("job did not finish")
+	}
+	return j.Result()
+}
+
+func TestJob_KeepsOutputAndExitCode(t *testing.T) {
+	dir := t.TempDir()
+	j, err := NewBashExecutor().Start("pwd; echo background; exit 3", dir)
+	require.NoError(t, err)
+	assert.Equal(t, "pwd; echo background; exit 3", j.Command)
+	assert.Positive(t, j.Pid())
+
+	res := waitJob(t, j)
+	assert.Equal(t, 3, res.ExitCode)
+	assert.Equal(t, dir, res.Dir)
+	assert.Equal(t, dir+"\nbackground\n", j.Output(), "terminal line endings are made plain")
+	assert.Empty(t, j.Output(), "output is only returned once")
+}
+
+func TestJob_StopContinueAndSignal(t *testing.T) {
+	j, err := NewBashExecutor().Start("sleep 5", "")
+	require.NoError(t, err)
+	assert.Nil(t, j.Result(), "running")
+
+	require.NoError(t, j.Stop())
+	assert.True(t, j.Stopped())
+	require.NoError(t, j.Continue())
+	assert.False(t, j.Stopped())
+
+	require.NoError(t, j.Signal(syscall.SIGTERM))
+	res := waitJob(t, j)
+	assert.Equal(t, syscall.SIGTERM, res.Signal)
+	assert.Equal(t, 128+int(syscall.SIGTERM), res.ExitCode)
+	assert.Error(t, j.Signal(syscall.SIGTERM), "the job has finished")
+}
+
+func TestJob_Foreground(t *testing.T) {
+	j, err := NewBashExecutor().Start("echo before; sleep 0.3; echo after", "")
+	require.NoError(t, err)
+	time.Sleep(100 * time.Millisecond)
+
+	var out bytes.Buffer
+	detached, err := j.Foreground(context.Background(), nil, &out)
+	require.NoError(t, err)
+	assert.False(t, detached)
+	assert.Equal(t, "before\nafter\n", out.String(), "output from the background, then as it arrives")
+	assert.Equal(t, 0, j.Result().ExitCode)
+}
+
+func TestJob_ForegroundInterrupted(t *testing.T) {
+	j, err := NewBashExecutor().Start("sleep 5", "")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	detached, err := j.Foreground(ctx, nil, &bytes.Buffer{})
+	assert.True(t, detached)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, syscall.SIGINT, waitJob(t, j).Signal)
+}
+
+func TestJob_ForegroundContinuesStoppedJob(t *testing.T) {
+	j, err := NewBashExecutor().Start("sleep 0.2; echo resumed", "")
+	require.NoError(t, err)
+	require.NoError(t, j.Stop())
+
+	var out bytes.Buffer
+	detached, err := j.Foreground(context.Background(), nil, &out)
+	require.NoError(t, err)
+	assert.False(t, detached)
+	assert.Equal(t, "resumed\n", out.String())
+}
+
+func TestJob_ForegroundLeavesLaterInput(t *testing.T) {
+	ptmx, tty, err := pty.Open()
+	require.NoError(t, err)
+	defer ptmx.Close()
+	defer tty.Close()
+	j, err := NewBashExecutor().Start("sleep 0.2", "")
+	require.NoError(t, err)
+
+	detached, err := j.Foreground(context.Background(), tty, &bytes.Buffer{})
+	require.NoError(t, err)
+	assert.False(t, detached)
+
+	_, err = ptmx.Write([]byte("next\n"))
+	require.NoError(t, err)
+	got := make(chan string, 1)
+	go func() {
+		b := make([]byte, 64)
+		n, _ := tty.Read(b)
+		got <- string(b[:n])
+	}()
+	select {
+	case line := <-got:
+		assert.Equal(t, "next\n", line, "input after fg is left for the REPL")
+	case <-time.After(2 * time.Second):
+		t.Fatal("input typed after fg returned was taken")
+	}
+}
+
+func TestJob_ForegroundCtrlZ(t *testing.T) {
+	ptmx, tty, err := pty.Open()
+	require.NoError(t, err)
+	defer ptmx.Close()
+	defer tty.Close()
+	j, err := NewBashExecutor().Start("sleep 5", "")
+	require.NoError(t, err)
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		_, _ = ptmx.Write([]byte{0x1a})
+	}()
+	detached, err := j.Foreground(context.Background(), tty, &bytes.Buffer{})
+	require.NoError(t, err)
+	assert.True(t, detached)
+	assert.True(t, j.Stopped())
+	require.NoError(t, j.Signal(syscall.SIGKILL))
+	waitJob(t, j)
+}
+
+func TestBashExecutor_RunCommandAsyncWithDir(t *testing.T) {
+	dir := t.TempDir()
+	j, err := NewBashExecutor().RunCommandAsyncWithDir("exit 2", dir)
+	require.NoError(t, err)
+	res := waitJob(t, j)
+	assert.Equal(t, 2, res.ExitCode)
+	assert.Equal(t, dir, res.Dir)
+}
+
+func TestRun_LaunchedCommandIsAJob(t *testing.T) {
+	res, err := NewBashExecutor().Run(context.Background(), "open /nonexistent", RunOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "[launched open]\n", res.Stdout)
+	require.NotNil(t, res.Job)
+	waitJob(t, res.Job)
+}
+
+func TestPersistentExecutor_Start(t *testing.T) {
+	e := NewPersistentExecutor()
+	defer e.Close()
+	_, err := e.RunCommand(context.Background(), "export JOB_VAR=kept; cd /")
+	require.NoError(t, err)
+
+	j, err := e.Start("echo $JOB_VAR; pwd", "")
+	require.NoError(t, err)
+	waitJob(t, j)
+	assert.Equal(t, "kept\n/\n", j.Output())
+}
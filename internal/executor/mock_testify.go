@@ -1,15 +1,38 @@
 package executor
 
-import "github.com/stretchr/testify/mock"
+import (
+	"context"
+	"io"
 
-// MockExecutorTestify is a testify-based mock for the Executor interface
+	"github.com/stretchr/testify/mock"
+)
+
+// MockExecutorTestify is a testify-based mock for the Executor and Runner interfaces
 // Used to replace manual MockExecutor in tests
 type MockExecutorTestify struct {
 	mock.Mock
 }
 
-// RunCommand mocks the Executor's RunCommand method.
-func (m *MockExecutorTestify) RunCommand(cmd string) (string, error) {
-	args := m.Called(cmd)
-	return args.String(0), args.Error(1)
+// Run mocks the Runner's Run method. The result's output is written to the
+// writers in opts, as a Runner streams it. A missing result is returned as an
+// empty one, since a Runner's result is never nil.
+func (m *MockExecutorTestify) Run(ctx context.Context, cmd string, opts RunOptions) (*CommandResult, error) {
+	args := m.Called(ctx, cmd, opts)
+	res, _ := args.Get(0).(*CommandResult)
+	if res == nil {
+		res = &CommandResult{Command: cmd, ExitCode: -1}
+	}
+	if opts.Stdout != nil {
+		_, _ = io.WriteString(opts.Stdout, res.Stdout)
+	}
+	if opts.Stderr != nil {
+		_, _ = io.WriteString(opts.Stderr, res.Stderr)
+	}
+	return res, args.Error(1)
+}
+
+// RunCommand runs the command through Run and returns its combined output.
+func (m *MockExecutorTestify) RunCommand(ctx context.Context, cmd string) (string, error) {
+	res, err := m.Run(ctx, cmd, RunOptions{})
+	return res.Combined, err
 }
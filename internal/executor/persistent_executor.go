@@ -0,0 +1,398 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// PersistentExecutor runs commands in one long-lived shell process instead of
+// a new one per command, so that exported variables, aliases, functions and
+// sourced scripts (e.g. a virtualenv's activate) carry over between commands.
+//
+// Each command is followed by a marker line on stdout holding its exit status
+// and the shell's working directory, and one on stderr, which is how its
+// output is split from the next one's and how a cd inside a command is
+// noticed. Interactive and GUI programs still get a process of their own,
+// started with the shell's exported environment.
+type PersistentExecutor struct {
+	// NoTTY captures the output of interactive commands instead of attaching
+	// them to the terminal, as for BashExecutor.
+	NoTTY bool
+
+	shell  Shell
+	mu     sync.Mutex
+	sh     *shellProcess
+	dir    string // the shell's working directory after the last command
+	marker string
+}
+
+// Output streams of the shell, indexing shellProcess.pipes and buf.
+const (
+	streamStdout = iota
+	streamStderr
+)
+
+// shellProcess is a running shell and the output it has written so far.
+type shellProcess struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	pipes  [2]*os.File   // read ends of the pipes the shell writes stdout and stderr to
+	chunks chan chunk    // output as it is read; closed when the shell exits
+	quit   chan struct{} // closed to stop reading
+	buf    [2][]byte     // output read but not yet passed on, per stream
+}
+
+// chunk is output read from one of the shell's streams.
+type chunk struct {
+	stream int
+	data   []byte
+}
+
+// NewPersistentExecutor creates a PersistentExecutor using bash. Its shell
+// starts with the first command.
+func NewPersistentExecutor() *PersistentExecutor {
+	e, _ := NewPersistentShellExecutor(Bash)
+	return e
+}
+
+// NewPersistentShellExecutor creates a PersistentExecutor using sh, which
+// must be a POSIX shell: bash, zsh or sh.
+func NewPersistentShellExecutor(sh Shell) (*PersistentExecutor, error) {
+	if sh.Name == "fish" {
+		return nil, errors.New("a persistent shell is not supported for fish")
+	}
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return &PersistentExecutor{shell: sh, marker: "binks-" + hex.EncodeToString(b)}, nil
+}
+
+// Shell returns the name of the shell commands run in
+func (e *PersistentExecutor) Shell() string {
+	return e.shell.Name
+}
+
+// Dir returns the shell's working directory after the last command, or "" if
+// no shell is running.
+func (e *PersistentExecutor) Dir() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.dir
+}
+
+// RunCommand runs a command in the shell and returns its combined output.
+func (e *PersistentExecutor) RunCommand(ctx context.Context, cmd string) (string, error) {
+	return e.RunCommandWithDir(ctx, cmd, "")
+}
+
+// RunCommandWithDir runs a command in the shell after changing to dir, unless
+// dir is empty. When ctx is cancelled the command is interrupted; if it does
+// not stop, the shell is killed and a new one starts with the next command.
+func (e *PersistentExecutor) RunCommandWithDir(ctx context.Context, cmd, dir string) (string, error) {
+	res, err := e.Run(ctx, cmd, RunOptions{Dir: dir})
+	return res.Combined, err
+}
+
+// Run runs a command like RunCommandWithDir and reports its result.
+func (e *PersistentExecutor) Run(ctx context.Context, cmd string, opts RunOptions) (*CommandResult, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	res := newResult(cmd, opts.Dir)
+	rec := newRecorder(opts)
+	defer rec.fill(res)
+	stdout, stderr := rec.writers()
+	if err := e.start(opts.Dir); err != nil {
+		res.Ended = time.Now()
+		return res, err
+	}
+	if opts.Dir == "" {
+		res.Dir = e.dir
+	}
+	_, async := isAsyncCommand(cmd)
+	if async || (isInteractiveCommand(cmd) || WantsTTY(ctx)) && !e.NoTTY {
+		env, err := e.environ(ctx)
+		if err != nil {
+			res.Ended = time.Now()
+			return res, err
+		}
+		execCmd := e.shell.Command(ctx, cmd)
+		if async {
+			execCmd = e.shell.Command(context.Background(), cmd) // outlives the line that launched it
+		}
+		execCmd.Dir, execCmd.Env = res.Dir, env
+		if async {
+			job, err := startJob(execCmd, cmd, false)
+			res.Ended = time.Now()
+			if err == nil {
+				launched(res, job, stdout)
+			}
+			return res, err
+		}
+		err = attach(ctx, execCmd)
+		res.exited(ctx, execCmd.ProcessState)
+		return res, err
+	}
+
+	script := ""
+	if opts.Dir != "" && opts.Dir != e.dir {
+		script = "cd -- " + e.shell.Quote(opts.Dir) + " && "
+	}
+	// The command reads /dev/null rather than the script that follows it, and
+	// runs in a group rather than a subshell so that its changes persist.
+	script += "{ eval " + e.shell.Quote(cmd) + "\n} </dev/null\n"
+	status, err := e.run(ctx, script, stdout, stderr)
+	res.Ended = time.Now()
+	if err != nil {
+		return res, err
+	}
+	res.setExitCode(status)
+	if status != 0 {
+		return res, &ExitError{Code: status}
+	}
+	return res, nil
+}
+
+// Start starts a command as a background job, in a new process of the shell
+// with the persistent shell's directory and environment. What the job changes
+// does not carry over to the persistent shell.
+func (e *PersistentExecutor) Start(cmd, dir string) (*Job, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.start(dir); err != nil {
+		return nil, err
+	}
+	env, err := e.environ(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	if dir == "" {
+		dir = e.dir
+	}
+	execCmd := e.shell.Command(context.Background(), cmd)
+	execCmd.Dir, execCmd.Env = dir, env
+	return startJob(execCmd, cmd, true)
+}
+
+// Close stops the shell.
+func (e *PersistentExecutor) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.kill()
+	return nil
+}
+
+// start launches the shell in dir if it is not running.
+func (e *PersistentExecutor) start(dir string) error {
+	if e.sh != nil {
+		return nil
+	}
+	var args []string
+	if e.shell.Name == "bash" {
+		args = []string{"--noprofile", "--norc"}
+	}
+	cmd := exec.Command(e.shell.program(), args...)
+	if dir != "" {
+		// With PWD set the shell keeps the path as given rather than resolving
+		// symlinks, so that it matches the session's directory.
+		cmd.Dir, cmd.Env = dir, append(os.Environ(), "PWD="+dir)
+	}
+	sh := &shellProcess{cmd: cmd, chunks: make(chan chunk), quit: make(chan struct{})}
+	var writeEnds [2]*os.File
+	for i := range sh.pipes {
+		r, w, err := os.Pipe()
+		if err != nil {
+			closeAll(sh.pipes[:i]...)
+			closeAll(writeEnds[:i]...)
+			return err
+		}
+		sh.pipes[i], writeEnds[i] = r, w
+	}
+	cmd.Stdout, cmd.Stderr = writeEnds[streamStdout], writeEnds[streamStderr]
+	// Its own process group, so an interrupt reaches the running command
+	// without reaching binks.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	stdin, err := cmd.StdinPipe()
+	if err == nil {
+		err = cmd.Start()
+	}
+	closeAll(writeEnds[:]...)
+	if err != nil {
+		closeAll(sh.pipes[:]...)
+		return err
+	}
+	sh.stdin = stdin
+	var readers sync.WaitGroup
+	for i, r := range sh.pipes {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				b := make([]byte, 32*1024)
+				n, err := r.Read(b)
+				if n > 0 {
+					select {
+					case sh.chunks <- chunk{i, b[:n]}:
+					case <-sh.quit:
+						return
+					}
+				}
+				if err != nil {
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		readers.Wait()
+		close(sh.chunks)
+	}()
+	e.sh = sh
+	// A trapped SIGINT is reset for the commands the shell runs, so Ctrl+C
+	// stops the command while the shell, and its state, lives on.
+	init := "trap : INT\n"
+	if e.shell.Name == "bash" {
+		init += "shopt -s expand_aliases\n"
+	}
+	_, err = e.run(context.Background(), init+e.shell.rcScript(), io.Discard, io.Discard)
+	return err
+}
+
+// closeAll closes files, ignoring errors.
+func closeAll(files ...*os.File) {
+	for _, f := range files {
+		_ = f.Close()
+	}
+}
+
+// run sends script to the shell, writes its output to stdout and stderr as it
+// arrives, up to the markers that follow it, and returns the exit status of
+// its last command.
+func (e *PersistentExecutor) run(ctx context.Context, script string, stdout, stderr io.Writer) (int, error) {
+	sh := e.sh
+	script += fmt.Sprintf("printf '\\036%s %%d %%s\\n' \"$?\" \"$PWD\"; printf '\\036%s\\n' >&2\n", e.marker, e.marker)
+	if _, err := io.WriteString(sh.stdin, script); err != nil {
+		e.kill()
+		return -1, fmt.Errorf("shell exited: %w", err)
+	}
+	out := [2]io.Writer{stdout, stderr}
+	// rest passes on the output read but not yet passed on.
+	rest := func() {
+		for i, w := range out {
+			_, _ = w.Write(sh.buf[i])
+			sh.buf[i] = nil
+		}
+	}
+	interrupted := false
+	done := ctx.Done()
+	var timer <-chan time.Time
+	for {
+		if status, ok := e.parse(out); ok {
+			if interrupted {
+				return status, ctx.Err()
+			}
+			return status, nil
+		}
+		e.flush(out)
+		select {
+		case c, ok := <-sh.chunks:
+			if !ok {
+				// The command ended the shell, e.g. with exit.
+				rest()
+				closeAll(sh.pipes[:]...)
+				err := sh.cmd.Wait()
+				e.sh, e.dir = nil, ""
+				if interrupted {
+					return -1, ctx.Err()
+				}
+				var ee *exec.ExitError
+				if errors.As(err, &ee) {
+					return ee.ExitCode(), nil
+				}
+				return 0, err
+			}
+			sh.buf[c.stream] = append(sh.buf[c.stream], c.data...)
+		case <-done:
+			interrupted, done = true, nil
+			_ = syscall.Kill(-sh.cmd.Process.Pid, syscall.SIGINT)
+			timer = time.After(waitDelay)
+		case <-timer:
+			// The command ignored the interrupt.
+			rest()
+			e.kill()
+			return -1, ctx.Err()
+		}
+	}
+}
+
+// flush passes on the output read so far, holding back from the first byte
+// that could start a marker line until it is known whether it does.
+func (e *PersistentExecutor) flush(out [2]io.Writer) {
+	sh := e.sh
+	for i, w := range out {
+		n := len(sh.buf[i])
+		if j := bytes.IndexByte(sh.buf[i], '\x1e'); j >= 0 {
+			n = j
+		}
+		if n > 0 {
+			_, _ = w.Write(sh.buf[i][:n])
+			sh.buf[i] = append(sh.buf[i][:0], sh.buf[i][n:]...)
+		}
+	}
+}
+
+// parse passes on the rest of a finished command's output once the markers
+// on both streams have arrived, and records the shell's working directory.
+func (e *PersistentExecutor) parse(out [2]io.Writer) (int, bool) {
+	sh := e.sh
+	i := bytes.Index(sh.buf[streamStdout], []byte("\x1e"+e.marker+" "))
+	j := bytes.Index(sh.buf[streamStderr], []byte("\x1e"+e.marker+"\n"))
+	if i < 0 || j < 0 {
+		return 0, false
+	}
+	rest := sh.buf[streamStdout][i+len(e.marker)+2:]
+	end := bytes.IndexByte(rest, '\n')
+	if end < 0 {
+		return 0, false
+	}
+	_, _ = out[streamStdout].Write(sh.buf[streamStdout][:i])
+	_, _ = out[streamStderr].Write(sh.buf[streamStderr][:j])
+	code, pwd, _ := strings.Cut(string(rest[:end]), " ")
+	sh.buf[streamStdout] = append([]byte(nil), rest[end+1:]...)
+	sh.buf[streamStderr] = append([]byte(nil), sh.buf[streamStderr][j+len(e.marker)+2:]...)
+	status, _ := strconv.Atoi(code)
+	e.dir = pwd
+	return status, true
+}
+
+// environ returns the shell's exported environment.
+func (e *PersistentExecutor) environ(ctx context.Context) ([]string, error) {
+	var env bytes.Buffer
+	if _, err := e.run(ctx, "env -0\n", &env, io.Discard); err != nil {
+		return nil, err
+	}
+	return strings.Split(strings.TrimSuffix(env.String(), "\x00"), "\x00"), nil
+}
+
+// kill stops the shell and everything it started.
+func (e *PersistentExecutor) kill() {
+	if e.sh == nil {
+		return
+	}
+	close(e.sh.quit)
+	_ = e.sh.stdin.Close()
+	closeAll(e.sh.pipes[:]...)
+	_ = syscall.Kill(-e.sh.cmd.Process.Pid, syscall.SIGKILL)
+	_ = e.sh.cmd.Wait()
+	e.sh, e.dir = nil, ""
+}
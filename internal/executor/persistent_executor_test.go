@@ -0,0 +1,167 @@
+package executor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPersistent(t *testing.T) *PersistentExecutor {
+	t.Helper()
+	e := NewPersistentExecutor()
+	e.NoTTY = true
+	t.Cleanup(func() { _ = e.Close() })
+	return e
+}
+
+func TestPersistentExecutor_StateSurvivesBetweenCommands(t *testing.T) {
+	e := newPersistent(t)
+	ctx := context.Background()
+	steps := []struct {
+		command string
+		expect  string
+	}{
+		{"export GREETING=hello; NAME=binks", ""},
+		{"echo $GREETING $NAME", "hello binks\n"},
+		{"alias hi='echo hi there'", ""},
+		{"hi", "hi there\n"},
+		{"shout() { echo \"$1!\"; }", ""},
+		{"shout hey", "hey!\n"},
+		{"printf 'no newline'", "no newline"},
+		{"echo err >&2", "err\n"},
+	}
+	for _, s := range steps {
+		output, err := e.RunCommand(ctx, s.command)
+		require.NoError(t, err, s.command)
+		assert.Equal(t, s.expect, output, s.command)
+	}
+}
+
+func TestPersistentExecutor_ExitStatus(t *testing.T) {
+	e := newPersistent(t)
+	output, err := e.RunCommand(context.Background(), "echo failing; exit_with() { return $1; }; exit_with 3")
+	assert.Equal(t, "failing\n", output)
+	var ee *ExitError
+	require.ErrorAs(t, err, &ee)
+	assert.Equal(t, 3, ee.ExitCode())
+	assert.EqualError(t, err, "exit status 3")
+
+	_, err = e.RunCommand(context.Background(), "nonexistentcommand12345")
+	require.ErrorAs(t, err, &ee)
+	assert.Equal(t, 127, ee.Code)
+}
+
+func TestPersistentExecutor_TracksDirectory(t *testing.T) {
+	e := newPersistent(t)
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0o755))
+	ctx := context.Background()
+
+	output, err := e.RunCommandWithDir(ctx, "pwd", dir)
+	require.NoError(t, err)
+	assert.Equal(t, dir+"\n", output)
+	assert.Equal(t, dir, e.Dir())
+
+	_, err = e.RunCommandWithDir(ctx, "cd sub", dir)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "sub"), e.Dir(), "a cd inside a command is reported")
+
+	output, err = e.RunCommandWithDir(ctx, "pwd", dir)
+	require.NoError(t, err)
+	assert.Equal(t, dir+"\n", output, "the caller's directory wins when it differs")
+
+	_, err = e.RunCommandWithDir(ctx, "echo unreachable", filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+	assert.Equal(t, dir, e.Dir())
+}
+
+func TestPersistentExecutor_CommandsDoNotReadTheShellInput(t *testing.T) {
+	e := newPersistent(t)
+	output, err := e.RunCommand(context.Background(), "cat; echo after")
+	require.NoError(t, err)
+	assert.Equal(t, "after\n", output)
+}
+
+func TestPersistentExecutor_InterruptKeepsState(t *testing.T) {
+	e := newPersistent(t)
+	_, err := e.RunCommand(context.Background(), "export KEPT=yes")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = e.RunCommand(ctx, "sleep 5")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 3*time.Second)
+
+	output, err := e.RunCommand(context.Background(), "echo $KEPT")
+	require.NoError(t, err)
+	assert.Equal(t, "yes\n", output)
+}
+
+func TestPersistentExecutor_RestartsAfterStuckCommandOrExit(t *testing.T) {
+	e := newPersistent(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err := e.RunCommand(ctx, "trap '' INT; sleep 5")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Empty(t, e.Dir(), "the stuck shell was killed")
+
+	output, err := e.RunCommand(context.Background(), "echo again")
+	require.NoError(t, err)
+	assert.Equal(t, "again\n", output)
+
+	output, err = e.RunCommand(context.Background(), "echo bye; exit 4")
+	assert.Equal(t, "bye\n", output)
+	assert.EqualError(t, err, "exit status 4")
+	output, err = e.RunCommand(context.Background(), "echo new shell")
+	require.NoError(t, err)
+	assert.Equal(t, "new shell\n", output)
+}
+
+func TestPersistentExecutor_SeparateProcessesSeeExportedEnv(t *testing.T) {
+	e := newPersistent(t)
+	e.NoTTY = false
+	dir := t.TempDir()
+	_, err := e.RunCommandWithDir(context.Background(), "export BINKS_PERSIST_TEST=1", dir)
+	require.NoError(t, err)
+	env, err := e.environ(context.Background())
+	require.NoError(t, err)
+	assert.Contains(t, env, "BINKS_PERSIST_TEST=1")
+}
+
+func TestPersistentExecutor_Shells(t *testing.T) {
+	forEachShell(t, func(t *testing.T, sh Shell) {
+		e, err := NewPersistentShellExecutor(sh)
+		if sh.Name == "fish" {
+			assert.EqualError(t, err, "a persistent shell is not supported for fish")
+			return
+		}
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = e.Close() })
+		_, err = e.RunCommand(context.Background(), "export STAGE=dev; greet() { echo \"hi $1\"; }")
+		require.NoError(t, err)
+		output, err := e.RunCommand(context.Background(), "greet $STAGE")
+		require.NoError(t, err)
+		assert.Equal(t, "hi dev\n", output)
+		assert.Equal(t, sh.Name, e.Shell())
+	})
+}
+
+func TestPersistentExecutor_Run(t *testing.T) {
+	e := newPersistent(t)
+	testRunner(t, e)
+
+	w := newLiveWriter()
+	res, err := e.Run(context.Background(), "printf 'no newline'; printf 'warn' >&2; exit_with() { return $1; }; exit_with 5", RunOptions{Stdout: w})
+	assert.EqualError(t, err, "exit status 5")
+	assert.Equal(t, 5, res.ExitCode)
+	assert.Equal(t, "no newline", res.Stdout)
+	assert.Equal(t, "warn", res.Stderr)
+	assert.Len(t, w.String(), len("no newlinewarn"), "the markers are not streamed")
+}
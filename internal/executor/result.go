@@ -0,0 +1,157 @@
+package executor
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// CommandResult is what running a command produced and how it ended.
+type CommandResult struct {
+	Command string
+	Stdout  string
+	Stderr  string
+	// Combined is stdout and stderr interleaved in the order they were read.
+	Combined string
+	// ExitCode is the status a shell would report in $?: 128 plus the signal
+	// number for a command ended by a signal. It is -1 if the command never
+	// ran or was stopped because its context was cancelled.
+	ExitCode int
+	// Signal is the signal that ended the command, or 0. As with $?, an exit
+	// status above 128 is taken to mean the shell's command was ended by
+	// signal status-128.
+	Signal  syscall.Signal
+	Started time.Time
+	Ended   time.Time
+	// Dir is the directory the command started in.
+	Dir string
+	// Job is the command itself when it was launched in the background
+	// rather than waited for, as GUI apps are.
+	Job *Job
+}
+
+// SignalName returns the name of the signal that ended the command, such as
+// SIGTERM, or "" if none did.
+func (r *CommandResult) SignalName() string {
+	if r.Signal == 0 {
+		return ""
+	}
+	return unix.SignalName(r.Signal)
+}
+
+// Duration returns how long the command ran.
+func (r *CommandResult) Duration() time.Duration {
+	return r.Ended.Sub(r.Started)
+}
+
+// RunOptions are the optional settings for Runner.Run.
+type RunOptions struct {
+	// Dir is the directory to run the command in; "" means the executor's.
+	Dir string
+	// Stdout and Stderr, when set, receive the command's output as it
+	// arrives. The output kept in the result is then cut to its last
+	// MaxCapture bytes per stream. Notes about the command, such as that it
+	// was launched in the background, go to Stdout as well.
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Runner is implemented by executors that report everything about a command
+// they ran. The result is never nil. The error is non-nil when the command
+// could not run, was stopped, or exited non-zero, as for RunCommand.
+type Runner interface {
+	Run(ctx context.Context, cmd string, opts RunOptions) (*CommandResult, error)
+}
+
+// Starter is implemented by executors that can start a command as a
+// background job, in dir or, when dir is "", the executor's directory.
+type Starter interface {
+	Start(cmd, dir string) (*Job, error)
+}
+
+// newResult starts the result of running cmd in dir.
+func newResult(cmd, dir string) *CommandResult {
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+	return &CommandResult{Command: cmd, Dir: dir, ExitCode: -1, Started: time.Now()}
+}
+
+// exited records how the command's process ended. A command stopped through
+// ctx keeps an exit code of -1.
+func (r *CommandResult) exited(ctx context.Context, state *os.ProcessState) {
+	r.Ended = time.Now()
+	if state == nil {
+		return
+	}
+	code := state.ExitCode()
+	if ws, ok := state.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		code = 128 + int(ws.Signal())
+	}
+	if ctx.Err() == nil {
+		r.setExitCode(code)
+	}
+}
+
+// setExitCode records the command's exit status and the signal it implies.
+func (r *CommandResult) setExitCode(code int) {
+	r.ExitCode = code
+	if code > 128 && code <= 128+64 {
+		r.Signal = syscall.Signal(code - 128)
+	}
+}
+
+// recorder collects a command's output into a result as it is written,
+// passing it on to the caller's writers. Writes to either stream are
+// serialized, so the caller may pass the same writer for both.
+type recorder struct {
+	mu                       sync.Mutex
+	stdout, stderr, combined capture
+	out, errOut              io.Writer
+}
+
+func newRecorder(opts RunOptions) *recorder {
+	r := &recorder{out: opts.Stdout, errOut: opts.Stderr}
+	if r.out != nil {
+		r.stdout.max, r.stderr.max, r.combined.max = MaxCapture, MaxCapture, MaxCapture
+	}
+	if r.errOut == nil {
+		r.errOut = r.out
+	}
+	return r
+}
+
+// writers returns the writers for the command's stdout and stderr.
+func (r *recorder) writers() (io.Writer, io.Writer) {
+	return streamWriter{r, &r.stdout, r.out}, streamWriter{r, &r.stderr, r.errOut}
+}
+
+// fill copies the output collected into res.
+func (r *recorder) fill(res *CommandResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	res.Stdout, res.Stderr, res.Combined = r.stdout.String(), r.stderr.String(), r.combined.String()
+}
+
+// streamWriter is one stream of a recorder.
+type streamWriter struct {
+	r    *recorder
+	own  *capture
+	pass io.Writer
+}
+
+func (w streamWriter) Write(p []byte) (int, error) {
+	w.r.mu.Lock()
+	defer w.r.mu.Unlock()
+	_, _ = w.own.Write(p)
+	_, _ = w.r.combined.Write(p)
+	if w.pass != nil {
+		return w.pass.Write(p)
+	}
+	return len(p), nil
+}
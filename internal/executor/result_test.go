@@ -0,0 +1,113 @@
+package executor
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// liveWriter records what is written to it and signals each write.
+type liveWriter struct {
+	mu     sync.Mutex
+	buf    strings.Builder
+	writes chan struct{}
+}
+
+func newLiveWriter() *liveWriter {
+	return &liveWriter{writes: make(chan struct{}, 100)}
+}
+
+func (w *liveWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf.Write(p)
+	select {
+	case w.writes <- struct{}{}:
+	default:
+	}
+	return len(p), nil
+}
+
+func (w *liveWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+// testRunner checks what every Runner must do: report each stream, the exit
+// code, any signal, the timing and the directory, and stream output live.
+func testRunner(t *testing.T, r Runner) {
+	t.Helper()
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	before := time.Now()
+	res, err := r.Run(ctx, "echo out; sleep 0.1; echo err >&2; exit 2", RunOptions{Dir: dir})
+	require.NotNil(t, res)
+	assert.EqualError(t, err, "exit status 2")
+	assert.Equal(t, "out\n", res.Stdout)
+	assert.Equal(t, "err\n", res.Stderr)
+	assert.Equal(t, "out\nerr\n", res.Combined)
+	assert.Equal(t, 2, res.ExitCode)
+	assert.Zero(t, res.Signal)
+	assert.Equal(t, dir, res.Dir)
+	assert.False(t, res.Started.Before(before))
+	assert.GreaterOrEqual(t, res.Duration(), 100*time.Millisecond)
+
+	res, err = r.Run(ctx, "sh -c 'kill -TERM $$'", RunOptions{Dir: dir})
+	assert.Error(t, err)
+	assert.Equal(t, 143, res.ExitCode)
+	assert.Equal(t, syscall.SIGTERM, res.Signal)
+
+	res, err = r.Run(ctx, "true", RunOptions{Dir: dir})
+	require.NoError(t, err)
+	assert.Equal(t, 0, res.ExitCode)
+
+	stdout, stderr := newLiveWriter(), newLiveWriter()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		res, err = r.Run(ctx, "echo first; sleep 1; echo second; echo oops >&2", RunOptions{Dir: dir, Stdout: stdout, Stderr: stderr})
+	}()
+	select {
+	case <-stdout.writes:
+		select {
+		case <-done:
+			t.Fatal("the command finished before its first line was seen")
+		default:
+		}
+		assert.Equal(t, "first\n", stdout.String())
+	case <-done:
+		t.Fatal("no output arrived while the command ran")
+	}
+	<-done
+	require.NoError(t, err)
+	assert.Equal(t, "first\nsecond\n", stdout.String())
+	assert.Equal(t, "oops\n", stderr.String())
+	assert.Equal(t, "first\nsecond\n", res.Stdout)
+	assert.Equal(t, "oops\n", res.Stderr)
+	assert.ElementsMatch(t, []string{"first", "second", "oops"}, strings.Fields(res.Combined))
+
+	stdout = newLiveWriter()
+	res, err = r.Run(ctx, "seq 1 100000", RunOptions{Dir: dir, Stdout: stdout})
+	require.NoError(t, err)
+	assert.Len(t, stdout.String(), 588895, "everything is streamed")
+	assert.True(t, strings.HasPrefix(res.Stdout, "[... "), res.Stdout[:40])
+	assert.True(t, strings.HasSuffix(res.Stdout, "\n99999\n100000\n"))
+	assert.LessOrEqual(t, len(res.Combined), MaxCapture+100)
+}
+
+func TestCommandResult_ContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	res, err := NewBashExecutor().Run(ctx, "echo started; sleep 5", RunOptions{})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, -1, res.ExitCode, "a stopped command has no exit code")
+	assert.Equal(t, "started\n", res.Stdout)
+}
@@ -0,0 +1,130 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Shell describes a shell that commands run in: how to invoke it, how to load
+// the user's rc file and how to quote words for it.
+type Shell struct {
+	// Name is the kind of shell: bash, zsh, fish or sh.
+	Name string
+	// Path is the program to run. Defaults to Name.
+	Path string
+	// LoadRC reads the user's interactive rc file (~/.bashrc, ~/.zshrc, $ENV
+	// for sh, config.fish) before each command, for its aliases and functions.
+	LoadRC bool
+}
+
+// Bash is the shell binks uses unless told otherwise.
+var Bash = Shell{Name: "bash"}
+
+// shellKinds maps shell program names to the kind of shell they are.
+var shellKinds = map[string]string{
+	"bash": "bash",
+	"zsh":  "zsh",
+	"fish": "fish",
+	"sh":   "sh",
+	"dash": "sh",
+	"ash":  "sh",
+	"ksh":  "sh",
+	"mksh": "sh",
+}
+
+// LookupShell returns the Shell for a name (zsh) or a path (/usr/local/bin/zsh).
+func LookupShell(nameOrPath string) (Shell, error) {
+	base := filepath.Base(nameOrPath)
+	kind, ok := shellKinds[base]
+	if !ok {
+		return Shell{}, fmt.Errorf("unsupported shell: %s (use bash, zsh, fish or sh)", nameOrPath)
+	}
+	sh := Shell{Name: kind}
+	if base != kind || strings.ContainsRune(nameOrPath, filepath.Separator) {
+		sh.Path = nameOrPath
+	}
+	return sh, nil
+}
+
+// Available reports whether the shell's program can be found.
+func (s Shell) Available() bool {
+	_, err := exec.LookPath(s.program())
+	return err == nil
+}
+
+func (s Shell) program() string {
+	if s.Path != "" {
+		return s.Path
+	}
+	return s.Name
+}
+
+// Command returns the command that runs cmd in a new process of the shell.
+func (s Shell) Command(ctx context.Context, cmd string) *exec.Cmd {
+	return exec.CommandContext(ctx, s.program(), s.args(cmd)...)
+}
+
+func (s Shell) args(cmd string) []string {
+	if s.Name == "fish" {
+		// fish reads config.fish even when not interactive.
+		if !s.LoadRC {
+			return []string{"--no-config", "-c", cmd}
+		}
+		return []string{"-c", cmd}
+	}
+	// The rc file is read on lines of its own, so that aliases it defines
+	// apply to the command.
+	return []string{"-c", s.rcScript() + cmd}
+}
+
+// rcScript returns the lines that read the user's rc file, or "" when
+// LoadRC is off. fish needs none; it reads config.fish itself.
+func (s Shell) rcScript() string {
+	if !s.LoadRC {
+		return ""
+	}
+	switch s.Name {
+	case "bash":
+		return "shopt -s expand_aliases\nif [ -f ~/.bashrc ]; then . ~/.bashrc; fi\n"
+	case "zsh":
+		return "if [[ -f ${ZDOTDIR:-$HOME}/.zshrc ]]; then source ${ZDOTDIR:-$HOME}/.zshrc; fi\n"
+	case "sh":
+		return "if [ -n \"$ENV\" ] && [ -f \"$ENV\" ]; then . \"$ENV\"; fi\n"
+	}
+	return ""
+}
+
+// Quote returns word quoted so that the shell reads it as a single word.
+func (s Shell) Quote(word string) string {
+	if word != "" && strings.IndexFunc(word, needsQuote) < 0 {
+		return word
+	}
+	if s.Name == "fish" {
+		// fish has no '\'' idiom; inside single quotes it takes \' and \\.
+		r := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+		return "'" + r.Replace(word) + "'"
+	}
+	return "'" + strings.ReplaceAll(word, "'", `'\''`) + "'"
+}
+
+// Join quotes words and joins them into a command line for the shell.
+func (s Shell) Join(words ...string) string {
+	quoted := make([]string, len(words))
+	for i, w := range words {
+		quoted[i] = s.Quote(w)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// needsQuote reports whether r means something to a shell, so a word
+// containing it must be quoted.
+func needsQuote(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return false
+	}
+	return !strings.ContainsRune("-_./:=+,@%", r)
+}
@@ -0,0 +1,46 @@
+package executor
+
+import "context"
+
+// ShellExecutor runs each command in a new process of a given shell, such as
+// zsh or fish. BashExecutor is the same for bash.
+type ShellExecutor struct {
+	// NoTTY captures the output of interactive commands instead of attaching
+	// them to the terminal, as for BashExecutor.
+	NoTTY bool
+
+	shell Shell
+}
+
+// NewShellExecutor creates a ShellExecutor running commands in sh.
+func NewShellExecutor(sh Shell) *ShellExecutor {
+	return &ShellExecutor{shell: sh}
+}
+
+// Shell returns the name of the shell commands run in
+func (e *ShellExecutor) Shell() string {
+	return e.shell.Name
+}
+
+// RunCommandWithDir runs a command in the shell in the specified directory
+// and returns the combined output. The command is killed when ctx is
+// cancelled or its deadline passes.
+func (e *ShellExecutor) RunCommandWithDir(ctx context.Context, cmd, dir string) (string, error) {
+	res, err := e.Run(ctx, cmd, RunOptions{Dir: dir})
+	return res.Combined, err
+}
+
+// Run runs a command in the shell and reports its result.
+func (e *ShellExecutor) Run(ctx context.Context, cmd string, opts RunOptions) (*CommandResult, error) {
+	return runCommand(ctx, e.shell, e.NoTTY, cmd, opts)
+}
+
+// Start starts a command in the shell as a background job.
+func (e *ShellExecutor) Start(cmd, dir string) (*Job, error) {
+	return startInShell(e.shell, cmd, dir)
+}
+
+// RunCommand runs a command in the shell and returns the combined output.
+func (e *ShellExecutor) RunCommand(ctx context.Context, cmd string) (string, error) {
+	return e.RunCommandWithDir(ctx, cmd, "")
+}
@@ -0,0 +1,126 @@
+package executor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupShell(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Shell
+	}{
+		{"bash", Shell{Name: "bash"}},
+		{"zsh", Shell{Name: "zsh"}},
+		{"/usr/local/bin/fish", Shell{Name: "fish", Path: "/usr/local/bin/fish"}},
+		{"dash", Shell{Name: "sh", Path: "dash"}},
+		{"/bin/sh", Shell{Name: "sh", Path: "/bin/sh"}},
+	}
+	for _, tt := range tests {
+		got, err := LookupShell(tt.in)
+		require.NoError(t, err, tt.in)
+		assert.Equal(t, tt.want, got, tt.in)
+	}
+	_, err := LookupShell("/bin/tcsh")
+	assert.EqualError(t, err, "unsupported shell: /bin/tcsh (use bash, zsh, fish or sh)")
+}
+
+func TestShell_Args(t *testing.T) {
+	assert.Equal(t, []string{"-c", "ls"}, Bash.args("ls"))
+	assert.Equal(t, []string{"--no-config", "-c", "ls"}, Shell{Name: "fish"}.args("ls"))
+	assert.Equal(t, []string{"-c", "ls"}, Shell{Name: "fish", LoadRC: true}.args("ls"))
+	zsh := Shell{Name: "zsh", LoadRC: true}.args("ls")
+	assert.Equal(t, "if [[ -f ${ZDOTDIR:-$HOME}/.zshrc ]]; then source ${ZDOTDIR:-$HOME}/.zshrc; fi\nls", zsh[1])
+}
+
+func TestShell_Quote(t *testing.T) {
+	posix, fish := Bash, Shell{Name: "fish"}
+	tests := []struct {
+		word, posix, fish string
+	}{
+		{"plain-word_1.txt", "plain-word_1.txt", "plain-word_1.txt"},
+		{"", "''", "''"},
+		{"two words", "'two words'", "'two words'"},
+		{"it's", `'it'\''s'`, `'it\'s'`},
+		{`back\slash`, `'back\slash'`, `'back\\slash'`},
+		{"$HOME *", "'$HOME *'", "'$HOME *'"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.posix, posix.Quote(tt.word), tt.word)
+		assert.Equal(t, tt.fish, fish.Quote(tt.word), tt.word)
+	}
+	assert.Equal(t, "echo 'a b' c", posix.Join("echo", "a b", "c"))
+}
+
+// forEachShell runs test once for every kind of shell, skipping those that
+// are not installed.
+func forEachShell(t *testing.T, test func(t *testing.T, sh Shell)) {
+	for _, name := range []string{"bash", "zsh", "fish", "sh"} {
+		t.Run(name, func(t *testing.T) {
+			sh := Shell{Name: name}
+			if !sh.Available() {
+				t.Skip(name, "is not installed")
+			}
+			test(t, sh)
+		})
+	}
+}
+
+func TestShellExecutor_Shells(t *testing.T) {
+	words := []string{"it's", `back\slash`, "$HOME", "two  spaces", "*", "semi;colon", "new\nline"}
+	forEachShell(t, func(t *testing.T, shell Shell) {
+		e := NewShellExecutor(shell)
+		assert.Equal(t, shell.Name, e.Shell())
+		dir := t.TempDir()
+
+		output, err := e.RunCommandWithDir(context.Background(), "pwd", dir)
+		require.NoError(t, err)
+		resolved, _ := filepath.EvalSymlinks(dir)
+		assert.Contains(t, []string{dir + "\n", resolved + "\n"}, output)
+
+		var want string
+		for _, w := range words {
+			want += w + "\n"
+		}
+		output, err = e.RunCommand(context.Background(), shell.Join(append([]string{"printf", `%s\n`}, words...)...))
+		require.NoError(t, err)
+		assert.Equal(t, want, output, "quoted words come back unchanged")
+
+		_, err = e.RunCommand(context.Background(), "exit 3")
+		assert.Equal(t, 3, err.(interface{ ExitCode() int }).ExitCode())
+
+		testRunner(t, e)
+	})
+}
+
+func TestShellExecutor_LoadRC(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("ZDOTDIR", home)
+	t.Setenv("ENV", filepath.Join(home, ".shrc"))
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	require.NoError(t, os.MkdirAll(filepath.Join(home, ".config", "fish"), 0o755))
+	alias := "alias greet='echo hi from rc'\n"
+	for file, content := range map[string]string{
+		".bashrc":                  "greet() { echo hi from rc; }\n",
+		".zshrc":                   alias,
+		".shrc":                    alias,
+		".config/fish/config.fish": "function greet; echo hi from rc; end\n",
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(home, file), []byte(content), 0o644))
+	}
+	forEachShell(t, func(t *testing.T, sh Shell) {
+		_, err := NewShellExecutor(sh).RunCommand(context.Background(), "greet")
+		assert.Error(t, err, "the rc file is not read by default")
+
+		sh.LoadRC = true
+		output, err := NewShellExecutor(sh).RunCommand(context.Background(), "greet")
+		require.NoError(t, err)
+		assert.Equal(t, "hi from rc\n", output)
+	})
+}
@@ -0,0 +1,66 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"time"
+
+	"github.com/binks-cli/binks/internal/agent"
+)
+
+// callTimeout bounds a single tools/call made on behalf of the agent.
+const callTimeout = 60 * time.Second
+
+// invalidToolChars matches characters model APIs reject in function names.
+var invalidToolChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// ToolName returns the agent-facing name of an MCP tool, namespaced by server
+// so tools from different servers cannot collide.
+func ToolName(server, tool string) string {
+	name := invalidToolChars.ReplaceAllString(server, "_") + "__" + invalidToolChars.ReplaceAllString(tool, "_")
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
+
+// AgentTools lists the server's tools and wraps each one as an agent.Tool, so
+// they can be registered alongside the built-in tools.
+func (c *Client) AgentTools(ctx context.Context) ([]agent.Tool, error) {
+	tools, err := c.ListTools(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]agent.Tool, 0, len(tools))
+	for _, t := range tools {
+		t := t
+		schema := t.InputSchema
+		if schema == nil {
+			schema = map[string]any{"type": "object", "properties": map[string]any{}}
+		}
+		out = append(out, agent.Tool{
+			Name:        ToolName(c.Name, t.Name),
+			Description: t.Description,
+			Parameters:  schema,
+			Run: func(ctx context.Context, args map[string]any) (string, error) {
+				ctx, cancel := context.WithTimeout(ctx, callTimeout)
+				defer cancel()
+				res, err := c.CallTool(ctx, t.Name, args)
+				if err != nil {
+					return "", err
+				}
+				if res.IsError {
+					return "", errors.New(res.Text())
+				}
+				return res.Text(), nil
+			},
+			Describe: func(args map[string]any) string {
+				raw, _ := json.Marshal(args)
+				return "mcp " + c.Name + "/" + t.Name + " " + string(raw)
+			},
+		})
+	}
+	return out, nil
+}
@@ -0,0 +1,294 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// handshakeTimeout bounds how long a server may take to answer initialize.
+const handshakeTimeout = 10 * time.Second
+
+// ServerConfig describes an MCP server that binks starts as a subprocess.
+type ServerConfig struct {
+	Name    string            `yaml:"name"`
+	Command string            `yaml:"command"`
+	Args    []string          `yaml:"args"`
+	Env     map[string]string `yaml:"env"`
+}
+
+// Client is a connection to a single MCP server speaking JSON-RPC 2.0 over
+// newline-delimited stdio.
+type Client struct {
+	Name       string         // name from the config, used to namespace tools
+	ServerInfo Implementation // filled in by Initialize
+
+	cmd    *exec.Cmd
+	w      io.WriteCloser
+	writeM sync.Mutex
+
+	mu      sync.Mutex
+	nextID  int64
+	pending map[string]chan *message
+	err     error         // set once the connection is gone
+	done    chan struct{} // closed when the read loop exits
+}
+
+// Start launches the server described by cfg and performs the initialize handshake.
+func Start(cfg ServerConfig) (*Client, error) {
+	if cfg.Command == "" {
+		return nil, fmt.Errorf("MCP server %q has no command", cfg.Name)
+	}
+	cmd := exec.Command(cfg.Command, cfg.Args...)
+	cmd.Env = os.Environ()
+	for k, v := range cfg.Env {
+		cmd.Env = append(cmd.Env, k+"="+os.ExpandEnv(v))
+	}
+	cmd.Stderr = io.Discard
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("starting MCP server %q: %w", cfg.Name, err)
+	}
+	c := NewClient(cfg.Name, stdout, stdin)
+	c.cmd = cmd
+	ctx, cancel := context.WithTimeout(context.Background(), handshakeTimeout)
+	defer cancel()
+	if err := c.Initialize(ctx); err != nil {
+		_ = c.Close()
+		return nil, fmt.Errorf("initializing MCP server %q: %w", cfg.Name, err)
+	}
+	return c, nil
+}
+
+// NewClient creates a client reading server messages from r and writing
+// requests to w. The caller must call Initialize before using the client.
+func NewClient(name string, r io.Reader, w io.WriteCloser) *Client {
+	c := &Client{
+		Name:    name,
+		w:       w,
+		pending: make(map[string]chan *message),
+		done:    make(chan struct{}),
+	}
+	go c.readLoop(r)
+	return c
+}
+
+// Initialize performs the MCP handshake: the initialize request followed by
+// the initialized notification.
+func (c *Client) Initialize(ctx context.Context) error {
+	var res initializeResult
+	err := c.call(ctx, "initialize", initializeParams{
+		ProtocolVersion: ProtocolVersion,
+		Capabilities:    map[string]any{},
+		ClientInfo:      Implementation{Name: "binks", Version: "0.5.0"},
+	}, &res)
+	if err != nil {
+		return err
+	}
+	c.ServerInfo = res.ServerInfo
+	return c.notify("notifications/initialized", nil)
+}
+
+// ListTools returns every tool the server offers, following pagination.
+func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
+	var tools []Tool
+	cursor := ""
+	for {
+		var res listToolsResult
+		if err := c.call(ctx, "tools/list", cursorParams{Cursor: cursor}, &res); err != nil {
+			return nil, err
+		}
+		tools = append(tools, res.Tools...)
+		if res.NextCursor == "" {
+			return tools, nil
+		}
+		cursor = res.NextCursor
+	}
+}
+
+// CallTool invokes a tool on the server.
+func (c *Client) CallTool(ctx context.Context, name string, args map[string]any) (*CallToolResult, error) {
+	var res CallToolResult
+	if err := c.call(ctx, "tools/call", callToolParams{Name: name, Arguments: args}, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// ListResources returns every resource the server offers, following pagination.
+func (c *Client) ListResources(ctx context.Context) ([]Resource, error) {
+	var resources []Resource
+	cursor := ""
+	for {
+		var res listResourcesResult
+		if err := c.call(ctx, "resources/list", cursorParams{Cursor: cursor}, &res); err != nil {
+			return nil, err
+		}
+		resources = append(resources, res.Resources...)
+		if res.NextCursor == "" {
+			return resources, nil
+		}
+		cursor = res.NextCursor
+	}
+}
+
+// ReadResource fetches the contents of a resource.
+func (c *Client) ReadResource(ctx context.Context, uri string) ([]ResourceContents, error) {
+	var res readResourceResult
+	if err := c.call(ctx, "resources/read", map[string]string{"uri": uri}, &res); err != nil {
+		return nil, err
+	}
+	return res.Contents, nil
+}
+
+// Close shuts the connection down and stops the server process, if any.
+func (c *Client) Close() error {
+	err := c.w.Close()
+	if c.cmd != nil {
+		select {
+		case <-c.done:
+		case <-time.After(time.Second):
+			_ = c.cmd.Process.Kill()
+		}
+		_ = c.cmd.Wait()
+	}
+	return err
+}
+
+// call sends a request and decodes the result into out.
+func (c *Client) call(ctx context.Context, method string, params, out any) error {
+	c.mu.Lock()
+	if c.err != nil {
+		err := c.err
+		c.mu.Unlock()
+		return err
+	}
+	c.nextID++
+	id := strconv.FormatInt(c.nextID, 10)
+	ch := make(chan *message, 1)
+	c.pending[id] = ch
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	if err := c.write(&message{JSONRPC: "2.0", ID: json.RawMessage(id), Method: method, Params: raw}); err != nil {
+		return err
+	}
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if out == nil {
+			return nil
+		}
+		return json.Unmarshal(resp.Result, out)
+	case <-c.done:
+		return c.connErr()
+	case <-ctx.Done():
+		// Let the server stop the work instead of finishing it for nobody.
+		_ = c.notify("notifications/cancelled", cancelledParams{RequestID: json.RawMessage(id), Reason: ctx.Err().Error()})
+		return ctx.Err()
+	}
+}
+
+// notify sends a notification, which has no response.
+func (c *Client) notify(method string, params any) error {
+	msg := &message{JSONRPC: "2.0", Method: method}
+	if params != nil {
+		raw, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		msg.Params = raw
+	}
+	return c.write(msg)
+}
+
+func (c *Client) write(msg *message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	c.writeM.Lock()
+	defer c.writeM.Unlock()
+	_, err = c.w.Write(append(data, '\n'))
+	return err
+}
+
+// readLoop dispatches responses to waiting callers until the stream ends.
+func (c *Client) readLoop(r io.Reader) {
+	reader := bufio.NewReader(r)
+	var err error
+	for {
+		var line []byte
+		line, err = reader.ReadBytes('\n')
+		if len(line) > 0 {
+			c.handle(line)
+		}
+		if err != nil {
+			break
+		}
+	}
+	if errors.Is(err, io.EOF) {
+		err = errors.New("MCP server closed the connection")
+	}
+	c.mu.Lock()
+	c.err = err
+	c.mu.Unlock()
+	close(c.done)
+}
+
+func (c *Client) handle(line []byte) {
+	var msg message
+	if json.Unmarshal(line, &msg) != nil {
+		return // not JSON-RPC; servers sometimes log to stdout
+	}
+	switch {
+	case msg.Method != "" && msg.ID != nil:
+		// Server-to-client requests (sampling, roots, ...) are not supported.
+		_ = c.write(&message{JSONRPC: "2.0", ID: msg.ID, Error: &RPCError{Code: CodeMethodNotFound, Message: "method not supported by binks: " + msg.Method}})
+	case msg.Method != "":
+		// Notifications are ignored.
+	default:
+		c.mu.Lock()
+		ch, ok := c.pending[string(msg.ID)]
+		c.mu.Unlock()
+		if ok {
+			// A duplicate or late response is dropped rather than blocking
+			// every response after it.
+			select {
+			case ch <- &msg:
+			default:
+			}
+		}
+	}
+}
+
+func (c *Client) connErr() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
@@ -0,0 +1,183 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var fakeServerPath string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "binks-mcp")
+	if err != nil {
+		panic(err)
+	}
+	fakeServerPath = filepath.Join(dir, "fakeserver")
+	build := exec.Command("go", "build", "-o", fakeServerPath, "./testdata/fakeserver")
+	if out, err := build.CombinedOutput(); err != nil {
+		panic("building fake MCP server: " + err.Error() + "\n" + string(out))
+	}
+	code := m.Run()
+	_ = os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func startFake(t *testing.T) *Client {
+	t.Helper()
+	c, err := Start(ServerConfig{Name: "fake", Command: fakeServerPath})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+func TestClient_HandshakeAndTools(t *testing.T) {
+	c := startFake(t)
+	assert.Equal(t, "fake", c.ServerInfo.Name)
+
+	ctx := context.Background()
+	tools, err := c.ListTools(ctx)
+	require.NoError(t, err)
+	require.Len(t, tools, 2, "both pages should be returned")
+	assert.Equal(t, "echo", tools[0].Name)
+	assert.Equal(t, "fail", tools[1].Name)
+
+	res, err := c.CallTool(ctx, "echo", map[string]any{"text": "hi"})
+	require.NoError(t, err)
+	assert.False(t, res.IsError)
+	assert.Equal(t, "hi", res.Text())
+
+	res, err = c.CallTool(ctx, "fail", nil)
+	require.NoError(t, err)
+	assert.True(t, res.IsError)
+
+	_, err = c.CallTool(ctx, "missing", nil)
+	var rpcErr *RPCError
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, CodeInvalidParams, rpcErr.Code)
+}
+
+func TestClient_Resources(t *testing.T) {
+	c := startFake(t)
+	ctx := context.Background()
+	resources, err := c.ListResources(ctx)
+	require.NoError(t, err)
+	require.Len(t, resources, 1)
+	assert.Equal(t, "memo://greeting", resources[0].URI)
+
+	contents, err := c.ReadResource(ctx, "memo://greeting")
+	require.NoError(t, err)
+	require.Len(t, contents, 1)
+	assert.Equal(t, "hello from fake", contents[0].Text)
+}
+
+func TestClient_AgentTools(t *testing.T) {
+	c := startFake(t)
+	tools, err := c.AgentTools(context.Background())
+	require.NoError(t, err)
+	require.Len(t, tools, 2)
+	assert.Equal(t, "fake__echo", tools[0].Name)
+	assert.Equal(t, "object", tools[1].Parameters["type"], "missing schemas get an empty object schema")
+
+	out, err := tools[0].Run(context.Background(), map[string]any{"text": "via agent"})
+	require.NoError(t, err)
+	assert.Equal(t, "via agent", out)
+	assert.Equal(t, `mcp fake/echo {"text":"x"}`, tools[0].Describe(map[string]any{"text": "x"}))
+
+	_, err = tools[1].Run(context.Background(), nil)
+	assert.EqualError(t, err, "it broke")
+}
+
+func TestClient_ClosedConnection(t *testing.T) {
+	c := startFake(t)
+	require.NoError(t, c.Close())
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_, err := c.ListTools(ctx)
+	assert.Error(t, err)
+}
+
+// pipeClient returns a client connected to the test through pipes: the
+// client's requests are read from requests, and replies written to replies.
+func pipeClient(t *testing.T) (c *Client, requests *bufio.Reader, replies io.Writer) {
+	t.Helper()
+	reqR, reqW := io.Pipe()
+	repR, repW := io.Pipe()
+	t.Cleanup(func() {
+		_ = repW.Close()
+		_ = reqR.Close()
+	})
+	return NewClient("pipe", repR, reqW), bufio.NewReader(reqR), repW
+}
+
+func TestClient_DuplicateResponseDoesNotBlock(t *testing.T) {
+	c, _, _ := pipeClient(t)
+	c.mu.Lock()
+	c.pending["1"] = make(chan *message, 1)
+	c.mu.Unlock()
+
+	handled := make(chan struct{})
+	go func() {
+		resp := []byte(`{"jsonrpc":"2.0","id":1,"result":{}}`)
+		c.handle(resp)
+		c.handle(resp)
+		close(handled)
+	}()
+	select {
+	case <-handled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("a duplicate response blocked the read loop")
+	}
+}
+
+func TestClient_CancelledCallNotifiesServer(t *testing.T) {
+	c, requests, _ := pipeClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		_, err := c.ListTools(ctx)
+		errc <- err
+	}()
+
+	var req message
+	line, err := requests.ReadBytes('\n')
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(line, &req))
+	assert.Equal(t, "tools/list", req.Method)
+	cancel()
+
+	var note message
+	line, err = requests.ReadBytes('\n')
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(line, &note))
+	assert.Equal(t, "notifications/cancelled", note.Method)
+	assert.Nil(t, note.ID)
+	var params cancelledParams
+	require.NoError(t, json.Unmarshal(note.Params, &params))
+	assert.JSONEq(t, string(req.ID), string(params.RequestID))
+	assert.ErrorIs(t, <-errc, context.Canceled)
+}
+
+func TestStart_Errors(t *testing.T) {
+	_, err := Start(ServerConfig{Name: "empty"})
+	assert.EqualError(t, err, `MCP server "empty" has no command`)
+	_, err = Start(ServerConfig{Name: "missing", Command: "/no/such/binary"})
+	assert.ErrorContains(t, err, `starting MCP server "missing"`)
+	_, err = Start(ServerConfig{Name: "true", Command: "true"})
+	assert.ErrorContains(t, err, `initializing MCP server "true"`)
+}
+
+func TestToolName(t *testing.T) {
+	assert.Equal(t, "git__log", ToolName("git", "log"))
+	assert.Equal(t, "my_server__read_file", ToolName("my server", "read.file"))
+	assert.Len(t, ToolName("s", string(make([]byte, 100))), 64)
+}
@@ -0,0 +1,148 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// ToolHandler runs a served tool with its decoded arguments and returns the
+// text shown to the caller.
+type ToolHandler func(ctx context.Context, args map[string]any) (string, error)
+
+type serverTool struct {
+	tool    Tool
+	handler ToolHandler
+}
+
+// Server serves tools to an MCP client over newline-delimited stdio.
+// Requests are handled one at a time, in order.
+type Server struct {
+	Info         Implementation
+	Instructions string // optional usage hints sent during initialize
+
+	tools []serverTool
+	mu    sync.Mutex // guards writes
+}
+
+// NewServer creates a server that identifies itself as info.
+func NewServer(info Implementation) *Server {
+	return &Server{Info: info}
+}
+
+// AddTool exposes a tool to clients.
+func (s *Server) AddTool(t Tool, h ToolHandler) {
+	if t.InputSchema == nil {
+		t.InputSchema = map[string]any{"type": "object", "properties": map[string]any{}}
+	}
+	s.tools = append(s.tools, serverTool{tool: t, handler: h})
+}
+
+// Serve reads requests from r and writes responses to w until r is exhausted
+// or ctx is cancelled.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	reader := bufio.NewReader(r)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			s.handle(ctx, line, w)
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) handle(ctx context.Context, line []byte, w io.Writer) {
+	var req message
+	if err := json.Unmarshal(line, &req); err != nil {
+		s.reply(w, &message{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &RPCError{Code: CodeParseError, Message: "parse error"}})
+		return
+	}
+	if req.ID == nil {
+		return // notifications need no answer
+	}
+	if req.Method == "" {
+		return // a response to a request we never send
+	}
+	result, rpcErr := s.dispatch(ctx, req.Method, req.Params)
+	resp := &message{JSONRPC: "2.0", ID: req.ID}
+	if rpcErr != nil {
+		resp.Error = rpcErr
+	} else {
+		raw, err := json.Marshal(result)
+		if err != nil {
+			resp.Error = &RPCError{Code: CodeInternalError, Message: err.Error()}
+		} else {
+			resp.Result = raw
+		}
+	}
+	s.reply(w, resp)
+}
+
+func (s *Server) dispatch(ctx context.Context, method string, params json.RawMessage) (any, *RPCError) {
+	switch method {
+	case "initialize":
+		return initializeResult{
+			ProtocolVersion: ProtocolVersion,
+			Capabilities:    map[string]any{"tools": map[string]any{}},
+			ServerInfo:      s.Info,
+			Instructions:    s.Instructions,
+		}, nil
+	case "ping":
+		return map[string]any{}, nil
+	case "tools/list":
+		tools := make([]Tool, 0, len(s.tools))
+		for _, t := range s.tools {
+			tools = append(tools, t.tool)
+		}
+		return listToolsResult{Tools: tools}, nil
+	case "tools/call":
+		var p callToolParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &RPCError{Code: CodeInvalidParams, Message: err.Error()}
+		}
+		for _, t := range s.tools {
+			if t.tool.Name == p.Name {
+				return callHandler(ctx, t.handler, p.Arguments), nil
+			}
+		}
+		return nil, &RPCError{Code: CodeInvalidParams, Message: fmt.Sprintf("unknown tool %q", p.Name)}
+	}
+	return nil, &RPCError{Code: CodeMethodNotFound, Message: "method not found: " + method}
+}
+
+// callHandler runs a tool. Tool failures are reported in the result (isError),
+// not as protocol errors, so the model can see and react to them.
+func callHandler(ctx context.Context, h ToolHandler, args map[string]any) CallToolResult {
+	if args == nil {
+		args = map[string]any{}
+	}
+	text, err := h(ctx, args)
+	if err != nil {
+		if text != "" {
+			text += "\n"
+		}
+		return CallToolResult{Content: []Content{{Type: "text", Text: text + "Error: " + err.Error()}}, IsError: true}
+	}
+	return CallToolResult{Content: []Content{{Type: "text", Text: text}}}
+}
+
+func (s *Server) reply(w io.Writer, msg *message) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, _ = w.Write(append(data, '\n'))
+}
@@ -0,0 +1,74 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// servePipe connects a Client to an in-process Server.
+func servePipe(t *testing.T, srv *Server) *Client {
+	t.Helper()
+	reqR, reqW := io.Pipe()
+	respR, respW := io.Pipe()
+	go func() {
+		_ = srv.Serve(context.Background(), reqR, respW)
+		_ = respW.Close()
+	}()
+	c := NewClient("test", respR, reqW)
+	require.NoError(t, c.Initialize(context.Background()))
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+func TestServer_RoundTrip(t *testing.T) {
+	srv := NewServer(Implementation{Name: "srv", Version: "9"})
+	srv.AddTool(Tool{Name: "upper", Description: "Upper-case text"}, func(_ context.Context, args map[string]any) (string, error) {
+		s, _ := args["text"].(string)
+		return strings.ToUpper(s), nil
+	})
+	srv.AddTool(Tool{Name: "broken"}, func(context.Context, map[string]any) (string, error) {
+		return "partial", errors.New("nope")
+	})
+	c := servePipe(t, srv)
+	assert.Equal(t, "srv", c.ServerInfo.Name)
+
+	ctx := context.Background()
+	tools, err := c.ListTools(ctx)
+	require.NoError(t, err)
+	require.Len(t, tools, 2)
+	assert.Equal(t, "object", tools[1].InputSchema["type"])
+
+	res, err := c.CallTool(ctx, "upper", map[string]any{"text": "hi"})
+	require.NoError(t, err)
+	assert.Equal(t, "HI", res.Text())
+
+	res, err = c.CallTool(ctx, "broken", nil)
+	require.NoError(t, err)
+	assert.True(t, res.IsError)
+	assert.Equal(t, "partial\nError: nope", res.Text())
+
+	_, err = c.CallTool(ctx, "missing", nil)
+	assert.EqualError(t, err, `MCP error -32602: unknown tool "missing"`)
+
+	_, err = c.ListResources(ctx)
+	assert.EqualError(t, err, "MCP error -32601: method not found: resources/list")
+}
+
+func TestServer_MalformedInput(t *testing.T) {
+	srv := NewServer(Implementation{Name: "srv"})
+	var out strings.Builder
+	in := strings.NewReader("not json\n" +
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}` + "\n" +
+		`{"jsonrpc":"2.0","id":7,"method":"ping"}` + "\n")
+	require.NoError(t, srv.Serve(context.Background(), in, &out))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 2, "notifications get no reply")
+	assert.Contains(t, lines[0], `"code":-32700`)
+	assert.Equal(t, `{"jsonrpc":"2.0","id":7,"result":{}}`, lines[1])
+}
@@ -0,0 +1,80 @@
+// Command fakeserver is a minimal MCP server used by the mcp package tests.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  any             `json:"result,omitempty"`
+	Error   any             `json:"error,omitempty"`
+}
+
+func main() {
+	out := json.NewEncoder(os.Stdout)
+	scanner := bufio.NewScanner(os.Stdin)
+	// Noise on stdout that clients must tolerate.
+	fmt.Println("fakeserver starting")
+	for scanner.Scan() {
+		var msg message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil || msg.Method == "" || msg.ID == nil {
+			continue // notifications and responses to our own requests
+		}
+		reply := message{JSONRPC: "2.0", ID: msg.ID}
+		switch msg.Method {
+		case "initialize":
+			// Ask the client something it does not support before answering.
+			_ = out.Encode(message{JSONRPC: "2.0", ID: json.RawMessage(`"srv-1"`), Method: "roots/list"})
+			reply.Result = map[string]any{
+				"protocolVersion": "2024-11-05",
+				"capabilities":    map[string]any{"tools": map[string]any{}, "resources": map[string]any{}},
+				"serverInfo":      map[string]any{"name": "fake", "version": "1.0"},
+			}
+		case "tools/list":
+			var p struct {
+				Cursor string `json:"cursor"`
+			}
+			_ = json.Unmarshal(msg.Params, &p)
+			if p.Cursor == "" {
+				reply.Result = map[string]any{
+					"tools": []any{map[string]any{
+						"name":        "echo",
+						"description": "Echo the text argument",
+						"inputSchema": map[string]any{"type": "object", "properties": map[string]any{"text": map[string]any{"type": "string"}}},
+					}},
+					"nextCursor": "page2",
+				}
+			} else {
+				reply.Result = map[string]any{"tools": []any{map[string]any{"name": "fail", "description": "Always fails"}}}
+			}
+		case "tools/call":
+			var p struct {
+				Name      string         `json:"name"`
+				Arguments map[string]any `json:"arguments"`
+			}
+			_ = json.Unmarshal(msg.Params, &p)
+			switch p.Name {
+			case "echo":
+				reply.Result = map[string]any{"content": []any{map[string]any{"type": "text", "text": fmt.Sprint(p.Arguments["text"])}}}
+			case "fail":
+				reply.Result = map[string]any{"content": []any{map[string]any{"type": "text", "text": "it broke"}}, "isError": true}
+			default:
+				reply.Error = map[string]any{"code": -32602, "message": "unknown tool " + p.Name}
+			}
+		case "resources/list":
+			reply.Result = map[string]any{"resources": []any{map[string]any{"uri": "memo://greeting", "name": "greeting", "mimeType": "text/plain"}}}
+		case "resources/read":
+			reply.Result = map[string]any{"contents": []any{map[string]any{"uri": "memo://greeting", "mimeType": "text/plain", "text": "hello from fake"}}}
+		default:
+			reply.Error = map[string]any{"code": -32601, "message": "method not found"}
+		}
+		_ = out.Encode(reply)
+	}
+}
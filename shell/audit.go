@@ -0,0 +1,294 @@
+package shell
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/binks-cli/binks/internal/agent"
+)
+
+// Decisions recorded in the audit log.
+const (
+	decisionAccepted = "accepted"
+	decisionEdited   = "edited"
+	decisionDeclined = "declined"
+)
+
+// auditRecord is one AI interaction in the audit log: an answer without a
+// command, or a suggested command together with what the user decided.
+type auditRecord struct {
+	Time     time.Time `json:"time"`
+	Cwd      string    `json:"cwd"`
+	Prompt   string    `json:"prompt"`
+	Model    string    `json:"model"`
+	Response string    `json:"response"`
+	Command  string    `json:"command,omitempty"`
+	// Edited is the command that ran instead of Command, if the user edited it.
+	Edited   string `json:"edited,omitempty"`
+	Risk     string `json:"risk,omitempty"`
+	Decision string `json:"decision,omitempty"`
+	ExitCode *int   `json:"exit_code,omitempty"`
+	// DurationMS is how long the command ran, in milliseconds.
+	DurationMS int64 `json:"duration_ms,omitempty"`
+}
+
+// auditLimit is how many records :audit shows unless told otherwise.
+const auditLimit = 20
+
+// auditPath returns the file the audit log is appended to,
+// binks/audit.jsonl under the user config dir.
+func auditPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "binks", "audit.jsonl")
+}
+
+// audit appends rec to the audit log, filling in what the session knows about
+// the exchange in progress. Secrets are redacted as they are from prompts sent
+// to the AI provider.
+func (s *Session) audit(rec auditRecord) {
+	if s.auditFile == "" {
+		return
+	}
+	rec.Time = time.Now()
+	rec.Cwd = s.Cwd()
+	rec.Prompt = s.query
+	rec.Model = agentModel(s.Agent)
+	for _, text := range []*string{&rec.Prompt, &rec.Response, &rec.Command, &rec.Edited} {
+		*text = agent.Redact(*text)
+	}
+	_ = appendAudit(s.auditFile, rec) // auditing must never break a query
+}
+
+// auditDecision records the user's answer to p, with the exit code of the
+// command it ran (if any) and how long that command took to run.
+func (s *Session) auditDecision(p *PendingSuggestion, decision string, ran *lastCommand, took time.Duration) {
+	rec := auditRecord{Response: p.raw, Decision: decision, DurationMS: took.Milliseconds()}
+	rec.Command, rec.Edited = p.suggested()
+	if decision == decisionAccepted && rec.Edited != "" {
+		rec.Decision = decisionEdited
+	}
+	if p.toolCall == nil {
+		rec.Risk = p.risk.level.String()
+	}
+	if ran != nil {
+		code := ran.result.ExitCode
+		rec.ExitCode = &code
+	}
+	s.audit(rec)
+}
+
+// declineSuggestion records that the user turned p down.
+func (s *Session) declineSuggestion(p *PendingSuggestion) {
+	p.declined = true
+	s.auditDecision(p, decisionDeclined, nil, 0)
+}
+
+// suggested returns the command awaiting an answer as the agent suggested it,
+// and the user's edit of it, if any.
+func (p *PendingSuggestion) suggested() (command, edited string) {
+	if p.isPlan() {
+		step := p.steps[p.current]
+		if step.original != "" {
+			return step.original, step.command
+		}
+		return step.command, ""
+	}
+	return p.command, p.edited
+}
+
+func appendAudit(path string, rec auditRecord) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(line, '\n'))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func readAudit(r io.Reader) []auditRecord {
+	var records []auditRecord
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 16<<20) // records hold whole AI responses
+	for scanner.Scan() {
+		var rec auditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err == nil {
+			records = append(records, rec)
+		}
+	}
+	return records
+}
+
+// auditFilter selects records for :audit.
+type auditFilter struct {
+	decision string
+	risk     string
+	model    string
+	since    time.Time
+	failed   bool
+	text     []string // words that must all appear in the prompt, command or response
+	limit    int
+}
+
+// parseAuditFilter parses the arguments of :audit: key=value filters
+// (decision, risk, model, since, limit), "failed", and search words.
+func parseAuditFilter(args []string, now time.Time) (auditFilter, error) {
+	f := auditFilter{limit: auditLimit}
+	for _, arg := range args {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			if arg == "failed" {
+				f.failed = true
+			} else {
+				f.text = append(f.text, strings.ToLower(arg))
+			}
+			continue
+		}
+		switch key {
+		case "decision":
+			f.decision = value
+		case "risk":
+			f.risk = value
+		case "model":
+			f.model = value
+		case "since":
+			since, err := parseSince(value, now)
+			if err != nil {
+				return f, err
+			}
+			f.since = since
+		case "limit":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return f, fmt.Errorf("invalid limit: %s", value)
+			}
+			f.limit = n
+		default:
+			return f, fmt.Errorf("unknown audit filter: %s", key)
+		}
+	}
+	return f, nil
+}
+
+// parseSince accepts a date (2006-01-02) or a duration back from now (24h).
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if t, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid since: %s (use a date like 2006-01-02 or a duration like 24h)", value)
+}
+
+func (f auditFilter) match(rec auditRecord) bool {
+	switch {
+	case f.decision != "" && rec.Decision != f.decision,
+		f.risk != "" && rec.Risk != f.risk,
+		f.model != "" && !strings.HasPrefix(rec.Model, f.model),
+		rec.Time.Before(f.since),
+		f.failed && (rec.ExitCode == nil || *rec.ExitCode == 0):
+		return false
+	}
+	haystack := strings.ToLower(rec.Prompt + "\n" + rec.Command + "\n" + rec.Edited + "\n" + rec.Response)
+	for _, word := range f.text {
+		if !strings.Contains(haystack, word) {
+			return false
+		}
+	}
+	return true
+}
+
+// metaAudit shows the most recent audit records matching the filters in args.
+func metaAudit(_ context.Context, sess *Session, args []string, out io.Writer) error {
+	if sess.auditFile == "" {
+		return fmt.Errorf("the audit log is not available")
+	}
+	filter, err := parseAuditFilter(args, time.Now())
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(sess.auditFile)
+	if os.IsNotExist(err) {
+		fmt.Fprintln(out, "[AI] The audit log is empty.")
+		return nil
+	} else if err != nil {
+		return err
+	}
+	records := readAudit(f)
+	_ = f.Close()
+
+	var matched []auditRecord
+	for _, rec := range records {
+		if filter.match(rec) {
+			matched = append(matched, rec)
+		}
+	}
+	if len(matched) == 0 {
+		fmt.Fprintln(out, "[AI] No matching audit records.")
+		return nil
+	}
+	if len(matched) > filter.limit {
+		fmt.Fprintf(out, "Showing the last %d of %d records (%s).\n", filter.limit, len(matched), sess.auditFile)
+		matched = matched[len(matched)-filter.limit:]
+	}
+	for _, rec := range matched {
+		printAuditRecord(out, rec)
+	}
+	return nil
+}
+
+func printAuditRecord(out io.Writer, rec auditRecord) {
+	line := rec.Time.Local().Format(time.DateTime)
+	if rec.Decision == "" {
+		line += "  answered"
+	} else {
+		line += "  " + rec.Decision
+	}
+	if rec.Risk != "" {
+		line += "  " + rec.Risk + " risk"
+	}
+	if rec.ExitCode != nil {
+		line += fmt.Sprintf("  exit %d  %s", *rec.ExitCode, time.Duration(rec.DurationMS)*time.Millisecond)
+	}
+	if rec.Model != "" {
+		line += "  " + rec.Model
+	}
+	fmt.Fprintln(out, line)
+	fmt.Fprintf(out, "  >> %s\n", oneLine(rec.Prompt))
+	switch {
+	case rec.Edited != "":
+		fmt.Fprintf(out, "  $ %s  (suggested: %s)\n", oneLine(rec.Edited), oneLine(rec.Command))
+	case rec.Command != "":
+		fmt.Fprintf(out, "  $ %s\n", oneLine(rec.Command))
+	}
+}
+
+// oneLine shortens text to a single line for listings.
+func oneLine(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if len(text) > 100 {
+		return text[:97] + "..."
+	}
+	return text
+}
@@ -5,7 +5,11 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/binks-cli/binks/internal/agent"
+	"github.com/binks-cli/binks/internal/executor"
+	"github.com/binks-cli/binks/internal/mcp"
 	"gopkg.in/yaml.v3"
 )
 
@@ -17,10 +21,168 @@ type ColorConfig struct {
 	// Future: add MCP, editor, etc.
 }
 
+// ContextConfig selects which parts of the local environment are described to
+// the AI agent in its system prompt. Unset fields default to on.
+type ContextConfig struct {
+	Cwd            *bool `yaml:"cwd"`
+	GitBranch      *bool `yaml:"git_branch"`
+	OS             *bool `yaml:"os"`
+	Shell          *bool `yaml:"shell"`
+	DirListing     *bool `yaml:"dir_listing"`
+	RecentCommands *bool `yaml:"recent_commands"`
+}
+
+// AIConfig holds settings for AI queries.
+type AIConfig struct {
+	// Provider selects the AI backend: openai, anthropic, ollama, llamacpp
+	// (also spelled llama.cpp) or dummy. BINKS_AI_PROVIDER overrides it; when
+	// both are unset, binks uses the first provider whose API key is set.
+	Provider string `yaml:"provider"`
+	// Profile names the entry of Profiles to use at startup (BINKS_AI_PROFILE
+	// overrides it). Profiles can be switched at runtime with :model.
+	Profile  string          `yaml:"profile"`
+	Profiles []agent.Profile `yaml:"profiles"`
+	Context  ContextConfig   `yaml:"context"`
+	// Tools offers run_command, read_file, list_dir and write_file to agents
+	// that support function calling. Defaults to on.
+	Tools *bool       `yaml:"tools"`
+	Usage UsageConfig `yaml:"usage"`
+	// OfferDiagnosis offers, after a command exits non-zero, to have the
+	// agent explain the failure (the same as typing :explain).
+	OfferDiagnosis bool         `yaml:"offer_diagnosis"`
+	Redact         RedactConfig `yaml:"redact"`
+}
+
+// RedactConfig adds to the secrets removed from everything sent to AI
+// providers. AWS keys, GitHub tokens, JWTs, private keys, secret-looking
+// assignments and random-looking tokens are always removed.
+type RedactConfig struct {
+	// Patterns are regular expressions for further secrets. When a pattern
+	// has a capture group only the group is removed.
+	Patterns []string `yaml:"patterns"`
+}
+
+// UsageConfig prices AI token usage and caps spending.
+type UsageConfig struct {
+	// Prices maps a model name, or a prefix of it, to its price in USD per
+	// million tokens.
+	Prices map[string]ModelPrice `yaml:"prices"`
+	Budget BudgetConfig          `yaml:"budget"`
+	// Prompt shows the session's AI usage in the prompt.
+	Prompt bool `yaml:"prompt"`
+}
+
+// ModelPrice is the price of a model in USD per million tokens.
+type ModelPrice struct {
+	Input  float64 `yaml:"input"`
+	Output float64 `yaml:"output"`
+}
+
+// BudgetConfig limits AI spending in USD. Once a budget is used up, AI queries
+// are refused. Zero means no limit.
+type BudgetConfig struct {
+	Session float64 `yaml:"session"`
+	Daily   float64 `yaml:"daily"`
+}
+
+// MCPConfig lists the MCP servers binks connects to at startup, and how
+// `binks mcp serve` exposes binks to other MCP clients.
+type MCPConfig struct {
+	Servers []mcp.ServerConfig `yaml:"servers"`
+	Serve   MCPServeConfig     `yaml:"serve"`
+}
+
+// MCPServeConfig configures `binks mcp serve`.
+type MCPServeConfig struct {
+	// Allow names the tools exposed to clients ("all" for every tool).
+	// Defaults to the read-only tools.
+	Allow []string `yaml:"allow"`
+}
+
+// ExecConfig configures how shell commands run: which shell runs them and
+// with what setup, whether one shell is kept for the whole session, and how
+// long they may take.
+type ExecConfig struct {
+	// Timeout bounds how long a command may run. Durations are written like
+	// "30s" or "10m"; zero means no limit.
+	Timeout time.Duration `yaml:"timeout"`
+	// Timeouts overrides Timeout per program, keyed by the first word of the
+	// command (e.g. make: 10m).
+	Timeouts map[string]time.Duration `yaml:"timeouts"`
+	// Persistent runs every command in one long-lived shell, so that exported
+	// variables, aliases and functions last from one command to the next.
+	Persistent bool `yaml:"persistent"`
+	// Shell is the shell commands run in: bash, zsh, fish or sh, or a path to
+	// one. Defaults to $SHELL when that is one of these, or else bash.
+	Shell string `yaml:"shell"`
+	// RC reads the shell's interactive rc file (e.g. ~/.zshrc) before each
+	// command, for its aliases and functions.
+	RC bool `yaml:"rc"`
+}
+
+// shell returns the shell commands run in. A configured shell that binks
+// does not support is reported, and bash used instead.
+func (c ExecConfig) shell() (executor.Shell, error) {
+	name := c.Shell
+	if name == "" {
+		name = os.Getenv("SHELL")
+	}
+	sh := executor.Bash
+	var err error
+	if name != "" {
+		var found executor.Shell
+		if found, err = executor.LookupShell(name); err == nil {
+			sh = found
+		} else if c.Shell == "" {
+			err = nil // a login shell binks cannot drive, such as tcsh
+		}
+	}
+	sh.LoadRC = c.RC
+	return sh, err
+}
+
+// NewExecutor returns the executor selected by the exec settings, and what
+// was wrong with them, if anything. With noTTY, interactive programs have
+// their output captured instead of taking over the terminal.
+func NewExecutor(c ExecConfig, noTTY bool) (executor.Executor, error) {
+	sh, err := c.shell()
+	se := executor.NewShellExecutor(sh)
+	se.NoTTY = noTTY
+	if c.Persistent {
+		pe, perr := executor.NewPersistentShellExecutor(sh)
+		if perr != nil {
+			return se, perr
+		}
+		pe.NoTTY = noTTY
+		return pe, err
+	}
+	return se, err
+}
+
+// ConfiguredShell returns the shell selected by ~/.binks.yaml or $SHELL, for
+// running commands outside a session.
+func ConfiguredShell() (executor.Shell, error) {
+	return readBinksConfig().Exec.shell()
+}
+
+// timeoutFor returns the time limit for a command line, or 0 for none.
+func (c ExecConfig) timeoutFor(cmd string) time.Duration {
+	fields := strings.Fields(cmd)
+	if len(fields) > 0 {
+		if d, ok := c.Timeouts[fields[0]]; ok {
+			return d
+		}
+	}
+	return c.Timeout
+}
+
 // BinksConfig holds the overall configuration for binks.
 type BinksConfig struct {
 	Colors ColorConfig `yaml:"colors"`
-	// Future: MCP, editor, etc.
+	AI     AIConfig    `yaml:"ai"`
+	MCP    MCPConfig   `yaml:"mcp"`
+	Exec   ExecConfig  `yaml:"exec"`
+	// Future: editor, etc.
 }
 
 var defaultColors = ColorConfig{
@@ -77,21 +239,44 @@ func LoadColorConfig() ColorConfig {
 	return cfg
 }
 
-// readConfigFile loads ~/.binks.yaml if present
+// contextOptions resolves which system prompt parts are enabled, defaulting to all of them.
+func contextOptions(c ContextConfig) ContextOptions {
+	on := func(b *bool) bool { return b == nil || *b }
+	return ContextOptions{
+		Cwd:            on(c.Cwd),
+		GitBranch:      on(c.GitBranch),
+		OS:             on(c.OS),
+		Shell:          on(c.Shell),
+		DirListing:     on(c.DirListing),
+		RecentCommands: on(c.RecentCommands),
+	}
+}
+
+// LoadConfig loads ~/.binks.yaml, returning the zero config if it is missing or invalid.
+func LoadConfig() BinksConfig {
+	return readBinksConfig()
+}
+
+// readConfigFile loads the color section of ~/.binks.yaml if present
 func readConfigFile() ColorConfig {
+	return readBinksConfig().Colors
+}
+
+// readBinksConfig loads ~/.binks.yaml if present, returning the zero config otherwise
+func readBinksConfig() BinksConfig {
 	home, err := os.UserHomeDir()
 	if err != nil {
-		return ColorConfig{}
+		return BinksConfig{}
 	}
 	path := filepath.Join(home, ".binks.yaml")
 	data, err := os.ReadFile(path)
 	if err != nil {
-		return ColorConfig{}
+		return BinksConfig{}
 	}
 	var cfg BinksConfig
 	err = yaml.Unmarshal(data, &cfg)
 	if err != nil {
-		return ColorConfig{}
+		return BinksConfig{}
 	}
-	return cfg.Colors
+	return cfg
 }
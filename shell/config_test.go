@@ -4,7 +4,9 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/binks-cli/binks/internal/executor"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -77,3 +79,57 @@ func TestReadConfigFile_Errors(t *testing.T) {
 	assert.Equal(t, ColorConfig{}, cfg)
 	_ = os.Remove(badPath)
 }
+
+func TestLoadConfig_ExecTimeouts(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	cfg := "exec:\n  timeout: 30s\n  timeouts:\n    make: 10m\nai:\n  profiles:\n    - name: local\n      type: ollama\n      timeout: 5m\n"
+	assert.NoError(t, os.WriteFile(filepath.Join(home, ".binks.yaml"), []byte(cfg), 0644))
+
+	loaded := LoadConfig()
+	assert.Equal(t, 30*time.Second, loaded.Exec.timeoutFor("sleep 100"))
+	assert.Equal(t, 10*time.Minute, loaded.Exec.timeoutFor("make -j8 all"))
+	assert.Equal(t, time.Duration(0), ExecConfig{}.timeoutFor("make"))
+	if assert.Len(t, loaded.AI.Profiles, 1) {
+		assert.Equal(t, 5*time.Minute, loaded.AI.Profiles[0].Timeout)
+	}
+}
+
+func TestExecConfig_Shell(t *testing.T) {
+	t.Setenv("SHELL", "/usr/bin/zsh")
+	sh, err := ExecConfig{}.shell()
+	assert.NoError(t, err)
+	assert.Equal(t, "zsh", sh.Name)
+
+	sh, err = ExecConfig{Shell: "fish", RC: true}.shell()
+	assert.NoError(t, err)
+	assert.Equal(t, executor.Shell{Name: "fish", LoadRC: true}, sh)
+
+	t.Setenv("SHELL", "/bin/tcsh")
+	sh, err = ExecConfig{}.shell()
+	assert.NoError(t, err, "an unsupported login shell falls back to bash")
+	assert.Equal(t, executor.Bash, sh)
+
+	sh, err = ExecConfig{Shell: "tcsh"}.shell()
+	assert.EqualError(t, err, "unsupported shell: tcsh (use bash, zsh, fish or sh)")
+	assert.Equal(t, executor.Bash, sh)
+}
+
+func TestNewExecutor(t *testing.T) {
+	t.Setenv("SHELL", "")
+	exec, err := NewExecutor(ExecConfig{Shell: "sh", Persistent: true}, false)
+	assert.NoError(t, err)
+	assert.IsType(t, &executor.PersistentExecutor{}, exec)
+	assert.Equal(t, "sh", exec.(executor.ShellNamer).Shell())
+	assert.False(t, exec.(*executor.PersistentExecutor).NoTTY)
+
+	exec, err = NewExecutor(ExecConfig{Shell: "fish", Persistent: true}, true)
+	assert.EqualError(t, err, "a persistent shell is not supported for fish")
+	assert.IsType(t, &executor.ShellExecutor{}, exec)
+	assert.True(t, exec.(*executor.ShellExecutor).NoTTY)
+
+	exec, err = NewExecutor(ExecConfig{Shell: "sh", Persistent: true}, true)
+	assert.NoError(t, err)
+	assert.True(t, exec.(*executor.PersistentExecutor).NoTTY, "no terminal for the persistent shell's interactive programs either")
+	exec.(*executor.PersistentExecutor).Close()
+}
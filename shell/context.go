@@ -0,0 +1,143 @@
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/binks-cli/binks/internal/agent"
+	"github.com/binks-cli/binks/internal/executor"
+)
+
+const (
+	maxListingEntries = 20 // directory entries described in the system prompt
+	maxPromptCommands = 5  // recent commands described in the system prompt
+	maxRecentCommands = 20 // commands remembered by the session
+)
+
+// ContextOptions selects which parts of the local environment are described to
+// the AI agent. When every part is off, no system prompt is sent.
+type ContextOptions struct {
+	Cwd            bool
+	GitBranch      bool
+	OS             bool
+	Shell          bool
+	DirListing     bool
+	RecentCommands bool
+}
+
+func (o ContextOptions) anyEnabled() bool {
+	return o.Cwd || o.GitBranch || o.OS || o.Shell || o.DirListing || o.RecentCommands
+}
+
+// CommandRecord is a command the session ran and how it exited.
+type CommandRecord struct {
+	Command  string
+	ExitCode int
+}
+
+// recordCommand remembers a finished command for the AI context.
+func (s *Session) recordCommand(res *executor.CommandResult) {
+	s.recent = append(s.recent, CommandRecord{Command: res.Command, ExitCode: res.ExitCode})
+	if len(s.recent) > maxRecentCommands {
+		s.recent = s.recent[len(s.recent)-maxRecentCommands:]
+	}
+}
+
+// History returns the commands the session ran recently, oldest first.
+func (s *Session) History() []CommandRecord {
+	return append([]CommandRecord(nil), s.recent...)
+}
+
+// exitCode extracts a process exit code from a command error: 0 for success,
+// the exit status for commands that ran, and -1 if the command never ran.
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var ee interface{ ExitCode() int }
+	if errors.As(err, &ee) {
+		return ee.ExitCode()
+	}
+	return -1
+}
+
+// SystemPrompt describes the session's environment to the AI agent, so that
+// suggestions fit the current OS, shell and directory. It returns "" when all
+// context parts are disabled.
+func (s *Session) SystemPrompt() string {
+	opts := s.Context
+	if !opts.anyEnabled() {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("You are binks, an assistant built into an interactive command-line shell. ")
+	b.WriteString("When you suggest a shell command, put it in a fenced code block. ")
+	b.WriteString("Only suggest commands that work in the environment below.\n\nEnvironment:\n")
+	if opts.OS {
+		fmt.Fprintf(&b, "- OS: %s/%s\n", runtime.GOOS, runtime.GOARCH)
+	}
+	if opts.Shell {
+		if sn, ok := s.Executor.(executor.ShellNamer); ok {
+			fmt.Fprintf(&b, "- Shell: %s\n", sn.Shell())
+		}
+	}
+	if opts.Cwd {
+		fmt.Fprintf(&b, "- Working directory: %s\n", s.cwd)
+	}
+	if opts.GitBranch {
+		if branch := GetGitBranch(s.cwd); branch != "" {
+			fmt.Fprintf(&b, "- Git branch: %s\n", branch)
+		}
+	}
+	if opts.DirListing {
+		if listing := dirListing(s.cwd, maxListingEntries); listing != "" {
+			fmt.Fprintf(&b, "- Directory contents: %s\n", listing)
+		}
+	}
+	if opts.RecentCommands && len(s.recent) > 0 {
+		b.WriteString("- Recent commands:\n")
+		start := len(s.recent) - maxPromptCommands
+		if start < 0 {
+			start = 0
+		}
+		for _, rec := range s.recent[start:] {
+			fmt.Fprintf(&b, "  - `%s` (exit %d)\n", rec.Command, rec.ExitCode)
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// dirListing returns up to limit entry names of dir, with directories marked by a trailing slash.
+func dirListing(dir string, limit int) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	names := make([]string, 0, limit)
+	for _, e := range entries {
+		if len(names) == limit {
+			names = append(names, fmt.Sprintf("... (%d more)", len(entries)-limit))
+			break
+		}
+		name := e.Name()
+		if e.IsDir() {
+			name += "/"
+		}
+		names = append(names, name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// buildMessages assembles the request for an AI query: the system prompt, the
+// conversation so far and the new user query.
+func (s *Session) buildMessages(query string) []agent.Message {
+	var messages []agent.Message
+	if sys := s.SystemPrompt(); sys != "" {
+		messages = append(messages, agent.Message{Role: agent.RoleSystem, Content: sys})
+	}
+	messages = append(messages, s.transcript.Messages()...)
+	return append(messages, agent.Message{Role: agent.RoleUser, Content: query})
+}
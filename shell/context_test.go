@@ -0,0 +1,128 @@
+package shell
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/binks-cli/binks/internal/agent"
+	"github.com/binks-cli/binks/internal/executor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var allContext = ContextOptions{Cwd: true, GitBranch: true, OS: true, Shell: true, DirListing: true, RecentCommands: true}
+
+// ran returns the result of a command that exited with code.
+func ran(cmd string, code int) *executor.CommandResult {
+	return &executor.CommandResult{Command: cmd, ExitCode: code}
+}
+
+func TestSystemPrompt_AllParts(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "src"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module x"), 0644))
+
+	sess := &Session{Executor: executor.NewBashExecutor(), cwd: dir, Context: allContext}
+	sess.recordCommand(ran("make", 0))
+	sess.recordCommand(ran("make test", -1))
+
+	prompt := sess.SystemPrompt()
+	assert.Contains(t, prompt, "- OS: "+runtime.GOOS+"/"+runtime.GOARCH)
+	assert.Contains(t, prompt, "- Shell: bash")
+	assert.Contains(t, prompt, "- Working directory: "+dir)
+	assert.Contains(t, prompt, "- Directory contents: go.mod, src/")
+	assert.Contains(t, prompt, "  - `make` (exit 0)")
+	assert.Contains(t, prompt, "  - `make test` (exit -1)")
+	assert.NotContains(t, prompt, "Git branch", "temp dir is not a git repo")
+}
+
+func TestSystemPrompt_PartsCanBeDisabled(t *testing.T) {
+	sess := &Session{Executor: &mockExecutor{}, cwd: t.TempDir(), Context: ContextOptions{OS: true}}
+	sess.recordCommand(ran("ls", 0))
+	prompt := sess.SystemPrompt()
+	assert.Contains(t, prompt, "- OS: ")
+	assert.NotContains(t, prompt, "Working directory")
+	assert.NotContains(t, prompt, "Recent commands")
+	assert.NotContains(t, prompt, "Shell:", "mockExecutor has no shell name")
+
+	sess.Context = ContextOptions{}
+	assert.Equal(t, "", sess.SystemPrompt())
+}
+
+func TestSystemPrompt_RecentCommandsAreBounded(t *testing.T) {
+	sess := &Session{cwd: ".", Context: ContextOptions{RecentCommands: true}}
+	for i := 0; i < maxRecentCommands+3; i++ {
+		sess.recordCommand(ran("cmd"+strings.Repeat("x", i), -1))
+	}
+	assert.Len(t, sess.recent, maxRecentCommands)
+	assert.Equal(t, maxPromptCommands, strings.Count(sess.SystemPrompt(), "(exit -1)"))
+}
+
+func TestHistory_ReturnsCopy(t *testing.T) {
+	sess := &Session{}
+	sess.recordCommand(ran("ls", 0))
+	sess.recordCommand(ran("false", -1))
+	history := sess.History()
+	assert.Equal(t, []CommandRecord{{Command: "ls"}, {Command: "false", ExitCode: -1}}, history)
+	history[0].Command = "changed"
+	assert.Equal(t, "ls", sess.History()[0].Command)
+}
+
+func TestDirListing_Limit(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a", "b", "c"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0644))
+	}
+	assert.Equal(t, "a, b, ... (1 more)", dirListing(dir, 2))
+	assert.Equal(t, "", dirListing(filepath.Join(dir, "missing"), 2))
+}
+
+func TestExecuteLine_SendsSystemPrompt(t *testing.T) {
+	var got []agent.Message
+	sess := &Session{
+		Agent: agent.AgentFunc(func(msgs []agent.Message) (string, error) {
+			got = msgs
+			return "ok", nil
+		}),
+		cwd:     "/tmp",
+		Context: ContextOptions{Cwd: true},
+	}
+	_, err := sess.ExecuteLine(">> where am I")
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	assert.Equal(t, agent.RoleSystem, got[0].Role)
+	assert.Contains(t, got[0].Content, "- Working directory: /tmp")
+	assert.Equal(t, agent.Message{Role: agent.RoleUser, Content: "where am I"}, got[1])
+	assert.Equal(t, 2, sess.transcript.Len(), "the system prompt is not stored in the conversation")
+}
+
+func TestNewSession_ContextConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	assert.Equal(t, allContext, NewSession().Context, "all parts default to on")
+
+	cfg := "ai:\n  context:\n    dir_listing: false\n    recent_commands: false\n"
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".binks.yaml"), []byte(cfg), 0644))
+	opts := NewSession().Context
+	assert.False(t, opts.DirListing)
+	assert.False(t, opts.RecentCommands)
+	assert.True(t, opts.Cwd)
+	assert.True(t, opts.GitBranch)
+}
+
+func TestMetaCommand_ContextPreview(t *testing.T) {
+	var out strings.Builder
+	sess := &Session{cwd: "/tmp", Context: ContextOptions{Cwd: true}}
+	assert.NoError(t, runMetaCommand(context.Background(), ":context", sess, &out))
+	assert.Contains(t, out.String(), "- Working directory: /tmp")
+
+	out.Reset()
+	sess.Context = ContextOptions{}
+	assert.NoError(t, runMetaCommand(context.Background(), ":context", sess, &out))
+	assert.Contains(t, out.String(), "No system prompt")
+}
@@ -0,0 +1,82 @@
+package shell
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/binks-cli/binks/internal/agent"
+	"github.com/binks-cli/binks/internal/executor"
+)
+
+// maxConversationMessages caps how many messages are kept in a session's
+// conversation so long sessions don't grow the request without bound.
+const maxConversationMessages = 40
+
+// maxResultOutput is how much of a command's output is fed back to the agent.
+const maxResultOutput = 2000
+
+// remember appends a message to the session's conversation, dropping the oldest
+// messages once the conversation grows past maxConversationMessages.
+func (s *Session) remember(role, content string) {
+	s.transcript.Append(role, content)
+	if s.transcript.Len() > maxConversationMessages {
+		s.transcript.Trim(maxConversationMessages)
+	}
+}
+
+// runSuggestion runs an accepted AI suggestion and records its result in the
+// conversation, so follow-up queries can refer to what happened.
+func (s *Session) runSuggestion(ctx context.Context, p *PendingSuggestion, cmd string) (string, error) {
+	started := time.Now()
+	res, err := s.RunCommandResult(ctx, cmd)
+	s.remember(agent.RoleUser, commandResultMessage(res, err))
+	s.auditDecision(p, decisionAccepted, s.last, time.Since(started))
+	return res.Combined, err
+}
+
+// commandResultMessage describes an executed command for the agent.
+func commandResultMessage(res *executor.CommandResult, err error) string {
+	status := "succeeded"
+	if err != nil {
+		status = "failed: " + err.Error()
+	}
+	return fmt.Sprintf("I ran `%s` and it %s.\n%s", res.Command, status, describeOutput(res))
+}
+
+// describeOutput lays out a command's output for the agent, with its error
+// output apart so that the agent can tell the two.
+func describeOutput(res *executor.CommandResult) string {
+	stdout := truncateOutput(strings.TrimRight(res.Stdout, "\n"))
+	stderr := truncateOutput(strings.TrimRight(res.Stderr, "\n"))
+	switch {
+	case stdout == "" && stderr == "":
+		return "Output:\n(no output)"
+	case stderr == "":
+		return "Output:\n" + stdout
+	case stdout == "":
+		return "Error output:\n" + stderr
+	}
+	return "Output:\n" + stdout + "\nError output:\n" + stderr
+}
+
+// truncateOutput shortens command output to maxResultOutput bytes.
+func truncateOutput(output string) string {
+	if len(output) > maxResultOutput {
+		return output[:maxResultOutput] + "\n[output truncated]"
+	}
+	return output
+}
+
+// printConversation writes the session's conversation, one message per block.
+func printConversation(w io.Writer, messages []agent.Message) {
+	if len(messages) == 0 {
+		fmt.Fprintln(w, "[AI] Conversation is empty.")
+		return
+	}
+	for i, m := range messages {
+		fmt.Fprintf(w, "%d. [%s] %s\n", i+1, m.Role, m.Content)
+	}
+}
@@ -0,0 +1,78 @@
+package shell
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/binks-cli/binks/internal/agent"
+	"github.com/binks-cli/binks/internal/executor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSession_ConversationIsSentWithFollowUps(t *testing.T) {
+	var calls [][]agent.Message
+	sess := &Session{
+		Executor: &mockExecutor{},
+		Agent: agent.AgentFunc(func(msgs []agent.Message) (string, error) {
+			calls = append(calls, msgs)
+			if len(calls) == 1 {
+				return "Run:\n```sh\ngo test ./shell\n```", nil
+			}
+			return "Run:\n```sh\ngo test ./test\n```", nil
+		}),
+		cwd: ".",
+	}
+
+	_, err := sess.ExecuteLine(">> run the shell tests")
+	require.NoError(t, err)
+	_, err = sess.ExecuteLine("y")
+	require.NoError(t, err)
+	_, err = sess.ExecuteLine(">> now do the same for the tests dir")
+	require.NoError(t, err)
+
+	require.Len(t, calls, 2)
+	assert.Equal(t, agent.UserPrompt("run the shell tests"), calls[0])
+	second := calls[1]
+	require.Len(t, second, 4)
+	assert.Equal(t, agent.RoleAssistant, second[1].Role)
+	assert.Contains(t, second[1].Content, "go test ./shell")
+	assert.Equal(t, agent.RoleUser, second[2].Role)
+	assert.Contains(t, second[2].Content, "I ran `go test ./shell` and it succeeded.")
+	assert.Contains(t, second[2].Content, "executed: go test ./shell")
+	assert.Equal(t, "now do the same for the tests dir", second[3].Content)
+}
+
+func TestSession_ConversationSkipsFailedQueries(t *testing.T) {
+	sess := &Session{
+		Agent: agent.AgentFunc(func([]agent.Message) (string, error) { return "", errors.New("down") }),
+		cwd:   ".",
+	}
+	_, err := sess.ExecuteLine(">> hello")
+	assert.Error(t, err)
+	assert.Equal(t, 0, sess.transcript.Len())
+}
+
+func TestSession_ConversationIsCapped(t *testing.T) {
+	sess := &Session{}
+	for i := 0; i < maxConversationMessages+5; i++ {
+		sess.remember(agent.RoleUser, "msg")
+	}
+	assert.Equal(t, maxConversationMessages, sess.transcript.Len())
+}
+
+func TestCommandResultMessage(t *testing.T) {
+	msg := commandResultMessage(&executor.CommandResult{Command: "false", ExitCode: 1}, errors.New("exit status 1"))
+	assert.Equal(t, "I ran `false` and it failed: exit status 1.\nOutput:\n(no output)", msg)
+
+	res := &executor.CommandResult{Command: "make", Stdout: "building\n", Stderr: "warning: old\n"}
+	msg = commandResultMessage(res, nil)
+	assert.Equal(t, "I ran `make` and it succeeded.\nOutput:\nbuilding\nError output:\nwarning: old", msg)
+	res.Stdout = ""
+	assert.Equal(t, "I ran `make` and it succeeded.\nError output:\nwarning: old", commandResultMessage(res, nil))
+
+	long := commandResultMessage(&executor.CommandResult{Command: "yes", Stdout: strings.Repeat("y\n", maxResultOutput)}, nil)
+	assert.Contains(t, long, "[output truncated]")
+	assert.Less(t, len(long), maxResultOutput+100)
+}
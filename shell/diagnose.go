@@ -0,0 +1,94 @@
+package shell
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/binks-cli/binks/internal/agent"
+	"github.com/binks-cli/binks/internal/executor"
+)
+
+// lastCommand is the most recent command the session ran, kept so that a
+// failure can be explained by the agent.
+type lastCommand struct {
+	command string
+	result  *executor.CommandResult
+	err     error
+}
+
+// failed reports whether the command exited non-zero or could not run.
+func (c *lastCommand) failed() bool {
+	return c != nil && c.err != nil
+}
+
+// failure returns the command's error for display, naming the signal that
+// ended it, if one did.
+func (c *lastCommand) failure() error {
+	if name := c.result.SignalName(); name != "" && c.result.ExitCode > 0 {
+		return fmt.Errorf("exit status %d (%s)", c.result.ExitCode, name)
+	}
+	return c.err
+}
+
+// diagnosisQuery asks the agent to explain a failed command and suggest a fix.
+func diagnosisQuery(c *lastCommand) string {
+	res := c.result
+	status := fmt.Sprintf("exited with code %d", res.ExitCode)
+	switch {
+	case res.ExitCode < 0:
+		status = "failed: " + c.err.Error()
+	case res.Signal != 0:
+		status = fmt.Sprintf("was ended by signal %s (exit code %d)", res.SignalName(), res.ExitCode)
+	}
+	return fmt.Sprintf("%s The command `%s` %s.\n%s\n\nExplain why it failed and suggest a corrected command in a code block.",
+		agent.AIPrefix, c.command, status, describeOutput(res))
+}
+
+// diagnoseLast sends the last command's failure to the agent. Any corrected
+// command it suggests goes through the usual confirmation.
+func diagnoseLast(ctx context.Context, sess *Session, out, errOut io.Writer) error {
+	if sess.Agent == nil {
+		return errors.New("no AI agent is configured")
+	}
+	if sess.last == nil {
+		return errors.New("no command has run yet")
+	}
+	if !sess.last.failed() {
+		return fmt.Errorf("the last command (%s) succeeded", sess.last.command)
+	}
+	runAIExchange(ctx, diagnosisQuery(sess.last), sess, out, errOut)
+	return nil
+}
+
+func metaExplain(ctx context.Context, sess *Session, _ []string, out io.Writer) error {
+	return diagnoseLast(ctx, sess, out, sess.errWriter())
+}
+
+// offerDiagnosis asks whether to have the agent explain a command that just
+// exited non-zero, when ai.offer_diagnosis is on.
+func offerDiagnosis(sess *Session, out io.Writer) {
+	if !sess.OfferDiagnosis || sess.Agent == nil || !sess.last.failed() || sess.last.result.ExitCode <= 0 {
+		return
+	}
+	sess.diagnosisOffered = true
+	aiColor.Fprintf(out, "[AI] Ask the AI why it failed? [y/N]: ")
+}
+
+// answerDiagnosisOffer handles the line typed after offerDiagnosis and reports
+// whether it was an answer. Anything but y, n or an empty line is run as usual.
+func answerDiagnosisOffer(ctx context.Context, line string, sess *Session, out, errOut io.Writer) bool {
+	sess.diagnosisOffered = false
+	switch strings.ToLower(line) {
+	case "y", "yes":
+		if err := diagnoseLast(ctx, sess, out, errOut); err != nil {
+			fmt.Fprint(errOut, ErrorMessage(err))
+		}
+		return true
+	case "", "n", "no":
+		return true
+	}
+	return false
+}
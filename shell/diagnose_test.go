@@ -0,0 +1,136 @@
+package shell
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/binks-cli/binks/internal/agent"
+	"github.com/binks-cli/binks/internal/executor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func diagnosingSession(t *testing.T, prompts *[]string) *Session {
+	t.Helper()
+	return &Session{
+		cwd:      t.TempDir(),
+		Executor: &executor.BashExecutor{NoTTY: true},
+		Agent: agentFuncMock(func(prompt string) (string, error) {
+			*prompts = append(*prompts, prompt)
+			return "The directory is missing.\n```sh\necho fixed\n```", nil
+		}),
+	}
+}
+
+func TestREPL_OffersDiagnosisAfterFailure(t *testing.T) {
+	var prompts []string
+	sess := diagnosingSession(t, &prompts)
+	sess.OfferDiagnosis = true
+	var out, errOut strings.Builder
+
+	processREPLLine("echo no such dir >&2; exit 3", sess, &out, &errOut)
+	assert.Contains(t, errOut.String(), "no such dir\n", "the output of a failed command is shown")
+	assert.Contains(t, errOut.String(), "exit status 3")
+	assert.Contains(t, out.String(), "[AI] Ask the AI why it failed? [y/N]: ")
+	require.NotNil(t, sess.last)
+	assert.Equal(t, 3, sess.last.result.ExitCode)
+	assert.Equal(t, "no such dir\n", sess.last.result.Stderr)
+	out.Reset()
+
+	processREPLLine("y", sess, &out, &errOut)
+	require.Len(t, prompts, 1)
+	assert.Contains(t, prompts[0], "The command `echo no such dir >&2; exit 3` exited with code 3.\nError output:\nno such dir\n")
+	assert.Contains(t, out.String(), "AI suggests: echo fixed\nExecute this? [y/N]: ")
+
+	out.Reset()
+	processREPLLine("y", sess, &out, &errOut)
+	assert.Contains(t, out.String(), "fixed")
+	assert.False(t, sess.last.failed())
+}
+
+func TestREPL_DiagnosisOfferIgnoredByNextCommand(t *testing.T) {
+	var prompts []string
+	sess := diagnosingSession(t, &prompts)
+	sess.OfferDiagnosis = true
+	var out, errOut strings.Builder
+
+	processREPLLine("false", sess, &out, &errOut)
+	require.True(t, sess.diagnosisOffered)
+	processREPLLine("echo moving on", sess, &out, &errOut)
+	assert.Contains(t, out.String(), "moving on")
+	assert.False(t, sess.diagnosisOffered)
+	assert.Empty(t, prompts)
+
+	out.Reset()
+	sess.OfferDiagnosis = false
+	processREPLLine("false", sess, &out, &errOut)
+	assert.NotContains(t, out.String(), "Ask the AI", "the offer is opt-in")
+}
+
+func TestMetaExplain(t *testing.T) {
+	var prompts []string
+	sess := diagnosingSession(t, &prompts)
+	var out strings.Builder
+
+	assert.EqualError(t, runMetaCommand(context.Background(), ":explain", sess, &out), "no command has run yet")
+	_, err := sess.RunCommand("true")
+	require.NoError(t, err)
+	assert.EqualError(t, runMetaCommand(context.Background(), ":explain", sess, &out), "the last command (true) succeeded")
+
+	sess.Executor = &mockExecutor{fail: true, err: errors.New("command not found")}
+	_, err = sess.RunCommand("gti status")
+	require.Error(t, err)
+	require.NoError(t, runMetaCommand(context.Background(), ":explain", sess, &out))
+	require.Len(t, prompts, 1)
+	assert.Contains(t, prompts[0], "The command `gti status` failed: command not found.\nOutput:\n(no output)")
+	assert.Contains(t, out.String(), "AI suggests: echo fixed")
+	assert.NotNil(t, sess.pendingSuggestion)
+
+	sess.Agent = nil
+	assert.EqualError(t, metaExplain(context.Background(), sess, nil, &out), "no AI agent is configured")
+}
+
+// waitingAgent answers only when its request is cancelled.
+type waitingAgent struct{}
+
+func (waitingAgent) Respond(ctx context.Context, _ []agent.Message) (string, error) {
+	<-ctx.Done()
+	return "", ctx.Err()
+}
+
+func TestMetaExplain_Cancelled(t *testing.T) {
+	var prompts []string
+	sess := diagnosingSession(t, &prompts)
+	sess.Agent = waitingAgent{}
+	var errOut strings.Builder
+	sess.Err = &errOut
+	_, err := sess.RunCommand("false")
+	require.Error(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	var out strings.Builder
+	done := make(chan error, 1)
+	go func() { done <- runMetaCommand(ctx, ":explain", sess, &out) }()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal(":explain ignored the cancelled context")
+	}
+	assert.Contains(t, errOut.String(), "[AI] error:")
+	assert.NotContains(t, out.String(), "error", "errors go to the error writer")
+}
+
+func TestREPL_ShowsSignalThatEndedCommand(t *testing.T) {
+	var prompts []string
+	sess := diagnosingSession(t, &prompts)
+	var out, errOut strings.Builder
+
+	processREPLLine("sh -c 'kill -TERM $$'", sess, &out, &errOut)
+	assert.Contains(t, errOut.String(), "Error: exit status 143 (SIGTERM)")
+	assert.Contains(t, diagnosisQuery(sess.last), "was ended by signal SIGTERM (exit code 143).\nOutput:\n(no output)")
+}
@@ -0,0 +1,98 @@
+package shell
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// lineEditor reads a line with text already in the buffer for the user to
+// change. The interactive REPL provides one backed by readline; abandoning the
+// edit with Ctrl+C or Ctrl+D returns "".
+type lineEditor func(prompt, text string) (string, error)
+
+// editCommand lets the user change cmd before it runs: in the line editor for
+// a one-line command, or in $EDITOR for a multi-line one or when there is no
+// line editor. It returns the edited command, trimmed.
+func (s *Session) editCommand(cmd string) (string, error) {
+	if s.editLine != nil && !strings.Contains(cmd, "\n") {
+		edited, err := s.editLine("Edit: ", cmd)
+		return strings.TrimSpace(edited), err
+	}
+	return editInEditor(cmd)
+}
+
+// editInEditor opens text in $EDITOR (vi when unset) and returns what was saved.
+func editInEditor(text string) (string, error) {
+	f, err := os.CreateTemp("", "binks-*.sh")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(text + "\n")
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", err
+	}
+	editor := strings.Fields(os.Getenv("EDITOR"))
+	if len(editor) == 0 {
+		editor = []string{"vi"}
+	}
+	c := exec.Command(editor[0], append(editor[1:], f.Name())...)
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := c.Run(); err != nil {
+		return "", fmt.Errorf("editor %s: %w", editor[0], err)
+	}
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// runEditedSuggestion lets the user edit the pending command and runs the
+// result. Clearing the command cancels it. The edited command is checked
+// again, never below the risk the AI gave the suggestion, and one that is not
+// low risk is confirmed again as a suggestion would be: it becomes the pending
+// suggestion and "[AI]" is returned.
+func (s *Session) runEditedSuggestion(ctx context.Context) (string, error) {
+	p := s.pendingSuggestion
+	s.pendingSuggestion = nil
+	edited, err := s.editCommand(p.toRun())
+	if err != nil {
+		s.declineSuggestion(p)
+		return "[AI] error: " + err.Error(), err
+	}
+	if edited == "" {
+		s.declineSuggestion(p)
+		return "[AI] Cancelled.", nil
+	}
+	p.edited = edited
+	p.risk = s.suggestionRisk(edited, p.aiRisk)
+	if p.risk.level > riskLow {
+		s.pendingSuggestion = p
+		return "[AI]", nil
+	}
+	p.confirmed = true
+	return s.runSuggestion(p.runContext(ctx), p, edited)
+}
+
+// toRun returns the command that runs when the suggestion is accepted: the
+// user's edit of it, if there is one.
+func (p *PendingSuggestion) toRun() string {
+	if p.edited != "" {
+		return p.edited
+	}
+	return p.command
+}
+
+// printEditedSuggestion asks to confirm a command the user edited.
+func printEditedSuggestion(out io.Writer, p *PendingSuggestion) {
+	fmt.Fprintf(out, "Edited: %s%s\n", p.edited, p.risk.note())
+	fmt.Fprint(out, p.risk.confirmPrompt())
+}
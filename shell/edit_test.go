@@ -0,0 +1,137 @@
+package shell
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func suggesting(answer string, exec *mockExecutor) *Session {
+	return &Session{cwd: ".", Executor: exec, Agent: agentFuncMock(func(string) (string, error) {
+		return answer, nil
+	})}
+}
+
+func TestSession_EditSuggestion_LineEditor(t *testing.T) {
+	exec := &mockExecutor{}
+	sess := suggesting("Try:\n```sh\nls -l\n```", exec)
+	var gotPrompt, gotText string
+	sess.editLine = func(prompt, text string) (string, error) {
+		gotPrompt, gotText = prompt, text
+		return text + "a ", nil
+	}
+
+	_, err := sess.ExecuteLine(">> list")
+	require.NoError(t, err)
+	p := sess.pendingSuggestion
+	resp, err := sess.ExecuteLine("e")
+	require.NoError(t, err)
+	assert.Equal(t, "executed: ls -la", resp)
+	assert.Equal(t, "Edit: ", gotPrompt)
+	assert.Equal(t, "ls -l", gotText, "the suggestion is filled in")
+	assert.Equal(t, "ls -l", p.command)
+	assert.Equal(t, "ls -la", p.edited)
+	assert.True(t, p.confirmed)
+	assert.Nil(t, sess.pendingSuggestion)
+}
+
+func TestSession_EditSuggestion_RiskyEditIsConfirmed(t *testing.T) {
+	exec := &mockExecutor{}
+	sess := suggesting("```sh\nls /\n```", exec)
+	sess.editLine = func(string, string) (string, error) { return "sudo rm -rf /", nil }
+	var out, errOut strings.Builder
+
+	processREPLLine(">> list root", sess, &out, &errOut)
+	out.Reset()
+	processREPLLine("e", sess, &out, &errOut)
+	assert.Equal(t, "Edited: sudo rm -rf /  [high risk: runs as root with sudo; deletes files recursively; writes outside the working directory (/)]\nType yes or rm to run it: ", out.String())
+	assert.Zero(t, exec.calls, "a risky edit is not run until confirmed")
+
+	processREPLLine("y", sess, &out, &errOut)
+	assert.Contains(t, out.String(), "[AI] Cancelled.")
+	assert.Zero(t, exec.calls)
+	assert.Nil(t, sess.pendingSuggestion)
+}
+
+func TestSession_EditSuggestion_UnchangedHighRiskNeedsYes(t *testing.T) {
+	exec := &mockExecutor{}
+	sess := suggesting("```sh\nrm -rf build\n```", exec)
+	sess.editLine = func(_, text string) (string, error) { return text, nil }
+
+	_, err := sess.ExecuteLine(">> clean")
+	require.NoError(t, err)
+	resp, err := sess.ExecuteLine("e")
+	require.NoError(t, err)
+	assert.Equal(t, "[AI]", resp, "asks again")
+	assert.Zero(t, exec.calls)
+
+	resp, err = sess.ExecuteLine("e")
+	require.NoError(t, err)
+	assert.Equal(t, "[AI]", resp, "can be edited again")
+	_, err = sess.ExecuteLine("rm")
+	require.NoError(t, err)
+	assert.Equal(t, "rm -rf build", exec.lastCmd)
+}
+
+func TestSession_EditSuggestion_Cleared(t *testing.T) {
+	exec := &mockExecutor{}
+	sess := suggesting("```sh\nls\n```", exec)
+	sess.editLine = func(string, string) (string, error) { return "", nil }
+	var out, errOut strings.Builder
+
+	processREPLLine(">> list", sess, &out, &errOut)
+	processREPLLine("E", sess, &out, &errOut)
+	assert.Contains(t, out.String(), "[AI] Cancelled.")
+	assert.Zero(t, exec.calls)
+	assert.Nil(t, sess.pendingSuggestion)
+}
+
+func TestSession_EditSuggestion_MultiLineUsesEditor(t *testing.T) {
+	t.Setenv("EDITOR", "sed -i s/one/two/")
+	exec := &mockExecutor{}
+	sess := suggesting("```sh\necho one\necho ok\n```", exec)
+	sess.editLine = func(string, string) (string, error) {
+		t.Fatal("multi-line commands are edited in $EDITOR")
+		return "", nil
+	}
+	var out, errOut strings.Builder
+
+	processREPLLine(">> count", sess, &out, &errOut)
+	processREPLLine("e", sess, &out, &errOut)
+	assert.Empty(t, errOut.String())
+	assert.Equal(t, "echo two\necho ok", exec.lastCmd)
+}
+
+func TestSession_EditSuggestion_EditorFails(t *testing.T) {
+	t.Setenv("EDITOR", "false")
+	exec := &mockExecutor{}
+	sess := suggesting("```sh\nls\n```", exec)
+
+	_, err := sess.ExecuteLine(">> list")
+	require.NoError(t, err)
+	resp, err := sess.ExecuteLine("edit")
+	require.Error(t, err)
+	assert.Contains(t, resp, "[AI] error: editor false")
+	assert.Zero(t, exec.calls)
+}
+
+func TestPlan_EditStepInLineEditor(t *testing.T) {
+	exec := &mockExecutor{}
+	sess := suggesting("```sh\nmake\n```\n```sh\nmake test\n```", exec)
+	sess.editLine = func(_, text string) (string, error) { return text + " -j4", nil }
+
+	_, err := sess.ExecuteLine(">> build")
+	require.NoError(t, err)
+	p := sess.pendingSuggestion
+	_, err = sess.ExecuteLine("e")
+	require.NoError(t, err)
+	assert.Equal(t, "make -j4", p.command)
+	assert.Equal(t, planStep{command: "make -j4", lang: "sh", runnable: true, original: "make"}, p.steps[0])
+	assert.Zero(t, exec.calls, "an edited step is asked about again")
+
+	_, err = sess.ExecuteLine("r")
+	require.NoError(t, err)
+	assert.Equal(t, "make -j4", exec.lastCmd)
+}
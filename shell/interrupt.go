@@ -0,0 +1,52 @@
+package shell
+
+import (
+	"context"
+	"os"
+	"os/signal"
+)
+
+// interrupter turns Ctrl+C into cancellation of the line being processed, so
+// an interrupted AI query or command stops without ending the REPL.
+type interrupter struct {
+	signals chan os.Signal
+}
+
+// newInterrupter starts catching SIGINT. Call stop to restore the default
+// behaviour.
+func newInterrupter() *interrupter {
+	i := &interrupter{signals: make(chan os.Signal, 1)}
+	signal.Notify(i.signals, os.Interrupt)
+	return i
+}
+
+func (i *interrupter) stop() {
+	signal.Stop(i.signals)
+}
+
+// context returns a context that is cancelled by the next interrupt, and a
+// function to release it once the line is done. A nil interrupter returns a
+// context that is only cancelled by the release function.
+func (i *interrupter) context() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	if i == nil {
+		return ctx, cancel
+	}
+	// Forget an interrupt that arrived while no line was running.
+	select {
+	case <-i.signals:
+	default:
+	}
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-i.signals:
+			cancel()
+		case <-done:
+		}
+	}()
+	return ctx, func() {
+		close(done)
+		cancel()
+	}
+}
@@ -0,0 +1,34 @@
+package shell
+
+import (
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterrupter_CancelsCurrentLineOnly(t *testing.T) {
+	i := newInterrupter()
+	defer i.stop()
+
+	ctx, done := i.context()
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGINT))
+	select {
+	case <-ctx.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("interrupt did not cancel the line")
+	}
+	done()
+
+	next, nextDone := i.context()
+	defer nextDone()
+	assert.NoError(t, next.Err(), "a new line starts uncancelled")
+
+	var none *interrupter
+	ctx, done = none.context()
+	assert.NoError(t, ctx.Err())
+	done()
+	assert.Error(t, ctx.Err())
+}
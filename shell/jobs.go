@@ -0,0 +1,284 @@
+package shell
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/binks-cli/binks/internal/executor"
+	"golang.org/x/sys/unix"
+)
+
+// job is a command running in the background, numbered as `jobs` lists it.
+type job struct {
+	id int
+	*executor.Job
+}
+
+// addJob adds j to the session's jobs, numbered one past the highest number
+// in use.
+func (s *Session) addJob(j *executor.Job) *job {
+	id := 1
+	if n := len(s.jobs); n > 0 {
+		id = s.jobs[n-1].id + 1
+	}
+	added := &job{id: id, Job: j}
+	s.jobs = append(s.jobs, added)
+	return added
+}
+
+// removeJob drops j from the session's jobs.
+func (s *Session) removeJob(j *job) {
+	for i, other := range s.jobs {
+		if other == j {
+			s.jobs = append(s.jobs[:i], s.jobs[i+1:]...)
+			return
+		}
+	}
+}
+
+// startJob starts cmd in the background in the session's directory, with its
+// output kept until the job is brought to the foreground or finishes.
+func (s *Session) startJob(cmd string) (*job, error) {
+	st, ok := s.Executor.(executor.Starter)
+	if !ok {
+		return nil, errors.New("this executor cannot run commands in the background")
+	}
+	j, err := st.Start(cmd, s.cwd)
+	if err != nil {
+		return nil, err
+	}
+	return s.addJob(j), nil
+}
+
+// findJob returns the job named by spec: %n or n for job n, and "", %% or %+
+// for the latest.
+func (s *Session) findJob(spec string) (*job, error) {
+	switch spec {
+	case "", "%", "%%", "%+":
+		if len(s.jobs) == 0 {
+			return nil, errors.New("no current job")
+		}
+		return s.jobs[len(s.jobs)-1], nil
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(spec, "%"))
+	if err == nil {
+		for _, j := range s.jobs {
+			if j.id == id {
+				return j, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no such job: %s", spec)
+}
+
+// terminal returns the terminal a job brought to the foreground is attached
+// to, or nil outside the interactive REPL.
+func (s *Session) terminal() *os.File {
+	if s.interrupts == nil {
+		return nil
+	}
+	return os.Stdin
+}
+
+// backgroundCommand returns the command a line ending in '&' runs in the
+// background, and whether it does. '&&' and '>&' do not count.
+func backgroundCommand(line string) (string, bool) {
+	line = strings.TrimSpace(line)
+	if !strings.HasSuffix(line, "&") || strings.HasSuffix(line, "&&") || strings.HasSuffix(line, ">&") {
+		return "", false
+	}
+	cmd := strings.TrimSpace(strings.TrimSuffix(line, "&"))
+	return cmd, cmd != ""
+}
+
+// isJobBuiltin reports whether line is one of the job control built-ins:
+// jobs, fg, bg, or kill naming only jobs (kill %1). Other kills run in the
+// shell.
+func isJobBuiltin(line string) bool {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return false
+	}
+	switch fields[0] {
+	case "jobs", "fg", "bg":
+		return true
+	case "kill":
+		targets := killTargets(fields[1:])
+		if len(targets) == 0 {
+			return false
+		}
+		for _, t := range targets {
+			if !strings.HasPrefix(t, "%") {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// killTargets returns the arguments of kill after a leading signal option.
+func killTargets(args []string) []string {
+	if len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		return args[1:]
+	}
+	return args
+}
+
+// runJobBuiltin runs one of the job control built-ins.
+func runJobBuiltin(ctx context.Context, line string, sess *Session, out io.Writer) error {
+	fields := strings.Fields(line)
+	var spec string
+	if len(fields) > 1 {
+		spec = fields[1]
+	}
+	switch fields[0] {
+	case "jobs":
+		for _, j := range append([]*job(nil), sess.jobs...) {
+			if j.Result() != nil {
+				sess.reportJob(j, out)
+				continue
+			}
+			fmt.Fprint(out, formatJob(j))
+		}
+		return nil
+	case "fg":
+		j, err := sess.findJob(spec)
+		if err != nil {
+			return err
+		}
+		return sess.foreground(ctx, j, out)
+	case "bg":
+		j, err := sess.findJob(spec)
+		if err != nil {
+			return err
+		}
+		if !j.Stopped() {
+			return fmt.Errorf("job %d is already in the background", j.id)
+		}
+		if err := j.Continue(); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "[%d] %s &\n", j.id, j.Command)
+		return nil
+	default: // kill
+		return sess.killJobs(fields[1:])
+	}
+}
+
+// foreground brings j to the foreground until it exits or is stopped again,
+// and reports how it ended as the last command.
+func (s *Session) foreground(ctx context.Context, j *job, out io.Writer) error {
+	fmt.Fprintln(out, j.Command)
+	detached, err := j.Foreground(ctx, s.terminal(), out)
+	if detached {
+		if ctx.Err() == nil {
+			fmt.Fprint(out, "\n"+formatJob(j))
+		}
+		if err != nil && ctx.Err() == nil {
+			return err
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	s.removeJob(j)
+	res := j.Result()
+	var exitErr error
+	if res.ExitCode != 0 {
+		exitErr = &executor.ExitError{Code: res.ExitCode}
+	}
+	s.recordCommand(res)
+	s.last = &lastCommand{command: j.Command, result: res, err: exitErr}
+	if exitErr != nil {
+		return s.last.failure()
+	}
+	return nil
+}
+
+// killJobs sends a signal, SIGTERM unless args start with one such as -9 or
+// -KILL, to each job args name.
+func (s *Session) killJobs(args []string) error {
+	sig := syscall.SIGTERM
+	if len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		name := strings.TrimPrefix(args[0], "-")
+		if n, err := strconv.Atoi(name); err == nil {
+			sig = syscall.Signal(n)
+		} else if sig = unix.SignalNum("SIG" + strings.TrimPrefix(strings.ToUpper(name), "SIG")); sig == 0 {
+			return fmt.Errorf("unknown signal: %s", name)
+		}
+	}
+	for _, spec := range killTargets(args) {
+		j, err := s.findJob(spec)
+		if err != nil {
+			return err
+		}
+		switch sig {
+		case syscall.SIGSTOP, syscall.SIGTSTP:
+			err = j.Stop()
+		case syscall.SIGCONT:
+			err = j.Continue()
+		default:
+			err = j.Signal(sig)
+			if err == nil && j.Stopped() {
+				// A stopped job only acts on the signal once it runs again.
+				err = j.Continue()
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("job %d: %w", j.id, err)
+		}
+	}
+	return nil
+}
+
+// reportJobs shows the jobs that have finished, with what they wrote since
+// it was last shown, and forgets them.
+func (s *Session) reportJobs(w io.Writer) {
+	for _, j := range append([]*job(nil), s.jobs...) {
+		if j.Result() != nil {
+			s.reportJob(j, w)
+		}
+	}
+}
+
+// reportJob shows a finished job's remaining output and how it ended.
+func (s *Session) reportJob(j *job, w io.Writer) {
+	if output := j.Output(); output != "" {
+		fmt.Fprint(w, output)
+		if !strings.HasSuffix(output, "\n") {
+			fmt.Fprint(w, "\n")
+		}
+	}
+	fmt.Fprint(w, formatJob(j))
+	s.removeJob(j)
+}
+
+// formatJob returns the line describing a job in the jobs list, such as
+// "[1] Running  sleep 10".
+func formatJob(j *job) string {
+	return fmt.Sprintf("[%d] %-8s %s\n", j.id, jobStatus(j.Job), j.Command)
+}
+
+// jobStatus describes whether a job is running or stopped, or how it ended.
+func jobStatus(j *executor.Job) string {
+	res := j.Result()
+	switch {
+	case res == nil && j.Stopped():
+		return "Stopped"
+	case res == nil:
+		return "Running"
+	case res.Signal != 0:
+		return res.SignalName()
+	case res.ExitCode != 0:
+		return fmt.Sprintf("Exit %d", res.ExitCode)
+	}
+	return "Done"
+}
@@ -0,0 +1,179 @@
+package shell
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/binks-cli/binks/internal/executor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// jobSession returns a session running commands in new bash processes.
+func jobSession(t *testing.T) *Session {
+	sess := NewSession()
+	sess.Executor = executor.NewBashExecutor()
+	sess.cwd = t.TempDir()
+	return sess
+}
+
+// waitForJobs waits for every job in the session to finish.
+func waitForJobs(t *testing.T, sess *Session) {
+	t.Helper()
+	for _, j := range sess.jobs {
+		select {
+		case <-j.Done():
+		case <-time.After(5 * time.Second):
+			t.Fatalf("job %d did not finish", j.id)
+		}
+	}
+}
+
+func TestBackgroundCommand(t *testing.T) {
+	tests := []struct {
+		line string
+		cmd  string
+		ok   bool
+	}{
+		{"sleep 10 &", "sleep 10", true},
+		{"sleep 10&", "sleep 10", true},
+		{"make && make test", "", false},
+		{"make &&", "", false},
+		{"echo >&", "", false},
+		{"&", "", false},
+		{"echo hi", "", false},
+	}
+	for _, tt := range tests {
+		cmd, ok := backgroundCommand(tt.line)
+		assert.Equal(t, tt.ok, ok, tt.line)
+		assert.Equal(t, tt.cmd, cmd, tt.line)
+	}
+}
+
+func TestIsJobBuiltin(t *testing.T) {
+	for line, want := range map[string]bool{
+		"jobs":          true,
+		"fg":            true,
+		"fg %2":         true,
+		"bg %1":         true,
+		"kill %1":       true,
+		"kill -9 %1 %2": true,
+		"kill 1234":     false,
+		"kill -9 1234":  false,
+		"kill %1 1234":  false,
+		"kill":          false,
+		"jobsort":       false,
+	} {
+		assert.Equal(t, want, isJobBuiltin(line), line)
+	}
+}
+
+func TestREPL_BackgroundJobReportsDone(t *testing.T) {
+	sess := jobSession(t)
+	var out, errOut bytes.Buffer
+
+	processREPLLine("sleep 0.2; echo bg-out &", sess, &out, &errOut)
+	assert.Regexp(t, `^\[1\] \d+\n$`, out.String())
+	require.Len(t, sess.jobs, 1)
+
+	out.Reset()
+	processREPLLine("jobs", sess, &out, &errOut)
+	assert.Equal(t, "[1] Running  sleep 0.2; echo bg-out\n", out.String())
+
+	waitForJobs(t, sess)
+	out.Reset()
+	sess.reportJobs(&out)
+	assert.Equal(t, "bg-out\n[1] Done     sleep 0.2; echo bg-out\n", out.String())
+	assert.Empty(t, sess.jobs)
+	assert.Empty(t, errOut.String())
+}
+
+func TestREPL_StopContinueAndKillJob(t *testing.T) {
+	sess := jobSession(t)
+	var out, errOut bytes.Buffer
+
+	processREPLLine("sleep 5 &", sess, &out, &errOut)
+	processREPLLine("kill -STOP %1", sess, &out, &errOut)
+	out.Reset()
+	processREPLLine("jobs", sess, &out, &errOut)
+	assert.Equal(t, "[1] Stopped  sleep 5\n", out.String())
+
+	out.Reset()
+	processREPLLine("bg %1", sess, &out, &errOut)
+	assert.Equal(t, "[1] sleep 5 &\n", out.String())
+	processREPLLine("bg", sess, &out, &errOut)
+	assert.Contains(t, errOut.String(), "job 1 is already in the background")
+
+	errOut.Reset()
+	processREPLLine("kill -BOGUS %1", sess, &out, &errOut)
+	assert.Contains(t, errOut.String(), "unknown signal: BOGUS")
+	errOut.Reset()
+	processREPLLine("kill %2", sess, &out, &errOut)
+	assert.Contains(t, errOut.String(), "no such job: %2")
+
+	errOut.Reset()
+	processREPLLine("kill %1", sess, &out, &errOut)
+	assert.Empty(t, errOut.String())
+	waitForJobs(t, sess)
+	out.Reset()
+	sess.reportJobs(&out)
+	assert.Equal(t, "[1] SIGTERM  sleep 5\n", out.String())
+}
+
+func TestREPL_ForegroundJob(t *testing.T) {
+	sess := jobSession(t)
+	var out, errOut bytes.Buffer
+
+	processREPLLine("echo early; sleep 0.2; echo late; exit 4 &", sess, &out, &errOut)
+	out.Reset()
+	processREPLLine("fg", sess, &out, &errOut)
+	assert.Equal(t, "echo early; sleep 0.2; echo late; exit 4\nearly\nlate\n", out.String())
+	assert.Contains(t, errOut.String(), "Error: exit status 4")
+	assert.Empty(t, sess.jobs, "a job that ends in the foreground is done with")
+	require.NotNil(t, sess.last)
+	assert.Equal(t, 4, sess.last.result.ExitCode)
+
+	errOut.Reset()
+	processREPLLine("fg", sess, &out, &errOut)
+	assert.Contains(t, errOut.String(), "no current job")
+}
+
+func TestREPL_JobNumbersFollowHighest(t *testing.T) {
+	sess := jobSession(t)
+	var out bytes.Buffer
+
+	processREPLLine("sleep 5 &", sess, &out, &out)
+	processREPLLine("sleep 5 &", sess, &out, &out)
+	processREPLLine("kill %1", sess, &out, &out)
+	processREPLLine("kill -9 %2", sess, &out, &out)
+	waitForJobs(t, sess)
+	sess.reportJobs(&out)
+	processREPLLine("sleep 5 &", sess, &out, &out)
+	require.Len(t, sess.jobs, 1)
+	assert.Equal(t, 1, sess.jobs[0].id, "numbering starts over once every job is gone")
+	processREPLLine("kill -KILL %1", sess, &out, &out)
+	waitForJobs(t, sess)
+}
+
+func TestREPL_BackgroundNeedsStarter(t *testing.T) {
+	sess := &Session{Executor: &mockExecutor{}}
+	var out, errOut bytes.Buffer
+	processREPLLine("sleep 10 &", sess, &out, &errOut)
+	assert.Contains(t, errOut.String(), "cannot run commands in the background")
+	assert.Empty(t, sess.jobs)
+}
+
+func TestSession_TracksLaunchedApps(t *testing.T) {
+	sess := jobSession(t)
+	res, err := sess.RunCommandResult(context.Background(), "open /nonexistent")
+	require.NoError(t, err)
+	require.Len(t, sess.jobs, 1)
+	assert.Same(t, res.Job, sess.jobs[0].Job)
+
+	waitForJobs(t, sess)
+	var out bytes.Buffer
+	sess.reportJobs(&out)
+	assert.Regexp(t, `^\[1\] Exit \d+ +open /nonexistent\n$`, out.String())
+}
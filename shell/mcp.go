@@ -0,0 +1,126 @@
+package shell
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/binks-cli/binks/internal/mcp"
+)
+
+// mcpTimeout bounds MCP requests made while setting up or browsing servers.
+const mcpTimeout = 10 * time.Second
+
+// connectMCP starts the configured MCP servers and offers their tools to the
+// agent. Servers that fail to start are reported on Err and skipped.
+func (s *Session) connectMCP(servers []mcp.ServerConfig) {
+	for _, cfg := range servers {
+		client, err := mcp.Start(cfg)
+		if err != nil {
+			fmt.Fprintf(s.Err, "binks: %v\n", err)
+			continue
+		}
+		s.mcpClients = append(s.mcpClients, client)
+		if s.Tools == nil {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), mcpTimeout)
+		tools, err := client.AgentTools(ctx)
+		cancel()
+		if err != nil {
+			fmt.Fprintf(s.Err, "binks: listing tools of MCP server %q: %v\n", cfg.Name, err)
+			continue
+		}
+		for _, t := range tools {
+			s.Tools.Register(t)
+		}
+	}
+}
+
+// Close releases resources held by the session, stopping any MCP servers and
+// the executor's shell if it keeps one.
+func (s *Session) Close() error {
+	var errs []error
+	if c, ok := s.Executor.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, c := range s.mcpClients {
+		if err := c.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	s.mcpClients = nil
+	return errors.Join(errs...)
+}
+
+// mcpClient returns the connected server with the given name.
+func (s *Session) mcpClient(name string) (*mcp.Client, error) {
+	for _, c := range s.mcpClients {
+		if c.Name == name {
+			return c, nil
+		}
+	}
+	return nil, fmt.Errorf("no MCP server named %q", name)
+}
+
+func metaMCP(ctx context.Context, sess *Session, args []string, out io.Writer) error {
+	ctx, cancel := context.WithTimeout(ctx, mcpTimeout)
+	defer cancel()
+	if len(args) == 0 || args[0] == "tools" {
+		if len(sess.mcpClients) == 0 {
+			fmt.Fprintln(out, "No MCP servers connected. Add them under 'mcp: servers:' in ~/.binks.yaml.")
+			return nil
+		}
+		for _, c := range sess.mcpClients {
+			fmt.Fprintf(out, "%s (%s %s)\n", c.Name, c.ServerInfo.Name, c.ServerInfo.Version)
+			tools, err := c.ListTools(ctx)
+			if err != nil {
+				fmt.Fprintf(out, "  error: %v\n", err)
+				continue
+			}
+			for _, t := range tools {
+				fmt.Fprintf(out, "  %-24s %s\n", mcp.ToolName(c.Name, t.Name), t.Description)
+			}
+		}
+		return nil
+	}
+	switch args[0] {
+	case "resources":
+		for _, c := range sess.mcpClients {
+			resources, err := c.ListResources(ctx)
+			if err != nil {
+				fmt.Fprintf(out, "%s: error: %v\n", c.Name, err)
+				continue
+			}
+			for _, r := range resources {
+				fmt.Fprintf(out, "%s %s  %s\n", c.Name, r.URI, r.Name)
+			}
+		}
+		return nil
+	case "read":
+		if len(args) != 3 {
+			return errors.New("usage: :mcp read <server> <uri>")
+		}
+		c, err := sess.mcpClient(args[1])
+		if err != nil {
+			return err
+		}
+		contents, err := c.ReadResource(ctx, args[2])
+		if err != nil {
+			return err
+		}
+		for _, rc := range contents {
+			if rc.Text != "" {
+				fmt.Fprintln(out, rc.Text)
+			} else {
+				fmt.Fprintf(out, "[binary %s, %d bytes base64]\n", rc.MimeType, len(rc.Blob))
+			}
+		}
+		return nil
+	}
+	return errors.New("usage: :mcp [tools|resources|read <server> <uri>]")
+}
@@ -0,0 +1,102 @@
+package shell
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/binks-cli/binks/internal/agent"
+	"github.com/binks-cli/binks/internal/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func buildFakeMCPServer(t *testing.T) string {
+	t.Helper()
+	bin := filepath.Join(t.TempDir(), "fakeserver")
+	build := exec.Command("go", "build", "-o", bin, "../internal/mcp/testdata/fakeserver")
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Skipf("could not build fake MCP server: %v\n%s", err, out)
+	}
+	return bin
+}
+
+func TestSession_ConnectMCP(t *testing.T) {
+	bin := buildFakeMCPServer(t)
+	var errOut strings.Builder
+	sess := &Session{Executor: &mockExecutor{}, cwd: ".", Err: &errOut}
+	sess.Tools = agent.BuiltinTools(agent.ToolEnv{RunCommand: sess.RunCommandContext, Cwd: sess.Cwd})
+	sess.connectMCP([]mcp.ServerConfig{
+		{Name: "fake", Command: bin},
+		{Name: "broken", Command: "/no/such/server"},
+	})
+	defer sess.Close()
+
+	assert.Contains(t, errOut.String(), `starting MCP server "broken"`)
+	require.Len(t, sess.mcpClients, 1)
+	_, ok := sess.Tools.Lookup("fake__echo")
+	assert.True(t, ok, "MCP tools are offered to the agent")
+
+	// MCP tool calls go through the same confirmation gate as built-in tools.
+	ag := &scriptedToolAgent{replies: []agent.Reply{
+		{ToolCalls: []agent.ToolCall{{ID: "c1", Name: "fake__echo", Arguments: `{"text":"ping"}`}}},
+		{Content: "The server said ping."},
+	}}
+	sess.Agent = ag
+	resp, err := sess.ExecuteLine(">> ping the server")
+	require.NoError(t, err)
+	assert.Equal(t, "[AI]", resp)
+	assert.Equal(t, `mcp fake/echo {"text":"ping"}`, sess.pendingSuggestion.command)
+	resp, err = sess.ExecuteLine("yes")
+	require.NoError(t, err)
+	assert.Equal(t, "[AI] The server said ping.", resp)
+	last := ag.requests[1]
+	assert.Equal(t, "ping", last[len(last)-1].Content)
+
+	var out strings.Builder
+	require.NoError(t, runMetaCommand(context.Background(), ":mcp", sess, &out))
+	assert.Contains(t, out.String(), "fake (fake 1.0)")
+	assert.Contains(t, out.String(), "fake__echo")
+
+	out.Reset()
+	require.NoError(t, runMetaCommand(context.Background(), ":mcp resources", sess, &out))
+	assert.Contains(t, out.String(), "fake memo://greeting  greeting")
+
+	out.Reset()
+	require.NoError(t, runMetaCommand(context.Background(), ":mcp read fake memo://greeting", sess, &out))
+	assert.Equal(t, "hello from fake\n", out.String())
+	assert.EqualError(t, runMetaCommand(context.Background(), ":mcp read nope memo://x", sess, &out), `no MCP server named "nope"`)
+	assert.Error(t, runMetaCommand(context.Background(), ":mcp read fake", sess, &out))
+
+	assert.NoError(t, sess.Close())
+	assert.Empty(t, sess.mcpClients)
+}
+
+// closingExecutor records whether it was closed.
+type closingExecutor struct {
+	mockExecutor
+	closed bool
+}
+
+func (e *closingExecutor) Close() error {
+	e.closed = true
+	return errors.New("already closed")
+}
+
+func TestSession_CloseClosesExecutor(t *testing.T) {
+	e := &closingExecutor{}
+	sess := &Session{Executor: e}
+	assert.EqualError(t, sess.Close(), "already closed")
+	assert.True(t, e.closed)
+	assert.NoError(t, (&Session{Executor: &mockExecutor{}}).Close())
+}
+
+func TestMetaCommand_MCPWithoutServers(t *testing.T) {
+	var out strings.Builder
+	require.NoError(t, runMetaCommand(context.Background(), ":mcp", &Session{}, &out))
+	assert.Contains(t, out.String(), "No MCP servers connected")
+	assert.Error(t, runMetaCommand(context.Background(), ":mcp bogus", &Session{}, &out))
+}
@@ -0,0 +1,134 @@
+package shell
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// MetaPrefix starts a meta command, which configures binks itself instead of
+// running in the shell (e.g. ':chat reset').
+const MetaPrefix = ":"
+
+// metaCommand is a built-in command addressed to binks rather than the shell.
+type metaCommand struct {
+	name  string
+	usage string // argument synopsis shown in help
+	help  string
+	run   func(ctx context.Context, sess *Session, args []string, out io.Writer) error
+}
+
+// metaCommands lists the available meta commands in the order help shows them.
+var metaCommands = []metaCommand{
+	{
+		name:  "chat",
+		usage: "[show|reset|trim <n>]",
+		help:  "Show, clear or shorten the AI conversation",
+		run:   metaChat,
+	},
+	{
+		name: "context",
+		help: "Preview the system prompt sent with AI queries",
+		run:  metaContext,
+	},
+	{
+		name:  "model",
+		usage: "[profile]",
+		help:  "List provider profiles or switch to one",
+		run:   metaModel,
+	},
+	{
+		name: "models",
+		help: "List the models offered by the AI provider",
+		run:  metaModels,
+	},
+	{
+		name: "explain",
+		help: "Ask the AI why the last command failed and how to fix it",
+		run:  metaExplain,
+	},
+	{
+		name: "usage",
+		help: "Show AI tokens and cost for the session and per day",
+		run:  metaUsage,
+	},
+	{
+		name:  "audit",
+		usage: "[filters]",
+		help:  "Browse logged AI suggestions (decision=, risk=, since=, failed, words)",
+		run:   metaAudit,
+	},
+	{
+		name:  "mcp",
+		usage: "[tools|resources|read]",
+		help:  "Browse MCP servers (:mcp read <server> <uri>)",
+		run:   metaMCP,
+	},
+}
+
+// isMetaCommand reports whether the line is addressed to binks as a meta command.
+func isMetaCommand(line string) bool {
+	return strings.HasPrefix(line, MetaPrefix) && len(strings.TrimSpace(line)) > len(MetaPrefix)
+}
+
+// runMetaCommand parses and runs a meta command line. Cancelling ctx stops
+// any request it makes.
+func runMetaCommand(ctx context.Context, line string, sess *Session, out io.Writer) error {
+	fields := strings.Fields(strings.TrimPrefix(line, MetaPrefix))
+	name := strings.ToLower(fields[0])
+	for _, mc := range metaCommands {
+		if mc.name == name {
+			return mc.run(ctx, sess, fields[1:], out)
+		}
+	}
+	return fmt.Errorf("unknown command %s%s (type 'help' for a list)", MetaPrefix, name)
+}
+
+// printMetaHelp writes a usage line for every meta command.
+func printMetaHelp(w io.Writer) {
+	for _, mc := range metaCommands {
+		synopsis := MetaPrefix + mc.name
+		if mc.usage != "" {
+			synopsis += " " + mc.usage
+		}
+		fmt.Fprintf(w, "  %-28s – %s\n", synopsis, mc.help)
+	}
+}
+
+func metaChat(_ context.Context, sess *Session, args []string, out io.Writer) error {
+	if len(args) == 0 || args[0] == "show" {
+		printConversation(out, sess.transcript.Messages())
+		return nil
+	}
+	switch args[0] {
+	case "reset":
+		sess.transcript.Reset()
+		fmt.Fprintln(out, "[AI] Conversation cleared.")
+	case "trim":
+		if len(args) != 2 {
+			return errors.New("usage: :chat trim <n>")
+		}
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid message count %q", args[1])
+		}
+		sess.transcript.Trim(n)
+		fmt.Fprintf(out, "[AI] Kept the last %d messages.\n", sess.transcript.Len())
+	default:
+		return errors.New("usage: :chat [show|reset|trim <n>]")
+	}
+	return nil
+}
+
+func metaContext(_ context.Context, sess *Session, _ []string, out io.Writer) error {
+	prompt := sess.SystemPrompt()
+	if prompt == "" {
+		fmt.Fprintln(out, "[AI] No system prompt: every context part is disabled in ~/.binks.yaml.")
+		return nil
+	}
+	fmt.Fprintln(out, prompt)
+	return nil
+}
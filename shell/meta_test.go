@@ -0,0 +1,65 @@
+package shell
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/binks-cli/binks/internal/agent"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsMetaCommand(t *testing.T) {
+	assert.True(t, isMetaCommand(":chat reset"))
+	assert.True(t, isMetaCommand(":chat"))
+	assert.False(t, isMetaCommand(":"))
+	assert.False(t, isMetaCommand("echo :ai"))
+	assert.False(t, isMetaCommand(""))
+}
+
+func TestMetaCommand_Chat(t *testing.T) {
+	sess := &Session{cwd: "."}
+	var out strings.Builder
+
+	assert.NoError(t, runMetaCommand(context.Background(), ":chat", sess, &out))
+	assert.Contains(t, out.String(), "Conversation is empty")
+
+	sess.remember(agent.RoleUser, "first")
+	sess.remember(agent.RoleAssistant, "answer")
+	sess.remember(agent.RoleUser, "second")
+	out.Reset()
+	assert.NoError(t, runMetaCommand(context.Background(), ":chat show", sess, &out))
+	assert.Contains(t, out.String(), "1. [user] first")
+	assert.Contains(t, out.String(), "3. [user] second")
+
+	out.Reset()
+	assert.NoError(t, runMetaCommand(context.Background(), ":chat trim 1", sess, &out))
+	assert.Equal(t, 1, sess.transcript.Len())
+	assert.Error(t, runMetaCommand(context.Background(), ":chat trim x", sess, &out))
+	assert.Error(t, runMetaCommand(context.Background(), ":chat trim", sess, &out))
+
+	assert.NoError(t, runMetaCommand(context.Background(), ":chat reset", sess, &out))
+	assert.Equal(t, 0, sess.transcript.Len())
+	assert.Error(t, runMetaCommand(context.Background(), ":chat bogus", sess, &out))
+}
+
+func TestProcessREPLLine_MetaCommands(t *testing.T) {
+	exec := &mockExecutor{}
+	sess := NewSession()
+	sess.Executor = exec
+	var out, errOut strings.Builder
+
+	processREPLLine(":chat reset", sess, &out, &errOut)
+	assert.Contains(t, out.String(), "[AI] Conversation cleared.")
+
+	processREPLLine(":nosuch", sess, &out, &errOut)
+	assert.Contains(t, errOut.String(), "unknown command :nosuch")
+	assert.Equal(t, 0, exec.calls, "meta commands must not reach the shell")
+}
+
+func TestPrintHelp_ListsMetaCommands(t *testing.T) {
+	var out strings.Builder
+	printHelp(&out)
+	assert.Contains(t, out.String(), "Meta commands:")
+	assert.Contains(t, out.String(), ":chat [show|reset|trim <n>]")
+}
@@ -0,0 +1,224 @@
+package shell
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// codeBlockRe matches a fenced code block and its optional language tag.
+var codeBlockRe = regexp.MustCompile("(?s)```([a-zA-Z0-9_+-]*)[ \t]*\\n(.*?)```")
+
+// shellLanguages are the code block languages treated as runnable commands. A
+// block without a language counts as a command too.
+var shellLanguages = map[string]bool{
+	"":      true,
+	"sh":    true,
+	"bash":  true,
+	"shell": true,
+	"zsh":   true,
+	"fish":  true,
+	"ksh":   true,
+}
+
+// planStep is one code block of an AI answer.
+type planStep struct {
+	command  string
+	lang     string
+	runnable bool   // false for blocks in a non-shell language, which are only shown
+	original string // the suggested command, once the user has edited it
+	needsTTY bool   // a structured answer said the command needs the terminal
+	aiRisk   string // the risk a structured answer gave the command
+}
+
+// stepOutcome records what happened to a plan step.
+type stepOutcome struct {
+	step    int    // index into the plan's steps
+	command string // the command as run, after any edit
+	status  string // "ran", "skipped", "failed" or "aborted"
+	err     error
+}
+
+// parseAIPlan splits an AI answer into its explanation and every fenced code
+// block, in order.
+func parseAIPlan(resp string) (explanation string, steps []planStep) {
+	resp = strings.ReplaceAll(resp, "\r\n", "\n")
+	for _, m := range codeBlockRe.FindAllStringSubmatch(resp, -1) {
+		lang := strings.ToLower(m[1])
+		steps = append(steps, planStep{
+			command:  strings.TrimSpace(m[2]),
+			lang:     lang,
+			runnable: shellLanguages[lang],
+		})
+	}
+	if len(steps) == 0 {
+		return resp, nil
+	}
+	return strings.TrimSpace(codeBlockRe.ReplaceAllString(resp, "")), steps
+}
+
+// isPlan reports whether p steps through several code blocks rather than
+// confirming a single command.
+func (p *PendingSuggestion) isPlan() bool {
+	return len(p.steps) > 0
+}
+
+// nextRunnable moves p.current to the first runnable step at or after i and
+// reports whether there is one.
+func (p *PendingSuggestion) nextRunnable(i int) bool {
+	for ; i < len(p.steps); i++ {
+		if p.steps[i].runnable {
+			p.current = i
+			p.command = p.steps[i].command
+			p.needsTTY = p.steps[i].needsTTY
+			return true
+		}
+	}
+	p.current = len(p.steps)
+	return false
+}
+
+// advancePlan moves p to its first runnable step at or after i, assessing
+// that step's risk, and reports whether there is one.
+func (s *Session) advancePlan(p *PendingSuggestion, i int) bool {
+	if !p.nextRunnable(i) {
+		return false
+	}
+	p.risk = s.suggestionRisk(p.command, p.steps[p.current].aiRisk)
+	return true
+}
+
+// editStep replaces the command of p's current step, unless cmd is empty. The
+// new command is rated no lower than the AI rated the step.
+func (s *Session) editStep(p *PendingSuggestion, cmd string) {
+	cmd = strings.TrimSpace(cmd)
+	if cmd == "" {
+		return
+	}
+	step := &p.steps[p.current]
+	if step.original == "" {
+		step.original = step.command
+	}
+	step.command = cmd
+	p.command = cmd
+	p.risk = s.suggestionRisk(cmd, step.aiRisk)
+}
+
+// runnableSteps counts the steps of p that can be run.
+func (p *PendingSuggestion) runnableSteps() int {
+	n := 0
+	for _, st := range p.steps {
+		if st.runnable {
+			n++
+		}
+	}
+	return n
+}
+
+// answerPlan handles the answer to the current step of a plan: run it, skip it,
+// edit it or abort the rest. The plan ends, and the pending suggestion is
+// cleared, after the last step, on abort, or when a step fails.
+func (s *Session) answerPlan(ctx context.Context, answer string) (string, error) {
+	p := s.pendingSuggestion
+	step := &p.steps[p.current]
+	if p.editing {
+		p.editing = false
+		s.editStep(p, answer)
+		return "", nil
+	}
+	answer = strings.TrimSpace(answer)
+	lower := strings.ToLower(answer)
+	switch {
+	case p.risk.accepts(answer) || (p.risk.level != riskHigh && (lower == "r" || lower == "run")):
+		output, err := s.runSuggestion(p.runContext(ctx), p, step.command)
+		if err != nil {
+			p.history = append(p.history, stepOutcome{step: p.current, command: step.command, status: "failed", err: err})
+			s.pendingSuggestion = nil
+			return output, err
+		}
+		p.history = append(p.history, stepOutcome{step: p.current, command: step.command, status: "ran"})
+		if !s.advancePlan(p, p.current+1) {
+			s.pendingSuggestion = nil
+		}
+		return output, nil
+	case lower == "r" || lower == "run" || lower == "y":
+		// A high-risk step needs the word itself, so a habitual r does not run it.
+		return fmt.Sprintf("[AI] Step %d is high risk: type %s to run it.", p.current+1, p.risk.highAnswers()), nil
+	case lower == "s" || lower == "skip":
+		s.declineSuggestion(p)
+		p.history = append(p.history, stepOutcome{step: p.current, command: step.command, status: "skipped"})
+		if !s.advancePlan(p, p.current+1) {
+			s.pendingSuggestion = nil
+		}
+		return "", nil
+	case lower == "e" || lower == "edit":
+		if s.editLine == nil && !strings.Contains(step.command, "\n") {
+			// Without a line editor the next answer is the new command.
+			p.editing = true
+			return "", nil
+		}
+		edited, err := s.editCommand(step.command)
+		if err != nil {
+			return "[AI] error: " + err.Error(), err
+		}
+		s.editStep(p, edited)
+		return "", nil
+	default:
+		p.history = append(p.history, stepOutcome{step: p.current, command: step.command, status: "aborted"})
+		s.declineSuggestion(p)
+		s.pendingSuggestion = nil
+		return "[AI] Cancelled.", nil
+	}
+}
+
+// printPlan lists every step of a plan. Steps that will not be run are shown
+// with their language.
+func printPlan(out io.Writer, p *PendingSuggestion) {
+	fmt.Fprintf(out, "AI suggests a plan of %d steps:\n", len(p.steps))
+	for i, st := range p.steps {
+		label := fmt.Sprintf("%2d. ", i+1)
+		if !st.runnable {
+			label += "(" + st.lang + ", not run) "
+		}
+		fmt.Fprintf(out, "%s%s\n", label, indentLines(st.command, strings.Repeat(" ", 4)))
+	}
+}
+
+// printPlanPrompt asks what to do with the current step of a plan.
+func printPlanPrompt(out io.Writer, p *PendingSuggestion) {
+	if p.editing {
+		fmt.Fprintf(out, "New command for step %d (empty keeps it): ", p.current+1)
+		return
+	}
+	fmt.Fprintf(out, "Step %d/%d: %s%s\n", p.current+1, len(p.steps), p.command, p.risk.note())
+	if p.risk.level == riskHigh {
+		fmt.Fprintf(out, "Type %s to run it, or [s]kip, [e]dit or [a]bort? ", p.risk.highAnswers())
+		return
+	}
+	fmt.Fprint(out, "[r]un, [s]kip, [e]dit or [a]bort? ")
+}
+
+// planSummary describes how a finished plan went.
+func planSummary(p *PendingSuggestion) string {
+	counts := map[string]int{}
+	for _, o := range p.history {
+		counts[o.status]++
+	}
+	summary := fmt.Sprintf("%d of %d steps run, %d skipped", counts["ran"], p.runnableSteps(), counts["skipped"])
+	if n := len(p.history); n > 0 {
+		switch last := p.history[n-1]; last.status {
+		case "failed":
+			return fmt.Sprintf("[AI] Plan stopped: step %d failed (%s).", last.step+1, summary)
+		case "aborted":
+			return fmt.Sprintf("[AI] Plan aborted at step %d (%s).", last.step+1, summary)
+		}
+	}
+	return "[AI] Plan finished (" + summary + ")."
+}
+
+// indentLines indents every line of s after the first.
+func indentLines(s, indent string) string {
+	return strings.ReplaceAll(s, "\n", "\n"+indent)
+}
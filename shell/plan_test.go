@@ -0,0 +1,124 @@
+package shell
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const planAnswer = "First update, then test:\n```sh\ngit pull\n```\nThe config looks like:\n```yaml\nci: true\n```\n```bash\nfalse\n```\n```\nmake test\n```"
+
+// planExecutor records the commands it runs and fails "false".
+type planExecutor struct{ ran []string }
+
+func (e *planExecutor) RunCommand(_ context.Context, cmd string) (string, error) {
+	e.ran = append(e.ran, cmd)
+	if cmd == "false" {
+		return "", errors.New("exit status 1")
+	}
+	return "ok: " + cmd, nil
+}
+
+func (e *planExecutor) RunCommandWithDir(ctx context.Context, cmd, _ string) (string, error) {
+	return e.RunCommand(ctx, cmd)
+}
+
+func TestParseAIPlan(t *testing.T) {
+	explanation, steps := parseAIPlan(planAnswer)
+	assert.Equal(t, "First update, then test:\n\nThe config looks like:", explanation)
+	assert.Equal(t, []planStep{
+		{command: "git pull", lang: "sh", runnable: true},
+		{command: "ci: true", lang: "yaml", runnable: false},
+		{command: "false", lang: "bash", runnable: true},
+		{command: "make test", lang: "", runnable: true},
+	}, steps)
+
+	explanation, steps = parseAIPlan("no code here")
+	assert.Equal(t, "no code here", explanation)
+	assert.Nil(t, steps)
+}
+
+func TestPlan_StopsOnFirstFailure(t *testing.T) {
+	exec := &planExecutor{}
+	sess := &Session{cwd: ".", Executor: exec, AIEnabled: true, Agent: agentFuncMock(func(string) (string, error) {
+		return planAnswer, nil
+	})}
+	var out, errOut strings.Builder
+
+	processREPLLine("set up the project", sess, &out, &errOut)
+	assert.Contains(t, out.String(), "AI suggests a plan of 4 steps:")
+	assert.Contains(t, out.String(), " 2. (yaml, not run) ci: true")
+	assert.Contains(t, out.String(), "Step 1/4: git pull\n[r]un, [s]kip, [e]dit or [a]bort? ")
+	out.Reset()
+
+	processREPLLine("r", sess, &out, &errOut)
+	assert.Contains(t, out.String(), "ok: git pull")
+	assert.Contains(t, out.String(), "Step 3/4: false", "the yaml block is not a step to run")
+	p := sess.pendingSuggestion
+	out.Reset()
+
+	processREPLLine("run", sess, &out, &errOut)
+	assert.Contains(t, errOut.String(), "exit status 1")
+	assert.Contains(t, out.String(), "[AI] Plan stopped: step 3 failed (1 of 3 steps run, 0 skipped).")
+	assert.Nil(t, sess.pendingSuggestion)
+	assert.Equal(t, []string{"git pull", "false"}, exec.ran, "make test never runs")
+	require.Len(t, p.history, 2)
+	assert.Equal(t, "ran", p.history[0].status)
+	assert.Equal(t, "failed", p.history[1].status)
+}
+
+func TestPlan_SkipEditAbort(t *testing.T) {
+	exec := &planExecutor{}
+	sess := &Session{cwd: ".", Executor: exec, Agent: agentFuncMock(func(string) (string, error) {
+		return planAnswer, nil
+	})}
+
+	resp, err := sess.ExecuteLine(">> set up")
+	require.NoError(t, err)
+	assert.Equal(t, "[AI]", resp)
+	p := sess.pendingSuggestion
+	require.True(t, p.isPlan())
+
+	_, err = sess.ExecuteLine("s")
+	require.NoError(t, err)
+	assert.Equal(t, "false", p.command)
+
+	_, err = sess.ExecuteLine("e")
+	require.NoError(t, err)
+	assert.True(t, p.editing)
+	_, err = sess.ExecuteLine("true")
+	require.NoError(t, err)
+	resp, err = sess.ExecuteLine("r")
+	require.NoError(t, err)
+	assert.Equal(t, "ok: true", resp)
+	assert.Equal(t, "make test", p.command)
+
+	resp, err = sess.ExecuteLine("n")
+	require.NoError(t, err)
+	assert.Equal(t, "[AI] Cancelled.", resp)
+	assert.Nil(t, sess.pendingSuggestion)
+	assert.Equal(t, []string{"true"}, exec.ran)
+	assert.Equal(t, []stepOutcome{
+		{step: 0, command: "git pull", status: "skipped"},
+		{step: 2, command: "true", status: "ran"},
+		{step: 3, command: "make test", status: "aborted"},
+	}, p.history)
+	assert.Equal(t, "[AI] Plan aborted at step 4 (1 of 3 steps run, 1 skipped).", planSummary(p))
+}
+
+func TestPresentResponse_NonShellBlocks(t *testing.T) {
+	sess := &Session{cwd: "."}
+	resp, err := sess.presentResponse("Add this:\n```go\nfmt.Println(1)\n```")
+	require.NoError(t, err)
+	assert.Equal(t, "[AI] Add this:\n```go\nfmt.Println(1)\n```", resp)
+	assert.Nil(t, sess.pendingSuggestion)
+
+	resp, err = sess.presentResponse("```go\na\n```\n```yaml\nb: c\n```")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(resp, "[AI] "))
+	assert.Nil(t, sess.pendingSuggestion, "nothing to run")
+}
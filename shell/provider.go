@@ -0,0 +1,201 @@
+package shell
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/binks-cli/binks/internal/agent"
+)
+
+// selectAgent sets up the session's agent at startup. A profile named by
+// BINKS_AI_PROFILE or ai.profile wins; otherwise the provider is chosen as
+// described at newAgent.
+func (s *Session) selectAgent(cfg AIConfig) error {
+	s.profiles = cfg.Profiles
+	name := os.Getenv("BINKS_AI_PROFILE")
+	if name == "" {
+		name = cfg.Profile
+	}
+	if name != "" {
+		err := s.UseProfile(name)
+		if err == nil {
+			return nil
+		}
+		s.Agent, _ = newAgent(cfg) // fall back to the provider defaults
+		return err
+	}
+	ag, err := newAgent(cfg)
+	s.Agent = ag
+	return err
+}
+
+// UseProfile switches the session to the named provider profile, rebuilding
+// its agent. The current agent is kept if the profile cannot be used.
+func (s *Session) UseProfile(name string) error {
+	for _, p := range s.profiles {
+		if p.Name != name {
+			continue
+		}
+		ag, err := agent.NewAgent(p)
+		if err != nil {
+			return err
+		}
+		s.Agent = ag
+		s.profile = p.Name
+		return nil
+	}
+	return fmt.Errorf("unknown profile %q", name)
+}
+
+// newAgent builds the AI agent for the configured provider. The provider is
+// taken from BINKS_AI_PROVIDER, then ai.provider in ~/.binks.yaml; when neither
+// is set, the first provider with an API key in the environment is used, and
+// the echoing DummyAgent otherwise.
+func newAgent(cfg AIConfig) (agent.Agent, error) {
+	provider := os.Getenv("BINKS_AI_PROVIDER")
+	if provider == "" {
+		provider = cfg.Provider
+	}
+	if strings.TrimSpace(provider) != "" {
+		ag, err := agent.NewAgent(agent.Profile{Type: provider})
+		if err != nil {
+			return &agent.DummyAgent{}, err
+		}
+		return ag, nil
+	}
+	switch {
+	case os.Getenv("OPENAI_API_KEY") != "":
+		return agent.NewOpenAIAgent(), nil
+	case os.Getenv("ANTHROPIC_API_KEY") != "":
+		return agent.NewAnthropicAgent(), nil
+	}
+	return &agent.DummyAgent{}, nil
+}
+
+// agentProvider names the provider behind an agent.
+func agentProvider(ag agent.Agent) string {
+	switch ag.(type) {
+	case *agent.OpenAIAgent:
+		return "openai"
+	case *agent.AnthropicAgent:
+		return "anthropic"
+	case *agent.OllamaAgent:
+		return "ollama"
+	case *agent.LlamaCppAgent:
+		return "llamacpp"
+	case *agent.DummyAgent:
+		return "dummy"
+	}
+	return "custom"
+}
+
+// agentModel returns the model an agent is configured to use, if it has one.
+func agentModel(ag agent.Agent) string {
+	switch a := ag.(type) {
+	case *agent.OpenAIAgent:
+		return a.Model
+	case *agent.AnthropicAgent:
+		return a.Model
+	case *agent.OllamaAgent:
+		return a.Model
+	case *agent.LlamaCppAgent:
+		return a.Model
+	}
+	return ""
+}
+
+func metaModels(ctx context.Context, sess *Session, _ []string, out io.Writer) error {
+	lister, ok := sess.Agent.(agent.ModelLister)
+	if !ok {
+		return errors.New("the current AI provider cannot list models")
+	}
+	models, err := lister.ListModels(ctx)
+	if err != nil {
+		return err
+	}
+	if len(models) == 0 {
+		fmt.Fprintln(out, "[AI] The server offers no models.")
+		return nil
+	}
+	current := agentModel(sess.Agent)
+	for _, m := range models {
+		marker := " "
+		if m == current {
+			marker = "*"
+		}
+		fmt.Fprintf(out, "%s %s\n", marker, m)
+	}
+	return nil
+}
+
+func metaModel(_ context.Context, sess *Session, args []string, out io.Writer) error {
+	switch len(args) {
+	case 0:
+		current := strings.TrimSpace(agentProvider(sess.Agent) + " " + agentModel(sess.Agent))
+		if len(sess.profiles) == 0 {
+			fmt.Fprintf(out, "[AI] Using %s. Add profiles under ai.profiles in ~/.binks.yaml to switch.\n", current)
+			return nil
+		}
+		width := 0
+		for _, p := range sess.profiles {
+			width = max(width, len(p.Name))
+		}
+		for _, p := range sess.profiles {
+			marker := " "
+			if p.Name == sess.profile {
+				marker = "*"
+			}
+			fmt.Fprintf(out, "%s %-*s  %s\n", marker, width, p.Name, strings.TrimSpace(p.Type+" "+p.Model))
+		}
+		if sess.profile == "" {
+			fmt.Fprintf(out, "[AI] No profile active; using %s.\n", current)
+		}
+	case 1:
+		if err := sess.UseProfile(args[0]); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "[AI] Switched to %s (%s).\n", sess.profile,
+			strings.TrimSpace(agentProvider(sess.Agent)+" "+agentModel(sess.Agent)))
+	default:
+		return errors.New("usage: :model [profile]")
+	}
+	return nil
+}
+
+// aiErrorHint suggests what to do about a typed provider error, or returns ""
+// when there is nothing specific to suggest.
+func aiErrorHint(err error) string {
+	var apiErr *agent.APIError
+	errors.As(err, &apiErr)
+	switch {
+	case errors.Is(err, agent.ErrAuthFailed):
+		return "The API key was rejected. Check the key (e.g. OPENAI_API_KEY, or api_key_env in your profile)."
+	case errors.Is(err, agent.ErrQuotaExceeded):
+		return "The account is out of quota. Check your plan and billing with the provider."
+	case errors.Is(err, agent.ErrRateLimited):
+		if apiErr != nil && apiErr.RetryAfter > 0 {
+			return fmt.Sprintf("Rate limited by the provider. Try again in %s.", apiErr.RetryAfter.Round(time.Second))
+		}
+		return "Rate limited by the provider. Wait a moment and try again."
+	case errors.Is(err, agent.ErrContextTooLong):
+		return "The conversation is too long for the model. Shorten it with :chat trim <n> or :chat reset."
+	case errors.Is(err, agent.ErrUnavailable):
+		return "The provider is unavailable right now. Try again later, or switch with :model."
+	case errors.Is(err, ErrBudgetExceeded):
+		return "See :usage for details, or raise ai.usage.budget in ~/.binks.yaml."
+	}
+	return ""
+}
+
+// printAIError reports a failed AI request, with a hint when one applies.
+func printAIError(w io.Writer, err error) {
+	aiColor.Fprintf(w, "[AI] error: %s\n", err.Error())
+	if hint := aiErrorHint(err); hint != "" {
+		aiColor.Fprintf(w, "[AI] %s\n", hint)
+	}
+}
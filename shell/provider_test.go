@@ -0,0 +1,147 @@
+package shell
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/binks-cli/binks/internal/agent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAgent_Selection(t *testing.T) {
+	cases := []struct {
+		name     string
+		env      map[string]string
+		provider string
+		want     agent.Agent
+	}{
+		{"no keys", nil, "", &agent.DummyAgent{}},
+		{"openai key", map[string]string{"OPENAI_API_KEY": "k"}, "", &agent.OpenAIAgent{}},
+		{"anthropic key", map[string]string{"ANTHROPIC_API_KEY": "k"}, "", &agent.AnthropicAgent{}},
+		{"both keys prefer openai", map[string]string{"OPENAI_API_KEY": "k", "ANTHROPIC_API_KEY": "k"}, "", &agent.OpenAIAgent{}},
+		{"config provider", map[string]string{"OPENAI_API_KEY": "k"}, "anthropic", &agent.AnthropicAgent{}},
+		{"env overrides config", map[string]string{"BINKS_AI_PROVIDER": "OpenAI"}, "anthropic", &agent.OpenAIAgent{}},
+		{"dummy", map[string]string{"OPENAI_API_KEY": "k"}, "dummy", &agent.DummyAgent{}},
+		{"ollama", map[string]string{"OPENAI_API_KEY": "k"}, "ollama", &agent.OllamaAgent{}},
+		{"llama.cpp", map[string]string{"BINKS_AI_PROVIDER": "llama.cpp"}, "", &agent.LlamaCppAgent{}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, k := range []string{"BINKS_AI_PROVIDER", "OPENAI_API_KEY", "ANTHROPIC_API_KEY"} {
+				t.Setenv(k, tc.env[k])
+			}
+			ag, err := newAgent(AIConfig{Provider: tc.provider})
+			require.NoError(t, err)
+			assert.IsType(t, tc.want, ag)
+		})
+	}
+}
+
+func TestNewAgent_UnknownProvider(t *testing.T) {
+	t.Setenv("BINKS_AI_PROVIDER", "")
+	ag, err := newAgent(AIConfig{Provider: "skynet"})
+	assert.EqualError(t, err, `unknown AI provider "skynet" (want openai, anthropic, ollama, llamacpp or dummy)`)
+	assert.IsType(t, &agent.DummyAgent{}, ag)
+}
+
+func TestMetaCommand_Models(t *testing.T) {
+	var out strings.Builder
+	sess := &Session{Agent: &agent.DummyAgent{}}
+	assert.EqualError(t, runMetaCommand(context.Background(), ":models", sess, &out), "the current AI provider cannot list models")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/tags", r.URL.Path)
+		_, _ = io.WriteString(w, `{"models":[{"name":"llama3.2"},{"name":"qwen2.5-coder:7b"}]}`)
+	}))
+	defer srv.Close()
+	sess.Agent = &agent.OllamaAgent{Model: "qwen2.5-coder:7b", BaseURL: srv.URL, Client: srv.Client()}
+	assert.NoError(t, runMetaCommand(context.Background(), ":models", sess, &out))
+	assert.Equal(t, "  llama3.2\n* qwen2.5-coder:7b\n", out.String())
+}
+
+func TestProcessREPLLine_OllamaSuggestion(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"message":{"content":"Run:\n"},"done":false}`+"\n"+
+			`{"message":{"content":"`+"```sh\\ndu -sh .\\n```"+`"},"done":true}`+"\n")
+	}))
+	defer srv.Close()
+	sess := &Session{cwd: ".", Executor: &mockExecutor{}, Agent: &agent.OllamaAgent{Model: "m", BaseURL: srv.URL, Client: srv.Client()}}
+	var out, errOut strings.Builder
+
+	processREPLLine(">> how big is this dir", sess, &out, &errOut)
+	assert.Empty(t, errOut.String())
+	assert.Contains(t, out.String(), "AI suggests: du -sh .\nExecute this? [y/N]:")
+	require.NotNil(t, sess.pendingSuggestion)
+	assert.Equal(t, "du -sh .", sess.pendingSuggestion.command)
+}
+
+func TestMetaCommand_ModelSwitchesProfile(t *testing.T) {
+	t.Setenv("BINKS_AI_PROFILE", "")
+	sess := &Session{cwd: "."}
+	require.NoError(t, sess.selectAgent(AIConfig{
+		Profile: "local",
+		Profiles: []agent.Profile{
+			{Name: "local", Type: "ollama", Model: "llama3.2"},
+			{Name: "offline", Type: "dummy"},
+		},
+	}))
+	assert.IsType(t, &agent.OllamaAgent{}, sess.Agent)
+
+	var out strings.Builder
+	require.NoError(t, runMetaCommand(context.Background(), ":model", sess, &out))
+	assert.Equal(t, "* local    ollama llama3.2\n  offline  dummy\n", out.String())
+
+	out.Reset()
+	require.NoError(t, runMetaCommand(context.Background(), ":model offline", sess, &out))
+	assert.Equal(t, "[AI] Switched to offline (dummy).\n", out.String())
+	assert.IsType(t, &agent.DummyAgent{}, sess.Agent)
+
+	assert.EqualError(t, runMetaCommand(context.Background(), ":model nope", sess, &out), `unknown profile "nope"`)
+	assert.IsType(t, &agent.DummyAgent{}, sess.Agent, "a failed switch keeps the current agent")
+	assert.EqualError(t, runMetaCommand(context.Background(), ":model a b", sess, &out), "usage: :model [profile]")
+}
+
+func TestSelectAgent_BadProfileFallsBack(t *testing.T) {
+	t.Setenv("BINKS_AI_PROFILE", "missing")
+	t.Setenv("BINKS_AI_PROVIDER", "")
+	t.Setenv("OPENAI_API_KEY", "")
+	t.Setenv("ANTHROPIC_API_KEY", "")
+	sess := &Session{}
+	assert.EqualError(t, sess.selectAgent(AIConfig{}), `unknown profile "missing"`)
+	assert.IsType(t, &agent.DummyAgent{}, sess.Agent)
+
+	var out strings.Builder
+	require.NoError(t, runMetaCommand(context.Background(), ":model", sess, &out))
+	assert.Equal(t, "[AI] Using dummy. Add profiles under ai.profiles in ~/.binks.yaml to switch.\n", out.String())
+}
+
+func TestProcessREPLLine_AIErrorHints(t *testing.T) {
+	cases := []struct {
+		err  error
+		hint string
+	}{
+		{&agent.APIError{Provider: "OpenAI", Message: "bad key", Kind: agent.ErrAuthFailed}, "The API key was rejected"},
+		{&agent.APIError{Provider: "OpenAI", Message: "slow", Kind: agent.ErrRateLimited, RetryAfter: 90 * time.Second}, "Try again in 1m30s."},
+		{&agent.APIError{Provider: "OpenAI", Message: "long", Kind: agent.ErrContextTooLong}, ":chat trim <n> or :chat reset"},
+		{&agent.APIError{Provider: "Anthropic", Message: "Overloaded", Kind: agent.ErrUnavailable}, "switch with :model"},
+	}
+	for _, tc := range cases {
+		sess := &Session{cwd: ".", Agent: agent.AgentFunc(func([]agent.Message) (string, error) { return "", tc.err })}
+		var out, errOut strings.Builder
+		processREPLLine(">> hi", sess, &out, &errOut)
+		assert.Contains(t, errOut.String(), "[AI] error: "+tc.err.Error()+"\n")
+		assert.Contains(t, errOut.String(), tc.hint)
+	}
+
+	sess := &Session{cwd: ".", Agent: agent.AgentFunc(func([]agent.Message) (string, error) { return "", errors.New("AI error: boom") })}
+	var out, errOut strings.Builder
+	processREPLLine(">> hi", sess, &out, &errOut)
+	assert.Equal(t, "[AI] error: AI error: boom\n", errOut.String())
+}
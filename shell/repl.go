@@ -2,6 +2,7 @@ package shell
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -24,7 +25,7 @@ func RunREPL(sess *Session) error {
 		}
 		historyFile := filepath.Join(homeDir, ".binks_history")
 		config := &readline.Config{
-			Prompt:          promptWithAI(sess.Cwd(), sess.AIEnabled),
+			Prompt:          sess.prompt(),
 			HistoryLimit:    100,
 			InterruptPrompt: "^C\n",
 			EOFPrompt:       "exit\n",
@@ -36,6 +37,13 @@ func RunREPL(sess *Session) error {
 		if err != nil {
 			return err
 		}
+		// Ctrl+C at the prompt is handled by readline; while a line runs it
+		// cancels that line only.
+		sess.interrupts = newInterrupter()
+		defer func() {
+			sess.interrupts.stop()
+			sess.interrupts = nil
+		}()
 		return runREPLInteractive(sess, rl, os.Stdout, os.Stderr)
 	}
 	// Non-TTY: fallback to bufio.Scanner for integration tests and piping
@@ -47,15 +55,16 @@ func RunREPL(sess *Session) error {
 func RunREPLNonInteractive(sess *Session, in io.Reader, out, errOut io.Writer) error {
 	scanner := bufio.NewScanner(in)
 	// Print initial prompt
-	fmt.Fprint(out, promptWithAI(sess.Cwd(), sess.AIEnabled))
+	fmt.Fprint(out, sess.prompt())
 	if f, ok := out.(interface{ Sync() error }); ok {
 		_ = f.Sync()
 	}
 	for scanner.Scan() {
 		line := scanner.Text()
 		exit := processREPLLine(line, sess, out, errOut)
+		sess.reportJobs(out)
 		// Print prompt after each command (to match interactive mode)
-		fmt.Fprint(out, promptWithAI(sess.Cwd(), sess.AIEnabled))
+		fmt.Fprint(out, sess.prompt())
 		if f, ok := out.(interface{ Sync() error }); ok {
 			_ = f.Sync()
 		}
@@ -81,6 +90,20 @@ type LineReader interface {
 // This enables dependency injection for readline and testability.
 func runREPLInteractive(sess *Session, rl LineReader, out, errOut io.Writer) error {
 	defer rl.Close()
+	if d, ok := rl.(interface {
+		ReadlineWithDefault(string) (string, error)
+	}); ok {
+		sess.editLine = func(prompt, text string) (string, error) {
+			rl.SetPrompt(prompt)
+			defer rl.SetPrompt(sess.prompt())
+			line, err := d.ReadlineWithDefault(text)
+			if err != nil {
+				return "", nil // an abandoned edit leaves nothing to run
+			}
+			return line, nil
+		}
+		defer func() { sess.editLine = nil }()
+	}
 	for {
 		line, err := rl.Readline()
 		if err != nil {
@@ -95,11 +118,12 @@ func runREPLInteractive(sess *Session, rl LineReader, out, errOut io.Writer) err
 			return err
 		}
 		exit := processREPLLine(line, sess, out, errOut)
+		sess.reportJobs(out)
 		if strings.HasPrefix(line, "cd") || line == "help" || line == "?" {
-			rl.SetPrompt(promptWithAI(sess.Cwd(), sess.AIEnabled))
+			rl.SetPrompt(sess.prompt())
 			continue
 		}
-		rl.SetPrompt(promptWithAI(sess.Cwd(), sess.AIEnabled))
+		rl.SetPrompt(sess.prompt())
 		if exit {
 			break
 		}
@@ -111,10 +135,15 @@ var aiColor = color.New(color.FgCyan, color.Bold)
 
 // processREPLLine handles a single REPL input line and returns whether to exit the loop.
 func processREPLLine(line string, sess *Session, out, errOut io.Writer) (exit bool) {
+	ctx, done := sess.interrupts.context()
+	defer done()
 	line = strings.TrimSpace(line)
 	if isExit(line) {
 		return true
 	}
+	if sess.diagnosisOffered && answerDiagnosisOffer(ctx, line, sess, out, errOut) {
+		return false
+	}
 	if strings.HasPrefix(line, "cd") {
 		fields := strings.Fields(line)
 		var cdArg string
@@ -133,19 +162,54 @@ func processREPLLine(line string, sess *Session, out, errOut io.Writer) (exit bo
 		printHelp(out)
 		return false
 	}
+	if sess.pendingSuggestion != nil && sess.pendingSuggestion.toolCall != nil {
+		// Answers to tool calls continue the agent loop
+		runAIExchange(ctx, line, sess, out, errOut)
+		return false
+	}
+	if p := sess.pendingSuggestion; p != nil && p.isPlan() {
+		output, streamed, err := streamCommands(sess, out, errOut, func() (string, error) {
+			return sess.answerPlan(ctx, line)
+		})
+		if err != nil {
+			aiColor.Fprintf(errOut, "[AI] error: %s\n", err.Error())
+		} else if output != "" && !p.declined && !streamed {
+			aiColor.Fprintf(out, "%s\n", output)
+		}
+		if sess.pendingSuggestion != nil {
+			printPlanPrompt(out, p)
+		} else {
+			aiColor.Fprintf(out, "%s\n", planSummary(p))
+		}
+		return false
+	}
 	if sess.pendingSuggestion != nil {
-		answer := strings.ToLower(strings.TrimSpace(line))
-		if answer == "y" || answer == "yes" {
-			sess.pendingSuggestion.confirmed = true
-			output, err := sess.RunCommand(sess.pendingSuggestion.command)
+		if answer := strings.ToLower(line); answer == "e" || answer == "edit" {
+			output, streamed, err := streamCommands(sess, out, errOut, func() (string, error) {
+				return sess.runEditedSuggestion(ctx)
+			})
+			if err != nil {
+				aiColor.Fprintf(errOut, "[AI] error: %s\n", err.Error())
+			} else if p := sess.pendingSuggestion; p != nil {
+				printEditedSuggestion(out, p)
+			} else if output != "" && !streamed {
+				aiColor.Fprintf(out, "%s\n", output)
+			}
+			return false
+		}
+		if p := sess.pendingSuggestion; p.risk.accepts(line) {
+			p.confirmed = true
 			sess.pendingSuggestion = nil
+			output, streamed, err := streamCommands(sess, out, errOut, func() (string, error) {
+				return sess.runSuggestion(p.runContext(ctx), p, p.toRun())
+			})
 			if err != nil {
 				aiColor.Fprintf(errOut, "[AI] error: %s\n", err.Error())
-			} else if output != "" {
+			} else if output != "" && !streamed {
 				aiColor.Fprintf(out, "%s\n", output)
 			}
 		} else {
-			sess.pendingSuggestion.declined = true
+			sess.declineSuggestion(sess.pendingSuggestion)
 			aiColor.Fprintf(out, "[AI] Cancelled.\n")
 			sess.pendingSuggestion = nil
 		}
@@ -154,47 +218,153 @@ func processREPLLine(line string, sess *Session, out, errOut io.Writer) (exit bo
 	if line == "" {
 		return false
 	}
+	if isMetaCommand(line) {
+		if err := runMetaCommand(ctx, line, sess, out); err != nil {
+			fmt.Fprint(errOut, ErrorMessage(err))
+		}
+		return false
+	}
+	if isJobBuiltin(line) {
+		if err := runJobBuiltin(ctx, line, sess, out); err != nil {
+			fmt.Fprint(errOut, ErrorMessage(err))
+		}
+		return false
+	}
 	// Only reach here if no pending suggestion
-	if sess.AIEnabled && sess.Agent != nil {
-		if strings.HasPrefix(line, "!") {
+	if sess.Agent != nil && (sess.AIEnabled || agent.IsAIQuery(line)) {
+		if sess.AIEnabled && strings.HasPrefix(line, "!") {
 			// Force shell command
-			output, err := sess.RunCommand(strings.TrimSpace(line[1:]))
-			if err != nil {
-				fmt.Fprint(errOut, ErrorMessage(err))
-			} else if output != "" {
-				fmt.Fprint(out, output)
-				if !strings.HasSuffix(output, "\n") {
-					fmt.Fprint(out, "\n")
-				}
-			}
+			runShellLine(ctx, strings.TrimSpace(line[1:]), sess, out, errOut)
 			return false
 		}
-		resp, err := sess.ExecuteLine(agent.AIPrefix + line)
-		if err != nil {
-			aiColor.Fprintf(errOut, "[AI] error: %s\n", err.Error())
-			sess.pendingSuggestion = nil
-		} else if resp == "[AI]" && sess.pendingSuggestion != nil {
-			// Show explanation and command, prompt for confirmation
-			if sess.pendingSuggestion.explanation != "" {
-				fmt.Fprintf(out, "[AI] %s\n", sess.pendingSuggestion.explanation)
-			}
-			fmt.Fprintf(out, "AI suggests: %s\n", sess.pendingSuggestion.command)
-			fmt.Fprintf(out, "Execute this? [y/N]: ")
-		} else {
-			fmt.Fprintf(out, "%s\n", resp[5:])
+		query := line
+		if !agent.IsAIQuery(query) {
+			query = agent.AIPrefix + line
 		}
+		runAIExchange(ctx, query, sess, out, errOut)
 		return false
 	}
-	output, err := sess.RunCommand(line)
-	if err != nil {
-		fmt.Fprint(errOut, ErrorMessage(err))
-	} else if output != "" {
+	runShellLine(ctx, line, sess, out, errOut)
+	return false
+}
+
+// runShellLine runs a line as a shell command, or starts it as a job when it
+// ends in '&'.
+func runShellLine(ctx context.Context, line string, sess *Session, out, errOut io.Writer) {
+	if cmd, ok := backgroundCommand(line); ok {
+		j, err := sess.startJob(cmd)
+		if err != nil {
+			fmt.Fprint(errOut, ErrorMessage(err))
+			return
+		}
+		fmt.Fprintf(out, "[%d] %d\n", j.id, j.Pid())
+		return
+	}
+	output, streamed, err := streamCommands(sess, out, errOut, func() (string, error) {
+		return sess.RunCommandContext(ctx, line)
+	})
+	printCommandResult(sess, output, streamed, err, out, errOut)
+}
+
+// streamCommands calls run with the output of the command it runs written to
+// out and errOut as it arrives, and reports whether it was. A streamed output
+// has been shown already; a newline is added if it did not end with one.
+func streamCommands(sess *Session, out, errOut io.Writer, run func() (string, error)) (string, bool, error) {
+	w := &lineEndWriter{w: out, atLineStart: true}
+	sess.cmdOut, sess.cmdErr, sess.cmdStreamed = w, errOut, false
+	output, err := run()
+	streamed := sess.cmdStreamed
+	sess.cmdOut, sess.cmdErr, sess.cmdStreamed = nil, nil, false
+	if !w.atLineStart {
+		fmt.Fprint(out, "\n")
+	}
+	return output, streamed, err
+}
+
+// lineEndWriter passes writes on to w and notes whether the last one ended a line.
+type lineEndWriter struct {
+	w           io.Writer
+	atLineStart bool
+}
+
+func (l *lineEndWriter) Write(p []byte) (int, error) {
+	if len(p) > 0 {
+		l.atLineStart = p[len(p)-1] == '\n'
+	}
+	return l.w.Write(p)
+}
+
+// printCommandResult shows a command's output, including the output of a
+// failed command, unless it was streamed, followed by any error.
+func printCommandResult(sess *Session, output string, streamed bool, err error, out, errOut io.Writer) {
+	if output != "" && !streamed {
 		fmt.Fprint(out, output)
 		if !strings.HasSuffix(output, "\n") {
 			fmt.Fprint(out, "\n")
 		}
 	}
-	return false
+	if err != nil {
+		if sess.last != nil {
+			err = sess.last.failure() // the command just run
+		}
+		fmt.Fprint(errOut, ErrorMessage(err))
+		offerDiagnosis(sess, out)
+	}
+}
+
+// runAIExchange sends an AI query (or an answer to a pending tool call) through
+// the session, streaming partial output and prompting for confirmation when the
+// agent suggests a command.
+func runAIExchange(ctx context.Context, input string, sess *Session, out, errOut io.Writer) {
+	stream := &aiStreamWriter{w: out}
+	sess.streamOut = stream
+	resp, err := sess.ExecuteLineContext(ctx, input)
+	sess.streamOut = nil
+	stream.finish()
+	if err != nil {
+		printAIError(errOut, err)
+		sess.pendingSuggestion = nil
+	} else if resp == "[AI]" && sess.pendingSuggestion != nil {
+		// Show explanation and command, prompt for confirmation.
+		// A streamed response has already shown the explanation.
+		if sess.pendingSuggestion.explanation != "" && !sess.streamed {
+			fmt.Fprintf(out, "[AI] %s\n", sess.pendingSuggestion.explanation)
+		}
+		if sess.pendingSuggestion.isPlan() {
+			printPlan(out, sess.pendingSuggestion)
+			printPlanPrompt(out, sess.pendingSuggestion)
+			return
+		}
+		risk := sess.pendingSuggestion.risk
+		fmt.Fprintf(out, "AI suggests: %s%s\n", sess.pendingSuggestion.command, risk.note())
+		fmt.Fprint(out, risk.confirmPrompt())
+	} else if !sess.streamed {
+		fmt.Fprintf(out, "%s\n", resp[5:])
+	}
+}
+
+// aiStreamWriter forwards live AI output to the terminal and remembers how it
+// ended, so the next message starts on a fresh line.
+type aiStreamWriter struct {
+	w     io.Writer
+	wrote bool
+	last  byte
+}
+
+func (s *aiStreamWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	s.wrote = true
+	s.last = p[len(p)-1]
+	return s.w.Write(p)
+}
+
+// finish terminates a streamed response with a newline if it did not end with one.
+func (s *aiStreamWriter) finish() {
+	if s.wrote && s.last != '\n' {
+		fmt.Fprint(s.w, "\n")
+	}
 }
 
 // isExit checks if the command is a built-in exit command (case-insensitive)
@@ -213,15 +383,24 @@ func isExit(line string) bool {
 // printHelp prints the built-in help message to the given writer
 func printHelp(w io.Writer) {
 	help := `Built-in commands:
-  cd <dir>    – Change directory
-  exit        – Exit the shell
-  help, ?     – Show this help message
+  cd <dir>        – Change directory
+  <cmd> &         – Run a command in the background as a job
+  jobs            – List background jobs
+  fg [%n]         – Bring a job to the foreground (Ctrl+Z stops it again)
+  bg [%n]         – Continue a stopped job in the background
+  kill [-SIG] %n  – Send a job a signal (default SIGTERM)
+  exit            – Exit the shell
+  help, ?         – Show this help message
 
-AI queries: Start your input with '>>' to ask the AI agent (e.g., '>> how do I list files?').
-All other input is executed as shell commands in your shell environment.`
+Meta commands:`
 	if _, err := fmt.Fprintln(w, help); err != nil {
 		fmt.Fprintln(os.Stderr, "failed to print help:", err)
 	}
+	printMetaHelp(w)
+	fmt.Fprintln(w, `
+AI queries: Start your input with '>>' to ask the AI agent (e.g., '>> how do I list files?').
+Answer 'e' when asked to execute a suggestion to edit the command before it runs.
+All other input is executed as shell commands in your shell environment.`)
 }
 
 // promptWithAI returns the shell prompt string, with [AI] marker if AI mode is enabled.
@@ -1,6 +1,7 @@
 package shell
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -13,6 +14,7 @@ import (
 	"github.com/binks-cli/binks/internal/executor"
 	"github.com/fatih/color"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
 
@@ -109,22 +111,22 @@ func TestSession_NewSession(t *testing.T) {
 
 func TestRunREPL_MockExecutor(t *testing.T) {
 	// Test with testify/mock executor for controlled testing
-	mock := &executor.MockExecutorTestify{}
-	mock.On("RunCommand", "echo hi").Return("hi\n", nil)
-	mock.On("RunCommand", "failing-cmd").Return("", errors.New("command failed"))
+	mockExec := &executor.MockExecutorTestify{}
+	mockExec.On("Run", mock.Anything, "echo hi", mock.Anything).Return(&executor.CommandResult{Stdout: "hi\n", Combined: "hi\n"}, nil)
+	mockExec.On("Run", mock.Anything, "failing-cmd", mock.Anything).Return(&executor.CommandResult{}, errors.New("command failed"))
 
-	sess := &Session{Executor: mock}
+	sess := &Session{Executor: mockExec}
 
 	// Test that the session can use the mock executor
-	output, err := sess.Executor.RunCommand("echo hi")
+	output, err := sess.Executor.RunCommand(context.Background(), "echo hi")
 	require.NoError(t, err, "Expected no error")
 	assert.Equal(t, "hi", strings.TrimSpace(output), "Expected 'hi'")
 
 	// Test error case
-	_, err = sess.Executor.RunCommand("failing-cmd")
+	_, err = sess.Executor.RunCommand(context.Background(), "failing-cmd")
 	assert.Error(t, err, "Expected error for failing command")
 
-	mock.AssertExpectations(t)
+	mockExec.AssertExpectations(t)
 }
 
 func TestRunREPL_BlankLinePrompt(t *testing.T) {
@@ -370,18 +372,18 @@ func TestProcessREPLLine_BuiltinsAndExternal(t *testing.T) {
 	assert.Contains(t, out.String(), "Built-in commands:")
 
 	// Test external command (mock)
-	mock := &executor.MockExecutorTestify{}
-	mock.On("RunCommand", "echo hi").Return("hi\n", nil)
-	sess.Executor = mock
+	mockExec := &executor.MockExecutorTestify{}
+	mockExec.On("Run", mock.Anything, "echo hi", mock.Anything).Return(&executor.CommandResult{Stdout: "hi\n", Combined: "hi\n"}, nil)
+	sess.Executor = mockExec
 	out.Reset()
 	errOut.Reset()
 	exit = processREPLLine("echo hi", sess, &out, &errOut)
 	assert.False(t, exit)
 	assert.Contains(t, out.String(), "hi")
-	mock.AssertExpectations(t)
+	mockExec.AssertExpectations(t)
 
 	// Test external command error
-	mock.On("RunCommand", "fail").Return("", errors.New("fail"))
+	mockExec.On("Run", mock.Anything, "fail", mock.Anything).Return(&executor.CommandResult{}, errors.New("fail"))
 	out.Reset()
 	errOut.Reset()
 	exit = processREPLLine("fail", sess, &out, &errOut)
@@ -403,6 +405,17 @@ func TestRunREPLNonInteractive_Basic(t *testing.T) {
 	assert.Empty(t, errOut.String())
 }
 
+func TestRunREPLNonInteractive_AIQueryReachesConfiguredAgent(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("BINKS_AI_PROVIDER", "dummy")
+	sess := NewSession()
+	var out, errOut strings.Builder
+	err := RunREPLNonInteractive(sess, strings.NewReader(">> hello\nexit\n"), &out, &errOut)
+	require.NoError(t, err)
+	assert.Contains(t, out.String(), "Echo: hello")
+	assert.Empty(t, errOut.String(), "the query is not run as a shell redirect")
+}
+
 // mockLineReader implements LineReader for testing runREPLInteractive
 // It returns lines from the provided slice, then io.EOF
 // SetPrompt and Close are no-ops
@@ -587,3 +600,27 @@ func TestAIErrorOutput_REPL(t *testing.T) {
 	assert.Contains(t, errOut.String(), "AI request timed out")
 	assert.Nil(t, sess.pendingSuggestion)
 }
+
+func TestProcessREPLLine_StreamsCommandOutput(t *testing.T) {
+	var out, errOut strings.Builder
+	sess := &Session{
+		cwd:      t.TempDir(),
+		Executor: &executor.BashExecutor{NoTTY: true},
+		Agent: agentFuncMock(func(string) (string, error) {
+			return "```sh\necho suggested\n```", nil
+		}),
+	}
+
+	processREPLLine("printf partial; echo err >&2", sess, &out, &errOut)
+	assert.Equal(t, "partial\n", out.String(), "streamed once, with the final newline added")
+	assert.Equal(t, "err\n", errOut.String())
+	require.NotNil(t, sess.last)
+	assert.Equal(t, "partial", sess.last.result.Stdout)
+	assert.Equal(t, "err\n", sess.last.result.Stderr)
+	assert.Nil(t, sess.cmdOut, "only the line's own commands stream")
+
+	out.Reset()
+	processREPLLine(">> say something", sess, &out, &errOut)
+	processREPLLine("y", sess, &out, &errOut)
+	assert.True(t, strings.HasSuffix(out.String(), "Execute this? [y/N]: suggested\n"), out.String())
+}
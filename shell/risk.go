@@ -0,0 +1,638 @@
+package shell
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// riskLevel grades how much damage a suggested command could do.
+type riskLevel int
+
+const (
+	riskLow riskLevel = iota
+	riskMedium
+	riskHigh
+)
+
+func (l riskLevel) String() string {
+	switch l {
+	case riskMedium:
+		return "medium"
+	case riskHigh:
+		return "high"
+	}
+	return "low"
+}
+
+// commandRisk is the outcome of assessRisk: a level and why it was given.
+type commandRisk struct {
+	level   riskLevel
+	reasons []string
+	name    string // the program that made the command high risk, which may be typed to confirm it
+}
+
+func (r *commandRisk) add(level riskLevel, reason string) {
+	if level > r.level {
+		r.level = level
+	}
+	for _, have := range r.reasons {
+		if have == reason {
+			return
+		}
+	}
+	r.reasons = append(r.reasons, reason)
+}
+
+// merge adds the risk of a command that another runs, such as one in $(…) or
+// given to sh -c. Its name is kept if it is the one that makes this high.
+func (r *commandRisk) merge(o commandRisk) {
+	if o.level == riskHigh && r.level < riskHigh {
+		r.name = o.name
+	}
+	for _, reason := range o.reasons {
+		r.add(riskLow, reason)
+	}
+	if o.level > r.level {
+		r.level = o.level
+	}
+}
+
+// note returns the risk to show after a suggested command, or "" for a
+// low-risk one.
+func (r commandRisk) note() string {
+	if r.level == riskLow {
+		return ""
+	}
+	return fmt.Sprintf("  [%s risk: %s]", r.level, strings.Join(r.reasons, "; "))
+}
+
+// accepts reports whether answer confirms running a command of this risk: y or
+// yes normally, but only the word "yes" or the command's name when it is high.
+func (r commandRisk) accepts(answer string) bool {
+	answer = strings.TrimSpace(answer)
+	if r.level == riskHigh {
+		return strings.EqualFold(answer, "yes") || (r.name != "" && answer == r.name)
+	}
+	answer = strings.ToLower(answer)
+	return answer == "y" || answer == "yes"
+}
+
+// confirmPrompt asks to run a command of this risk.
+func (r commandRisk) confirmPrompt() string {
+	if r.level == riskHigh {
+		return fmt.Sprintf("Type %s to run it: ", r.highAnswers())
+	}
+	return "Execute this? [y/N]: "
+}
+
+// highAnswers names what confirms a high-risk command: "yes or <name>", or
+// only "yes" when no program made it high risk.
+func (r commandRisk) highAnswers() string {
+	if r.name == "" {
+		return "yes"
+	}
+	return "yes or " + r.name
+}
+
+// commandSegment is one simple command of a command line.
+type commandSegment struct {
+	words     []string
+	redirects []string // files written by > and >>
+	appends   []bool   // whether the redirect at the same index is >>
+	piped     bool     // stdin comes from the previous segment through |
+}
+
+// shellToken is a word or an operator of a command line.
+type shellToken struct {
+	text string
+	op   bool
+}
+
+// shellTokens splits a command line into words and operators, honouring
+// quotes and backslash escapes.
+func shellTokens(cmd string) []shellToken {
+	var tokens []shellToken
+	var word strings.Builder
+	inWord := false
+	flush := func() {
+		if inWord {
+			tokens = append(tokens, shellToken{text: word.String()})
+			word.Reset()
+			inWord = false
+		}
+	}
+	for i := 0; i < len(cmd); i++ {
+		c := cmd[i]
+		switch {
+		case c == '\\' && i+1 < len(cmd):
+			i++
+			word.WriteByte(cmd[i])
+			inWord = true
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(cmd[i+1:], c)
+			if end < 0 {
+				end = len(cmd) - i - 1
+			}
+			word.WriteString(cmd[i+1 : i+1+end])
+			i += end + 1
+			inWord = true
+		case c == '`':
+			end := strings.IndexByte(cmd[i+1:], '`')
+			if end < 0 {
+				end = len(cmd) - i - 1
+			}
+			word.WriteString(cmd[i:min(i+2+end, len(cmd))])
+			i += end + 1
+			inWord = true
+		case (c == '$' || c == '<' || c == '>') && i+1 < len(cmd) && cmd[i+1] == '(':
+			// $(…), <(…) and >(…) stay whole; assessRisk looks inside them.
+			end := closingParen(cmd, i+1)
+			word.WriteString(cmd[i:min(end+1, len(cmd))])
+			i = end
+			inWord = true
+		case c == ' ' || c == '\t':
+			flush()
+		case c == '>':
+			// 2> and &> redirect other streams to the same kind of target.
+			if w := word.String(); inWord && (w == "1" || w == "2" || w == "&") {
+				word.Reset()
+				inWord = false
+			}
+			flush()
+			op := ">"
+			if i+1 < len(cmd) && cmd[i+1] == '>' {
+				op = ">>"
+				i++
+			}
+			tokens = append(tokens, shellToken{text: op, op: true})
+		case strings.IndexByte("|&;\n<()", c) >= 0:
+			flush()
+			op := string(c)
+			if i+1 < len(cmd) && (c == '|' || c == '&') && cmd[i+1] == c {
+				op += string(c)
+				i++
+			}
+			tokens = append(tokens, shellToken{text: op, op: true})
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	flush()
+	return tokens
+}
+
+// closingParen returns the index of the ')' that closes the '(' at open in
+// cmd, or len(cmd) if it is not closed.
+func closingParen(cmd string, open int) int {
+	depth := 0
+	for i := open; i < len(cmd); i++ {
+		switch cmd[i] {
+		case '\\':
+			i++
+		case '\'', '"':
+			end := strings.IndexByte(cmd[i+1:], cmd[i])
+			if end < 0 {
+				return len(cmd)
+			}
+			i += end + 1
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(cmd)
+}
+
+// substitutions returns the commands a command line runs to build its words:
+// those in $(…), backticks, <(…) and >(…), outside single quotes. Ones nested
+// inside them are left in the text returned.
+func substitutions(cmd string) []string {
+	var subs []string
+	inDouble := false
+	for i := 0; i < len(cmd); i++ {
+		c := cmd[i]
+		switch {
+		case c == '\\':
+			i++
+		case c == '"':
+			inDouble = !inDouble
+		case c == '\'' && !inDouble:
+			end := strings.IndexByte(cmd[i+1:], '\'')
+			if end < 0 {
+				return subs
+			}
+			i += end + 1
+		case c == '`':
+			end := strings.IndexByte(cmd[i+1:], '`')
+			if end < 0 {
+				end = len(cmd) - i - 1
+			}
+			subs = append(subs, cmd[i+1:i+1+end])
+			i += end + 1
+		case (c == '$' || c == '<' || c == '>') && i+1 < len(cmd) && cmd[i+1] == '(':
+			end := closingParen(cmd, i+1)
+			subs = append(subs, cmd[i+2:end])
+			i = end
+		}
+	}
+	return subs
+}
+
+// parseSegments splits a command line into its simple commands.
+func parseSegments(cmd string) []commandSegment {
+	var segs []commandSegment
+	cur := commandSegment{}
+	tokens := shellTokens(cmd)
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		if !t.op {
+			cur.words = append(cur.words, t.text)
+			continue
+		}
+		switch t.text {
+		case ">", ">>":
+			if i+1 < len(tokens) && !tokens[i+1].op {
+				i++
+				if !strings.HasPrefix(tokens[i].text, "&") { // 2>&1 duplicates a stream
+					cur.redirects = append(cur.redirects, tokens[i].text)
+					cur.appends = append(cur.appends, t.text == ">>")
+				}
+			}
+		case "<":
+			i++ // the input file is only read
+		case "(", ")":
+		default:
+			segs = append(segs, cur)
+			cur = commandSegment{piped: t.text == "|"}
+		}
+	}
+	return append(segs, cur)
+}
+
+// wrappers run the command that follows them and their own options. The
+// options listed take a value.
+var wrappers = map[string][]string{
+	"sudo":    {"-u", "-g", "-C", "-D", "-p", "-r", "-t", "-U"},
+	"doas":    {"-u", "-C"},
+	"env":     {"-u", "-C"},
+	"nohup":   nil,
+	"time":    {"-f", "-o"},
+	"command": nil,
+	"exec":    {"-a"},
+	"nice":    {"-n"},
+	"xargs":   {"-I", "-n", "-P", "-L", "-s", "-d", "-E", "-a"},
+}
+
+// program returns the command a segment runs and its arguments, skipping
+// wrappers such as sudo and xargs and leading variable assignments. elevated
+// is the privilege wrapper used, if any.
+func (seg commandSegment) program() (name string, args []string, elevated string) {
+	words := seg.words
+	for len(words) > 0 {
+		w := words[0]
+		valued, wrapper := wrappers[w]
+		switch {
+		case wrapper:
+			if w == "sudo" || w == "doas" {
+				elevated = w
+			}
+			words = words[1:]
+			for len(words) > 0 && strings.HasPrefix(words[0], "-") {
+				opt := words[0]
+				words = words[1:]
+				if opt == "--" {
+					break
+				}
+				if slices.Contains(valued, opt) && len(words) > 0 {
+					words = words[1:]
+				}
+			}
+		case strings.Contains(w, "=") && !strings.HasPrefix(w, "="):
+			words = words[1:]
+		default:
+			return filepath.Base(w), words[1:], elevated
+		}
+	}
+	return "", nil, elevated
+}
+
+// shellPrograms are interpreters that run whatever is piped into them.
+var shellPrograms = map[string]bool{"sh": true, "bash": true, "zsh": true, "dash": true, "fish": true, "ksh": true, "python": true, "python3": true, "perl": true, "ruby": true, "node": true}
+
+// diskPrograms can destroy a disk or file system outright.
+var diskPrograms = map[string]string{
+	"dd":       "dd can overwrite disks",
+	"mkfs":     "creates a file system",
+	"wipefs":   "wipes file system signatures",
+	"fdisk":    "edits partition tables",
+	"parted":   "edits partition tables",
+	"shred":    "destroys file contents",
+	"shutdown": "shuts down the machine",
+	"reboot":   "reboots the machine",
+	"poweroff": "shuts down the machine",
+	"halt":     "shuts down the machine",
+}
+
+// assessRisk grades a suggested command run from dir.
+func assessRisk(cmd, dir string) commandRisk {
+	var r commandRisk
+	for _, sub := range substitutions(cmd) {
+		r.merge(assessRisk(sub, dir))
+	}
+	segs := parseSegments(cmd)
+	downloading := false
+	for _, seg := range segs {
+		name, args, elevated := seg.program()
+		wasHigh := r.level == riskHigh
+		var nested []commandRisk // of the commands this one runs
+		if !seg.piped {
+			downloading = false
+		}
+		if elevated != "" {
+			r.add(riskHigh, "runs as root with "+elevated)
+		}
+		if seg.piped && downloading && shellPrograms[name] {
+			r.add(riskHigh, "pipes a download into "+name)
+		}
+		if shellPrograms[name] && runsDownload(args) {
+			r.add(riskHigh, "runs a download with "+name)
+		}
+		if name == "curl" || name == "wget" {
+			downloading = true
+		}
+		if reason, ok := diskPrograms[name]; ok {
+			r.add(riskHigh, reason)
+		} else if strings.HasPrefix(name, "mkfs.") {
+			r.add(riskHigh, diskPrograms["mkfs"])
+		}
+
+		var written []string
+		switch name {
+		case "rm", "rmdir":
+			if hasFlag(args, 'r', "--recursive") || hasFlag(args, 'R', "") {
+				r.add(riskHigh, "deletes files recursively")
+			} else {
+				r.add(riskMedium, "deletes files")
+			}
+			written = operands(args)
+		case "mv", "cp", "ln", "install":
+			if ops := operands(args); len(ops) > 1 {
+				written = ops[len(ops)-1:]
+				if name == "mv" {
+					written = ops
+				}
+			}
+		case "tee":
+			written = operands(args)
+			if len(written) > 0 {
+				r.add(riskMedium, "writes "+strings.Join(written, ", "))
+			}
+		case "truncate":
+			written = operands(args, "-s", "--size", "-r", "--reference")
+			if len(written) > 0 {
+				r.add(riskMedium, "truncates "+strings.Join(written, ", "))
+			}
+		case "chmod", "chown", "chgrp":
+			if !hasFlag(args, 'R', "--recursive") {
+				break
+			}
+			level, reason := riskMedium, "changes ownership or permissions recursively"
+			for _, op := range operands(args) {
+				if rootOrHome(op) {
+					level, reason = riskHigh, "changes ownership or permissions of everything under "+op
+				}
+			}
+			r.add(level, reason)
+		case "find":
+			nested = findRisk(&r, args, dir)
+		case "sh", "bash", "zsh", "dash", "ksh", "fish":
+			if script, ok := shellScript(args); ok {
+				nested = append(nested, assessRisk(script, dir))
+			}
+		case "kill", "killall", "pkill":
+			r.add(riskMedium, "stops processes")
+		case "git":
+			gitRisk(&r, args)
+		}
+		for j, target := range seg.redirects {
+			if harmlessTarget(target) {
+				continue
+			}
+			if seg.appends[j] {
+				r.add(riskMedium, "appends to "+target)
+			} else {
+				r.add(riskMedium, "overwrites "+target)
+			}
+			written = append(written, target)
+		}
+		for _, target := range written {
+			if outsideDir(target, dir) {
+				r.add(riskHigh, "writes outside the working directory ("+target+")")
+			}
+		}
+		if !wasHigh && r.level == riskHigh {
+			// Typing this program confirms the segment that is dangerous, not
+			// a harmless one before it such as cd.
+			r.name = name
+		}
+		for _, n := range nested {
+			r.merge(n)
+		}
+	}
+	return r
+}
+
+// runsDownload reports whether args hold a command substitution that
+// downloads, as in bash <(curl …) or sh -c "$(curl …)".
+func runsDownload(args []string) bool {
+	for _, a := range args {
+		for _, sub := range substitutions(a) {
+			for _, seg := range parseSegments(sub) {
+				if name, _, _ := seg.program(); name == "curl" || name == "wget" {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// shellScript returns the script a shell is given with -c, as in bash -c or
+// sh -ec, and whether there is one.
+func shellScript(args []string) (string, bool) {
+	for i, a := range args {
+		if !strings.HasPrefix(a, "-") || a == "--" {
+			return "", false
+		}
+		if !strings.HasPrefix(a, "--") && strings.IndexByte(a[1:], 'c') >= 0 && i+1 < len(args) {
+			return args[i+1], true
+		}
+	}
+	return "", false
+}
+
+// findRisk flags find deleting what it matches, and returns the risk of the
+// commands it runs with -exec and the like.
+func findRisk(r *commandRisk, args []string, dir string) []commandRisk {
+	var nested []commandRisk
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-delete":
+			r.add(riskHigh, "deletes the files find matches")
+		case "-exec", "-execdir", "-ok", "-okdir":
+			end := i + 1
+			for end < len(args) && args[end] != ";" && args[end] != "+" {
+				end++
+			}
+			run := commandSegment{words: args[i+1 : end]}
+			switch name, _, _ := run.program(); name {
+			case "rm", "rmdir", "unlink", "shred":
+				r.add(riskHigh, "deletes the files find matches")
+			}
+			nested = append(nested, assessRisk(strings.Join(run.words, " "), dir))
+			i = end
+		}
+	}
+	return nested
+}
+
+// rootOrHome reports whether path names the root directory or the home
+// directory, as in / or ~.
+func rootOrHome(path string) bool {
+	path = strings.TrimSuffix(path, "*")
+	switch path {
+	case "/", "~", "~/", "$HOME", "$HOME/", "${HOME}", "${HOME}/":
+		return true
+	}
+	home, err := os.UserHomeDir()
+	return err == nil && filepath.IsAbs(path) && filepath.Clean(path) == filepath.Clean(home)
+}
+
+// gitRisk flags git commands that rewrite history or throw work away.
+func gitRisk(r *commandRisk, args []string) {
+	// Global options, some with a value, come before the subcommand.
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		switch args[0] {
+		case "-C", "-c", "--git-dir", "--work-tree", "--namespace", "--config-env":
+			args = args[1:]
+		}
+		if len(args) > 0 {
+			args = args[1:]
+		}
+	}
+	if len(args) == 0 {
+		return
+	}
+	sub, args := args[0], args[1:]
+	switch sub {
+	case "push":
+		force := hasFlag(args, 'f', "--force")
+		for _, a := range args {
+			if strings.HasPrefix(a, "--force") || (strings.HasPrefix(a, "+") && len(a) > 1) {
+				force = true
+			}
+		}
+		if force {
+			r.add(riskHigh, "force-pushes with git")
+		}
+	case "reset":
+		if hasFlag(args, 0, "--hard") {
+			r.add(riskMedium, "discards uncommitted changes")
+		}
+	case "clean":
+		if hasFlag(args, 'f', "--force") {
+			r.add(riskMedium, "deletes untracked files")
+		}
+	}
+}
+
+// hasFlag reports whether args hold the short flag (alone or combined, as in
+// -rf) or the long flag.
+func hasFlag(args []string, short byte, long string) bool {
+	for _, a := range args {
+		if a == "--" {
+			return false
+		}
+		if long != "" && a == long {
+			return true
+		}
+		if short != 0 && len(a) > 1 && a[0] == '-' && a[1] != '-' && strings.IndexByte(a[1:], short) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// operands returns the arguments that are not flags, nor the values of the
+// flags listed in valued.
+func operands(args []string, valued ...string) []string {
+	var out []string
+	flags := true
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if flags && a == "--" {
+			flags = false
+			continue
+		}
+		if flags && strings.HasPrefix(a, "-") && a != "-" {
+			if slices.Contains(valued, a) {
+				i++
+			}
+			continue
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+// harmlessTarget reports whether writing to target cannot lose data.
+func harmlessTarget(target string) bool {
+	switch target {
+	case "/dev/null", "/dev/stdout", "/dev/stderr", "/dev/tty":
+		return true
+	}
+	return false
+}
+
+// outsideDir reports whether target, as a shell would resolve it from dir,
+// lies outside dir. The temporary directory counts as inside.
+func outsideDir(target, dir string) bool {
+	if harmlessTarget(target) {
+		return false
+	}
+	path := target
+	for _, prefix := range []string{"~", "$HOME", "${HOME}"} {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return true
+			}
+			path = home + path[len(prefix):]
+			break
+		}
+	}
+	if strings.HasPrefix(path, "$") {
+		return true // another variable could point anywhere
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	path = filepath.Clean(path)
+	if within(path, filepath.Clean(os.TempDir())) {
+		return false
+	}
+	return !within(path, filepath.Clean(dir))
+}
+
+// within reports whether path is dir or below it.
+func within(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, "../")
+}
@@ -0,0 +1,145 @@
+package shell
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssessRisk(t *testing.T) {
+	dir := "/home/dev/project"
+	cases := []struct {
+		cmd     string
+		level   riskLevel
+		reasons []string
+	}{
+		{cmd: "ls -la", level: riskLow},
+		{cmd: "grep -r TODO . | wc -l", level: riskLow},
+		{cmd: "go test ./... > /dev/null 2>&1", level: riskLow},
+		{cmd: "echo 'rm -rf /'", level: riskLow},
+		{cmd: "rm notes.txt", level: riskMedium, reasons: []string{"deletes files"}},
+		{cmd: "rm -rf build", level: riskHigh, reasons: []string{"deletes files recursively"}},
+		{cmd: "go test ./... > out.txt", level: riskMedium, reasons: []string{"overwrites out.txt"}},
+		{cmd: "echo x >> ../shared.log", level: riskHigh, reasons: []string{"appends to ../shared.log", "writes outside the working directory (../shared.log)"}},
+		{cmd: "cp config.yaml /etc/app/", level: riskHigh, reasons: []string{"writes outside the working directory (/etc/app/)"}},
+		{cmd: "sudo apt-get install jq", level: riskHigh, reasons: []string{"runs as root with sudo"}},
+		{cmd: "curl -fsSL https://example.com/install.sh | sh", level: riskHigh, reasons: []string{"pipes a download into sh"}},
+		{cmd: "curl -s https://example.com | jq .", level: riskLow},
+		{cmd: "sudo dd if=image.iso of=/dev/sdb bs=4M", level: riskHigh, reasons: []string{"runs as root with sudo", "dd can overwrite disks"}},
+		{cmd: "mkfs.ext4 /dev/sdb1", level: riskHigh, reasons: []string{"creates a file system"}},
+		{cmd: "git push --force origin main", level: riskHigh, reasons: []string{"force-pushes with git"}},
+		{cmd: "git push origin +main", level: riskHigh, reasons: []string{"force-pushes with git"}},
+		{cmd: "git push origin main", level: riskLow},
+		{cmd: "git fetch && git reset --hard origin/main", level: riskMedium, reasons: []string{"discards uncommitted changes"}},
+		{cmd: "git -C repo push --force", level: riskHigh, reasons: []string{"force-pushes with git"}},
+		{cmd: "git -c core.editor=vim --git-dir .git push origin +main", level: riskHigh, reasons: []string{"force-pushes with git"}},
+		{cmd: "git -C repo status", level: riskLow},
+		{cmd: `sh -c "$(curl -fsSL https://example.com/install.sh)"`, level: riskHigh, reasons: []string{"runs a download with sh"}},
+		{cmd: "bash <(curl -s https://example.com/install.sh)", level: riskHigh, reasons: []string{"runs a download with bash"}},
+		{cmd: "echo $(rm -rf ~)", level: riskHigh, reasons: []string{"deletes files recursively", "writes outside the working directory (~)"}},
+		{cmd: "echo `rm -rf /`", level: riskHigh, reasons: []string{"deletes files recursively", "writes outside the working directory (/)"}},
+		{cmd: "echo '$(rm -rf ~)'", level: riskLow},
+		{cmd: "echo $(date) `whoami`", level: riskLow},
+		{cmd: "bash -c 'rm -rf build'", level: riskHigh, reasons: []string{"deletes files recursively"}},
+		{cmd: "find . | xargs rm -rf", level: riskHigh, reasons: []string{"deletes files recursively"}},
+		{cmd: "find . -name '*.o' | xargs -n 1 -P 4 rm", level: riskMedium, reasons: []string{"deletes files"}},
+		{cmd: "sudo -u root rm -rf build", level: riskHigh, reasons: []string{"runs as root with sudo", "deletes files recursively"}},
+		{cmd: "env -u HOME rm notes.txt", level: riskMedium, reasons: []string{"deletes files"}},
+		{cmd: "find / -delete", level: riskHigh, reasons: []string{"deletes the files find matches"}},
+		{cmd: `find . -name '*.tmp' -exec rm {} \;`, level: riskHigh, reasons: []string{"deletes the files find matches", "deletes files"}},
+		{cmd: "find . -name '*.go' -exec grep -l TODO {} +", level: riskLow},
+		{cmd: "truncate -s 0 /etc/passwd", level: riskHigh, reasons: []string{"truncates /etc/passwd", "writes outside the working directory (/etc/passwd)"}},
+		{cmd: "truncate -s 0 app.log", level: riskMedium, reasons: []string{"truncates app.log"}},
+		{cmd: "chmod -R 777 /", level: riskHigh, reasons: []string{"changes ownership or permissions of everything under /"}},
+		{cmd: "chown -R dev ~", level: riskHigh, reasons: []string{"changes ownership or permissions of everything under ~"}},
+		{cmd: "chmod -R g+w src", level: riskMedium, reasons: []string{"changes ownership or permissions recursively"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.cmd, func(t *testing.T) {
+			r := assessRisk(tc.cmd, dir)
+			assert.Equal(t, tc.level, r.level)
+			assert.Equal(t, tc.reasons, r.reasons)
+		})
+	}
+}
+
+func TestCommandRisk_Accepts(t *testing.T) {
+	low := assessRisk("ls", ".")
+	assert.True(t, low.accepts("y"))
+	assert.True(t, low.accepts("YES"))
+	assert.False(t, low.accepts("ls"))
+
+	high := assessRisk("sudo rm -rf /var/cache/app", ".")
+	assert.Equal(t, "rm", high.name)
+	assert.False(t, high.accepts("y"))
+	assert.True(t, high.accepts("yes"))
+	assert.True(t, high.accepts(" rm "))
+	assert.Equal(t, "Type yes or rm to run it: ", high.confirmPrompt())
+
+	chained := assessRisk("cd build && rm -rf *", ".")
+	assert.Equal(t, "rm", chained.name, "the name is the program that made it high risk")
+	assert.False(t, chained.accepts("cd"))
+	assert.True(t, chained.accepts("rm"))
+
+	nested := assessRisk("echo $(rm -rf ~)", ".")
+	assert.Equal(t, "rm", nested.name, "the name is the program in the substitution")
+	wrapped := assessRisk("bash -c 'cd build && rm -rf *'", ".")
+	assert.Equal(t, "rm", wrapped.name)
+	piped := assessRisk("find . | xargs rm -rf", ".")
+	assert.Equal(t, "rm", piped.name)
+
+	nameless := assessRisk("FOO=1 > /etc/app.conf", "/home/dev")
+	assert.Equal(t, riskHigh, nameless.level)
+	assert.Empty(t, nameless.name)
+	assert.False(t, nameless.accepts(""))
+	assert.True(t, nameless.accepts("yes"))
+	assert.Equal(t, "Type yes to run it: ", nameless.confirmPrompt())
+
+	rated := (&Session{cwd: "."}).suggestionRisk("make deploy", "high")
+	assert.Equal(t, "Type yes to run it: ", rated.confirmPrompt(), "only yes when the AI alone rated it high")
+}
+
+func TestAIConfirmation_HighRisk_REPL(t *testing.T) {
+	exec := &mockExecutor{}
+	sess := &Session{cwd: ".", Executor: exec, Agent: agentFuncMock(func(string) (string, error) {
+		return "Clean up:\n```sh\nrm -rf build\n```", nil
+	})}
+	var out, errOut strings.Builder
+
+	processREPLLine(">> clean", sess, &out, &errOut)
+	assert.Contains(t, out.String(), "AI suggests: rm -rf build  [high risk: deletes files recursively]\n")
+	assert.Contains(t, out.String(), "Type yes or rm to run it: ")
+	processREPLLine("y", sess, &out, &errOut)
+	assert.Contains(t, out.String(), "[AI] Cancelled.")
+	assert.Zero(t, exec.calls, "y is not enough for a high-risk command")
+
+	processREPLLine(">> clean", sess, &out, &errOut)
+	processREPLLine("rm", sess, &out, &errOut)
+	assert.Equal(t, "rm -rf build", exec.lastCmd)
+}
+
+func TestPlan_HighRiskStep(t *testing.T) {
+	exec := &mockExecutor{}
+	sess := &Session{cwd: ".", Executor: exec, Agent: agentFuncMock(func(string) (string, error) {
+		return "```sh\ngit status\n```\n```sh\ngit push -f\n```", nil
+	})}
+	_, err := sess.ExecuteLine(">> push")
+	require.NoError(t, err)
+	_, err = sess.ExecuteLine("r")
+	require.NoError(t, err)
+
+	var out strings.Builder
+	printPlanPrompt(&out, sess.pendingSuggestion)
+	assert.Equal(t, "Step 2/2: git push -f  [high risk: force-pushes with git]\nType yes or git to run it, or [s]kip, [e]dit or [a]bort? ", out.String())
+	resp, err := sess.ExecuteLine("r")
+	require.NoError(t, err)
+	assert.Equal(t, "[AI] Step 2 is high risk: type yes or git to run it.", resp)
+	assert.Equal(t, "git status", exec.lastCmd)
+
+	_, err = sess.ExecuteLine("yes")
+	require.NoError(t, err)
+	assert.Equal(t, "git push -f", exec.lastCmd)
+	assert.Nil(t, sess.pendingSuggestion)
+}
@@ -1,25 +1,54 @@
 package shell
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/binks-cli/binks/internal/agent"
 	"github.com/binks-cli/binks/internal/executor"
+	"github.com/binks-cli/binks/internal/mcp"
 )
 
 // Session represents the state of a shell session
 // Add Out and Err for output capturing
 type Session struct {
 	Executor          executor.Executor
-	Agent             agent.Agent        // AI agent for handling AI queries
-	cwd               string             // Current working directory
-	AIEnabled         bool               // Global AI mode toggle
-	pendingSuggestion *PendingSuggestion // Holds a pending AI suggestion for confirmation
-	Out               io.Writer          // For stdout (default: os.Stdout)
-	Err               io.Writer          // For stderr (default: os.Stderr)
+	Agent             agent.Agent         // AI agent for handling AI queries
+	profiles          []agent.Profile     // Provider profiles from ~/.binks.yaml
+	profile           string              // Name of the active profile, if any
+	cwd               string              // Current working directory
+	AIEnabled         bool                // Global AI mode toggle
+	pendingSuggestion *PendingSuggestion  // Holds a pending AI suggestion for confirmation
+	query             string              // The AI query being answered, for the audit log
+	streamOut         io.Writer           // Receives live AI output (streamed text, tool results) during a query
+	streamed          bool                // Whether the latest agent reply was streamed to streamOut
+	transcript        agent.Transcript    // Conversation with the agent, sent with every AI query
+	recent            []CommandRecord     // Recently run commands, described to the agent
+	last              *lastCommand        // The latest command and its output, for :explain
+	jobs              []*job              // Commands running in the background, in the order started
+	cmdOut, cmdErr    io.Writer           // Receive command output as it arrives, when set by the REPL
+	cmdStreamed       bool                // Whether the latest command's output was written to cmdOut
+	OfferDiagnosis    bool                // Offer to have the agent explain commands that exit non-zero
+	diagnosisOffered  bool                // The next line answers the offer to explain a failure
+	Context           ContextOptions      // Parts of the environment described in the AI system prompt
+	Tools             *agent.ToolRegistry // Tools offered to agents that support function calling
+	toolLoop          *toolLoop           // In-progress tool-calling exchange, if any
+	mcpClients        []*mcp.Client       // Connected MCP servers whose tools are offered to the agent
+	Exec              ExecConfig          // Command timeouts
+	Usage             UsageConfig         // AI prices and budgets
+	spent             []usageRecord       // Token usage of the session's AI requests
+	usageFile         string              // Where usage is kept across sessions; "" keeps it in memory only
+	auditFile         string              // Where AI suggestions and decisions are logged; "" disables the log
+	interrupts        *interrupter        // Cancels the line in progress on Ctrl+C; nil outside the interactive REPL
+	editLine          lineEditor          // Edits a line in place, with text filled in; nil outside the interactive REPL
+	Out               io.Writer           // For stdout (default: os.Stdout)
+	Err               io.Writer           // For stderr (default: os.Stderr)
 }
 
 // NewSession creates a new shell session
@@ -28,20 +57,45 @@ func NewSession() *Session {
 	if err != nil {
 		wd = "." // fallback
 	}
-	var ag agent.Agent
-	if os.Getenv("OPENAI_API_KEY") != "" {
-		ag = agent.NewOpenAIAgent()
-	} else {
-		ag = &agent.DummyAgent{}
-	}
-	return &Session{
-		Executor:  executor.NewBashExecutor(),
-		Agent:     ag,
+	cfg := readBinksConfig()
+	sess := &Session{
 		cwd:       wd,
 		AIEnabled: false, // Default to off
+		Context:   contextOptions(cfg.AI.Context),
+		Exec:      cfg.Exec,
+		Usage:     cfg.AI.Usage,
+		usageFile: usagePath(),
+		auditFile: auditPath(),
 		Out:       os.Stdout,
 		Err:       os.Stderr,
+
+		OfferDiagnosis: cfg.AI.OfferDiagnosis,
+	}
+	if sess.Executor, err = NewExecutor(cfg.Exec, false); err != nil {
+		fmt.Fprintf(sess.Err, "binks: %v\n", err)
+	}
+	if r, err := agent.NewRedactor(cfg.AI.Redact.Patterns); err != nil {
+		fmt.Fprintf(sess.Err, "binks: %v\n", err)
+	} else {
+		agent.SetRedactor(r)
+	}
+	if err := sess.selectAgent(cfg.AI); err != nil {
+		fmt.Fprintf(sess.Err, "binks: %v\n", err)
 	}
+	if cfg.AI.Tools == nil || *cfg.AI.Tools {
+		sess.Tools = agent.BuiltinTools(agent.ToolEnv{RunCommand: sess.RunCommandContext, Cwd: sess.Cwd})
+	}
+	sess.connectMCP(cfg.MCP.Servers)
+	return sess
+}
+
+// errWriter returns where the session reports errors: Err, or standard error
+// when it is not set.
+func (s *Session) errWriter() io.Writer {
+	if s.Err != nil {
+		return s.Err
+	}
+	return os.Stderr
 }
 
 // Cwd returns the current working directory for the session
@@ -85,12 +139,75 @@ func (s *Session) ChangeDir(path string) error {
 	return nil
 }
 
+// syncDir follows a cd made by a command, for executors that keep a shell
+// running between commands.
+func (s *Session) syncDir() {
+	dt, ok := s.Executor.(executor.DirTracker)
+	if !ok {
+		return
+	}
+	if dir := dt.Dir(); dir != "" && dir != s.cwd && os.Chdir(dir) == nil {
+		s.cwd = dir
+	}
+}
+
 // RunCommand runs a command in the session's current working directory
 func (s *Session) RunCommand(cmd string) (string, error) {
-	if be, ok := s.Executor.(*executor.BashExecutor); ok {
-		return be.RunCommandWithDir(cmd, s.cwd)
+	return s.RunCommandContext(context.Background(), cmd)
+}
+
+// RunCommandContext runs a command like RunCommand, stopping it when ctx is
+// cancelled or the timeout configured for the command passes.
+func (s *Session) RunCommandContext(ctx context.Context, cmd string) (string, error) {
+	res, err := s.RunCommandResult(ctx, cmd)
+	return res.Combined, err
+}
+
+// RunCommandResult runs a command like RunCommandContext and reports its
+// result: each output stream, the exit code and any signal, and its timing.
+func (s *Session) RunCommandResult(ctx context.Context, cmd string) (*executor.CommandResult, error) {
+	timeout := s.Exec.timeoutFor(cmd)
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	res, err := s.run(ctx, cmd)
+	s.syncDir()
+	switch {
+	case errors.Is(err, context.Canceled):
+		err = errors.New("interrupted")
+	case errors.Is(err, context.DeadlineExceeded):
+		err = fmt.Errorf("command timed out after %s", timeout)
+	}
+	if res.Job != nil {
+		s.addJob(res.Job)
+	}
+	s.recordCommand(res)
+	s.last = &lastCommand{command: cmd, result: res, err: err}
+	return res, err
+}
+
+// run runs cmd through the executor in the session's directory, streaming its
+// output to cmdOut and cmdErr when they are set. For executors that only
+// return output, the result is made from the output and error.
+func (s *Session) run(ctx context.Context, cmd string) (*executor.CommandResult, error) {
+	s.cmdStreamed = false
+	if r, ok := s.Executor.(executor.Runner); ok {
+		s.cmdStreamed = s.cmdOut != nil
+		return r.Run(ctx, cmd, executor.RunOptions{Dir: s.cwd, Stdout: s.cmdOut, Stderr: s.cmdErr})
+	}
+	res := &executor.CommandResult{Command: cmd, Dir: s.cwd, Started: time.Now()}
+	var output string
+	var err error
+	if dr, ok := s.Executor.(executor.DirRunner); ok {
+		output, err = dr.RunCommandWithDir(ctx, cmd, s.cwd)
+	} else {
+		output, err = s.Executor.RunCommand(ctx, cmd)
 	}
-	return s.Executor.RunCommand(cmd)
+	res.Ended = time.Now()
+	res.Stdout, res.Combined, res.ExitCode = output, output, exitCode(err)
+	return res, err
 }
 
 // PendingSuggestion holds an AI-suggested command and explanation for confirmation
@@ -99,10 +216,22 @@ func (s *Session) RunCommand(cmd string) (string, error) {
 // raw: the full AI response
 // confirmed: whether the user has confirmed execution
 // declined: whether the user has declined execution
+// steps: every code block when the answer holds several, stepped through as a plan
+// history: what happened to each plan step so far
+// edited: the user's edit of command, after answering e, which runs instead of it
 type PendingSuggestion struct {
 	explanation string
 	command     string
 	raw         string
 	confirmed   bool
 	declined    bool
+	toolCall    *agent.ToolCall // set when the suggestion is a tool call from the agent loop
+	risk        commandRisk     // how dangerous the command to run is, which decides the answer it needs
+	aiRisk      string          // the risk a structured answer gave the command, kept for an edit of it
+	edited      string
+	needsTTY    bool // run command attached to the terminal
+	steps       []planStep
+	current     int  // index of the plan step awaiting an answer
+	editing     bool // the next answer replaces the current step's command
+	history     []stepOutcome
 }
@@ -1,7 +1,9 @@
 package shell
 
 import (
+	"context"
 	"errors"
+	"strings"
 	"testing"
 
 	"github.com/binks-cli/binks/internal/agent"
@@ -67,3 +69,57 @@ func TestSession_AI_YesWithoutPending(t *testing.T) {
 	assert.Contains(t, resp, "executed: yes")
 	assert.Equal(t, 1, exec.calls)
 }
+
+// streamingAgentMock streams its response in fixed chunks.
+type streamingAgentMock struct {
+	chunks []string
+}
+
+func (m *streamingAgentMock) Respond(context.Context, []agent.Message) (string, error) {
+	return strings.Join(m.chunks, ""), nil
+}
+
+func (m *streamingAgentMock) RespondStream(_ context.Context, _ []agent.Message, onToken func(string)) (string, error) {
+	for _, c := range m.chunks {
+		onToken(c)
+	}
+	return strings.Join(m.chunks, ""), nil
+}
+
+func TestProcessREPLLine_StreamingSuggestion(t *testing.T) {
+	sess := NewSession()
+	sess.Executor = &mockExecutor{}
+	sess.Agent = &streamingAgentMock{chunks: []string{"Clean ", "up:\n", "```sh\nrm -rf build/\n```"}}
+	var out, errOut strings.Builder
+
+	processREPLLine(">> clean", sess, &out, &errOut)
+	output := out.String()
+	assert.Equal(t, 1, strings.Count(output, "Clean up:"), "streamed text should be shown once")
+	assert.Contains(t, output, "```\nAI suggests: rm -rf build/  [high risk: deletes files recursively]\n")
+	assert.Contains(t, output, "Type yes or rm to run it:")
+	assert.NotNil(t, sess.pendingSuggestion)
+	assert.Nil(t, sess.streamOut)
+	assert.Empty(t, errOut.String())
+}
+
+func TestProcessREPLLine_StreamingPlainText(t *testing.T) {
+	sess := NewSession()
+	sess.Agent = &streamingAgentMock{chunks: []string{"Use ", "ls -a"}}
+	var out, errOut strings.Builder
+
+	processREPLLine(">> list hidden files", sess, &out, &errOut)
+	assert.Equal(t, "Use ls -a\n", out.String())
+	assert.Nil(t, sess.pendingSuggestion)
+}
+
+func TestProcessREPLLine_AIPrefixWithoutAIMode(t *testing.T) {
+	exec := &mockExecutor{}
+	sess := NewSession()
+	sess.Executor = exec
+	sess.Agent = &agent.DummyAgent{}
+	var out, errOut strings.Builder
+
+	processREPLLine(">> hello", sess, &out, &errOut)
+	assert.Equal(t, "Echo: hello\n", out.String())
+	assert.Equal(t, 0, exec.calls, "AI queries must not reach the shell")
+}
@@ -1,7 +1,8 @@
 package shell
 
 import (
-	"regexp"
+	"context"
+	"fmt"
 	"strings"
 
 	"github.com/binks-cli/binks/internal/agent"
@@ -9,16 +10,31 @@ import (
 
 // ExecuteLine dispatches input to the shell executor or the Agent, depending on isAIQuery.
 func (s *Session) ExecuteLine(line string) (string, error) {
+	return s.ExecuteLineContext(context.Background(), line)
+}
+
+// ExecuteLineContext is ExecuteLine with a context; cancelling it aborts the
+// AI request or command the line started.
+func (s *Session) ExecuteLineContext(ctx context.Context, line string) (string, error) {
 	trimmed := strings.TrimSpace(line)
 	if s.pendingSuggestion != nil {
 		answer := strings.ToLower(trimmed)
-		if answer == "y" || answer == "yes" {
-			cmd := s.pendingSuggestion.command
-			s.pendingSuggestion = nil
-			resp, err := s.RunCommand(cmd)
+		if s.pendingSuggestion.toolCall != nil {
+			return s.answerToolCall(ctx, s.pendingSuggestion.risk.accepts(trimmed))
+		}
+		if s.pendingSuggestion.isPlan() {
+			return s.answerPlan(ctx, trimmed)
+		}
+		if answer == "e" || answer == "edit" {
+			return s.runEditedSuggestion(ctx)
+		}
+		p := s.pendingSuggestion
+		s.pendingSuggestion = nil
+		if p.risk.accepts(trimmed) {
+			resp, err := s.runSuggestion(p.runContext(ctx), p, p.toRun())
 			return resp, err
 		} else {
-			s.pendingSuggestion = nil
+			s.declineSuggestion(p)
 			return "[AI] Cancelled.", nil
 		}
 	}
@@ -26,39 +42,105 @@ func (s *Session) ExecuteLine(line string) (string, error) {
 		if strings.HasPrefix(trimmed, ">>") {
 			trimmed = strings.TrimSpace(trimmed[2:])
 		}
-		resp, err := s.Agent.Respond(trimmed)
+		if err := s.checkBudget(); err != nil {
+			return "[AI] error: " + err.Error(), err
+		}
+		s.query = trimmed
+		if ta, ok := s.Agent.(agent.ToolCallingAgent); ok && s.Tools != nil {
+			return s.startToolLoop(ctx, ta, trimmed)
+		}
+		resp, err := s.respond(ctx, s.buildMessages(trimmed))
 		if err != nil {
 			s.pendingSuggestion = nil
 			return "[AI] error: " + err.Error(), err
 		}
-		// Parse AI response for code block (shell command)
-		explanation, command := parseAISuggestion(resp)
-		if command != "" {
-			s.pendingSuggestion = &PendingSuggestion{
-				explanation: explanation,
-				command:     command,
-				raw:         resp,
-				confirmed:   false,
-				declined:    false,
-			}
-			return "[AI]", nil // Signal to REPL to prompt for confirmation
+		s.recordUsage(s.Agent)
+		s.remember(agent.RoleUser, trimmed)
+		s.remember(agent.RoleAssistant, readableReply(resp))
+		return s.presentResponse(resp)
+	}
+	resp, err := s.RunCommandContext(ctx, line)
+	return resp, err
+}
+
+// presentResponse turns a final AI answer into a pending suggestion if it holds
+// a command, or into plain "[AI] ..." text otherwise. Answers without a
+// command are audited here; suggestions are audited once they are answered.
+func (s *Session) presentResponse(resp string) (string, error) {
+	out, err := s.suggestFromResponse(resp)
+	if s.pendingSuggestion == nil {
+		s.audit(auditRecord{Response: resp})
+	}
+	return out, err
+}
+
+// suggestFromResponse does the work of presentResponse. An answer with several
+// code blocks becomes a plan that is confirmed one step at a time. Structured
+// answers are used as they are; others are scanned for fenced code blocks.
+func (s *Session) suggestFromResponse(resp string) (string, error) {
+	if sugg, ok := agent.ParseSuggestion(resp); ok {
+		return s.presentSuggestion(sugg, resp)
+	}
+	if explanation, steps := parseAIPlan(resp); len(steps) > 1 {
+		p := &PendingSuggestion{explanation: explanation, raw: resp, steps: steps}
+		if s.advancePlan(p, 0) {
+			s.pendingSuggestion = p
+			return "[AI]", nil
 		}
 		return "[AI] " + resp, nil
+	} else if len(steps) == 1 && !steps[0].runnable {
+		return "[AI] " + resp, nil
+	}
+	// Parse AI response for code block (shell command)
+	explanation, command := parseAISuggestion(resp)
+	if command != "" {
+		s.pendingSuggestion = &PendingSuggestion{
+			explanation: explanation,
+			command:     command,
+			raw:         resp,
+			confirmed:   false,
+			declined:    false,
+			risk:        assessRisk(command, s.Cwd()),
+		}
+		return "[AI]", nil // Signal to REPL to prompt for confirmation
+	}
+	return "[AI] " + resp, nil
+}
+
+// respond sends the conversation to the agent. When the agent supports streaming
+// and the caller has set streamOut, partial text is written there as it arrives.
+func (s *Session) respond(ctx context.Context, messages []agent.Message) (string, error) {
+	s.streamed = false
+	if sa, ok := s.Agent.(agent.StructuredAgent); ok {
+		reply, err := sa.RespondStructured(ctx, messages, nil, s.streamFunc())
+		return reply.Content, err
+	}
+	if sa, ok := s.Agent.(agent.StreamingAgent); ok && s.streamOut != nil {
+		return sa.RespondStream(ctx, messages, s.streamFunc())
+	}
+	return s.Agent.Respond(ctx, messages)
+}
+
+// streamFunc returns a callback writing streamed tokens to streamOut, or nil when
+// the caller is not interested in partial output.
+func (s *Session) streamFunc() func(string) {
+	if s.streamOut == nil {
+		return nil
+	}
+	return func(token string) {
+		s.streamed = true
+		fmt.Fprint(s.streamOut, token)
 	}
-	resp, err := s.RunCommand(line)
-	return resp, err
 }
 
 // parseAISuggestion extracts explanation and the first shell command code block from AI response.
 func parseAISuggestion(resp string) (explanation, command string) {
 	resp = strings.ReplaceAll(resp, "\r\n", "\n")
-	// Regex to match code block: ```[lang]?\n...\n```
-	re := regexp.MustCompile("(?s)```(?:[a-zA-Z]+)?\\n(.*?)```")
-	match := re.FindStringSubmatch(resp)
+	match := codeBlockRe.FindStringSubmatch(resp)
 	if match != nil {
-		cmd := strings.TrimSpace(match[1])
+		cmd := strings.TrimSpace(match[2])
 		// Remove the code block from the response for explanation
-		explanation = strings.TrimSpace(re.ReplaceAllString(resp, ""))
+		explanation = strings.TrimSpace(codeBlockRe.ReplaceAllString(resp, ""))
 		return explanation, cmd
 	}
 	return resp, ""
@@ -1,8 +1,11 @@
 package shell
 
 import (
+	"context"
 	"testing"
 
+	"github.com/binks-cli/binks/internal/agent"
+
 	"github.com/stretchr/testify/assert"
 )
 
@@ -85,6 +88,6 @@ func TestSession_ExecuteLine_AINoCodeBlock(t *testing.T) {
 
 type agentFuncMock func(string) (string, error)
 
-func (f agentFuncMock) Respond(prompt string) (string, error) {
-	return f(prompt)
+func (f agentFuncMock) Respond(_ context.Context, messages []agent.Message) (string, error) {
+	return f(agent.LastUserMessage(messages))
 }
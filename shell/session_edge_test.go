@@ -1,15 +1,18 @@
 package shell
 
 import (
+	"context"
 	"errors"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/binks-cli/binks/internal/agent"
 	"github.com/binks-cli/binks/internal/executor"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 // Remove local mockExecutor; now shared in session_test_helpers.go
@@ -59,11 +62,11 @@ func TestSession_RunCommand_EdgeCases(t *testing.T) {
 	sess := NewSession()
 
 	// Replace executor with a mock for error simulation
-	mock := &executor.MockExecutorTestify{}
-	mock.On("RunCommand", "").Return("", nil)
-	mock.On("RunCommand", "failing").Return("", errors.New("fail"))
-	mock.On("RunCommand", strings.Repeat("a", 10000)).Return("ok", nil)
-	sess.Executor = mock
+	mockExec := &executor.MockExecutorTestify{}
+	mockExec.On("Run", mock.Anything, "", mock.Anything).Return(&executor.CommandResult{}, nil)
+	mockExec.On("Run", mock.Anything, "failing", mock.Anything).Return(&executor.CommandResult{}, errors.New("fail"))
+	mockExec.On("Run", mock.Anything, strings.Repeat("a", 10000), mock.Anything).Return(&executor.CommandResult{Stdout: "ok", Combined: "ok"}, nil)
+	sess.Executor = mockExec
 
 	t.Run("empty command", func(t *testing.T) {
 		output, err := sess.RunCommand("")
@@ -83,7 +86,7 @@ func TestSession_RunCommand_EdgeCases(t *testing.T) {
 		assert.Equal(t, "ok", output)
 	})
 
-	mock.AssertExpectations(t)
+	mockExec.AssertExpectations(t)
 }
 
 func TestSession_ChangeDir_NoPanic(t *testing.T) {
@@ -183,7 +186,7 @@ func TestSession_ExecuteLine_AI(t *testing.T) {
 func TestSession_ExecuteLine_AIError(t *testing.T) {
 	s := &Session{
 		Executor: &mockExecutor{resp: "shell output"},
-		Agent:    agent.AgentFunc(func(prompt string) (string, error) { return "", errors.New("fail") }),
+		Agent:    agent.AgentFunc(func([]agent.Message) (string, error) { return "", errors.New("fail") }),
 		cwd:      ".",
 	}
 	out, err := s.ExecuteLine(">> fail")
@@ -194,3 +197,71 @@ func TestSession_ExecuteLine_AIError(t *testing.T) {
 		t.Errorf("expected error output, got %q", out)
 	}
 }
+
+func TestSession_RunCommandContext_TimeoutAndInterrupt(t *testing.T) {
+	sess := &Session{Executor: executor.NewBashExecutor(), cwd: t.TempDir()}
+	sess.Exec = ExecConfig{Timeouts: map[string]time.Duration{"sleep": 100 * time.Millisecond}}
+
+	_, err := sess.RunCommand("sleep 5")
+	assert.EqualError(t, err, "command timed out after 100ms")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	_, err = sess.RunCommandContext(ctx, "true && sleep 5")
+	assert.EqualError(t, err, "interrupted")
+
+	history := sess.History()
+	if assert.Len(t, history, 2) {
+		assert.Equal(t, -1, history[0].ExitCode)
+	}
+}
+
+func TestSession_PersistentExecutorKeepsStateAndFollowsCd(t *testing.T) {
+	t.Chdir(t.TempDir())
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	assert.NoError(t, err)
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0o755))
+	pe := executor.NewPersistentExecutor()
+	t.Cleanup(func() { _ = pe.Close() })
+	sess := &Session{Executor: pe, cwd: dir}
+
+	_, err = sess.RunCommand("export STAGE=dev; cd sub")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "sub"), sess.Cwd(), "a cd in a command moves the session")
+
+	out, err := sess.RunCommand("echo $STAGE; pwd")
+	assert.NoError(t, err)
+	assert.Equal(t, "dev\n"+filepath.Join(dir, "sub")+"\n", out)
+
+	assert.NoError(t, sess.ChangeDir(dir))
+	out, err = sess.RunCommand("pwd")
+	assert.NoError(t, err)
+	assert.Equal(t, dir+"\n", out, "the built-in cd moves the shell")
+
+	_, err = sess.RunCommand("false")
+	assert.EqualError(t, err, "exit status 1")
+	assert.Equal(t, 1, sess.last.result.ExitCode)
+}
+
+func TestSession_RunCommandResult(t *testing.T) {
+	dir := t.TempDir()
+	sess := &Session{Executor: executor.NewBashExecutor(), cwd: dir}
+
+	res, err := sess.RunCommandResult(context.Background(), "echo out; echo err >&2; exit 4")
+	assert.EqualError(t, err, "exit status 4")
+	assert.Equal(t, "out\n", res.Stdout)
+	assert.Equal(t, "err\n", res.Stderr)
+	assert.Equal(t, 4, res.ExitCode)
+	assert.Equal(t, dir, res.Dir)
+	assert.Same(t, res, sess.last.result)
+	assert.Equal(t, []CommandRecord{{Command: "echo out; echo err >&2; exit 4", ExitCode: 4}}, sess.History())
+
+	// Executors that only return output get a result made from it.
+	sess.Executor = &mockExecutor{resp: "plain\n"}
+	res, err = sess.RunCommandResult(context.Background(), "anything")
+	assert.NoError(t, err)
+	assert.Equal(t, "plain\n", res.Stdout)
+	assert.Equal(t, "plain\n", res.Combined)
+	assert.Equal(t, 0, res.ExitCode)
+	assert.False(t, res.Ended.Before(res.Started))
+}
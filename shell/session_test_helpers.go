@@ -1,5 +1,7 @@
 package shell
 
+import "context"
+
 // mockExecutor is a test double for Executor, used in multiple test files.
 type mockExecutor struct {
 	lastCmd string
@@ -9,7 +11,7 @@ type mockExecutor struct {
 	err     error
 }
 
-func (m *mockExecutor) RunCommand(cmd string) (string, error) {
+func (m *mockExecutor) RunCommand(_ context.Context, cmd string) (string, error) {
 	m.lastCmd = cmd
 	m.calls++
 	if m.fail {
@@ -21,6 +23,6 @@ func (m *mockExecutor) RunCommand(cmd string) (string, error) {
 	return "executed: " + cmd, nil
 }
 
-func (m *mockExecutor) RunCommandWithDir(cmd, dir string) (string, error) {
-	return m.RunCommand(cmd)
+func (m *mockExecutor) RunCommandWithDir(ctx context.Context, cmd, dir string) (string, error) {
+	return m.RunCommand(ctx, cmd)
 }
@@ -0,0 +1,65 @@
+package shell
+
+import (
+	"context"
+
+	"github.com/binks-cli/binks/internal/agent"
+	"github.com/binks-cli/binks/internal/executor"
+)
+
+// presentSuggestion turns a structured answer into a pending suggestion, or a
+// plan when it holds several commands, like presentResponse does for markdown.
+func (s *Session) presentSuggestion(sugg agent.Suggestion, raw string) (string, error) {
+	switch len(sugg.Commands) {
+	case 0:
+		return "[AI] " + sugg.Explanation, nil
+	case 1:
+		c := sugg.Commands[0]
+		s.pendingSuggestion = &PendingSuggestion{
+			explanation: sugg.Explanation,
+			command:     c.Command,
+			raw:         raw,
+			risk:        s.suggestionRisk(c.Command, c.Risk),
+			aiRisk:      c.Risk,
+			needsTTY:    c.NeedsTTY,
+		}
+		return "[AI]", nil
+	}
+	p := &PendingSuggestion{explanation: sugg.Explanation, raw: raw}
+	for _, c := range sugg.Commands {
+		p.steps = append(p.steps, planStep{command: c.Command, lang: "sh", runnable: true, needsTTY: c.NeedsTTY, aiRisk: c.Risk})
+	}
+	s.advancePlan(p, 0)
+	s.pendingSuggestion = p
+	return "[AI]", nil
+}
+
+// suggestionRisk assesses a suggested command, raising the level to the one
+// the agent gave it (if any) when that is higher.
+func (s *Session) suggestionRisk(cmd, aiRisk string) commandRisk {
+	r := assessRisk(cmd, s.Cwd())
+	for _, level := range []riskLevel{riskMedium, riskHigh} {
+		if aiRisk == level.String() && level > r.level {
+			r.add(level, "rated "+aiRisk+" risk by the AI")
+		}
+	}
+	return r
+}
+
+// readableReply returns the markdown form of a structured answer, for the
+// conversation and transcripts, and any other answer unchanged.
+func readableReply(resp string) string {
+	if sugg, ok := agent.ParseSuggestion(resp); ok {
+		return sugg.Markdown()
+	}
+	return resp
+}
+
+// runContext marks ctx for running p's command attached to the terminal when
+// the agent said it needs one.
+func (p *PendingSuggestion) runContext(ctx context.Context) context.Context {
+	if p.needsTTY {
+		return executor.WithTTY(ctx)
+	}
+	return ctx
+}
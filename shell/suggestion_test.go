@@ -0,0 +1,143 @@
+package shell
+
+import (
+	"context"
+	"testing"
+
+	"github.com/binks-cli/binks/internal/agent"
+	"github.com/binks-cli/binks/internal/executor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ttyExecutor records the commands it runs and whether each was to be
+// attached to the terminal.
+type ttyExecutor struct {
+	ran []string
+	tty []bool
+}
+
+func (e *ttyExecutor) RunCommand(ctx context.Context, cmd string) (string, error) {
+	e.ran = append(e.ran, cmd)
+	e.tty = append(e.tty, executor.WantsTTY(ctx))
+	return "", nil
+}
+
+func (e *ttyExecutor) RunCommandWithDir(ctx context.Context, cmd, _ string) (string, error) {
+	return e.RunCommand(ctx, cmd)
+}
+
+func structuredSession(output string) (*Session, *ttyExecutor) {
+	exec := &ttyExecutor{}
+	return &Session{cwd: ".", Executor: exec, Agent: agent.NewMockAgent(nil, agent.AgentResult{Output: output})}, exec
+}
+
+func TestSession_StructuredSuggestion(t *testing.T) {
+	sess, exec := structuredSession(`{"explanation":"Open the module file.","commands":[{"command":"vim go.mod","risk":"low","needs_tty":true}]}`)
+
+	resp, err := sess.ExecuteLine(">> edit the module")
+	require.NoError(t, err)
+	assert.Equal(t, "[AI]", resp)
+	p := sess.pendingSuggestion
+	require.NotNil(t, p)
+	assert.Equal(t, "Open the module file.", p.explanation)
+	assert.Equal(t, "vim go.mod", p.command)
+	assert.True(t, p.needsTTY)
+	assert.Equal(t, "Open the module file.\n```sh\nvim go.mod\n```", sess.transcript.Messages()[1].Content, "the conversation keeps a readable answer")
+
+	_, err = sess.ExecuteLine("y")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"vim go.mod"}, exec.ran)
+	assert.Equal(t, []bool{true}, exec.tty)
+}
+
+func TestSession_StructuredSuggestion_AIRiskRaisesLevel(t *testing.T) {
+	sess, exec := structuredSession(`{"explanation":"","commands":[{"command":"make deploy","risk":"high","needs_tty":false}]}`)
+
+	_, err := sess.ExecuteLine(">> ship it")
+	require.NoError(t, err)
+	assert.Equal(t, riskHigh, sess.pendingSuggestion.risk.level)
+	assert.Equal(t, []string{"rated high risk by the AI"}, sess.pendingSuggestion.risk.reasons)
+	resp, err := sess.ExecuteLine("y")
+	require.NoError(t, err)
+	assert.Equal(t, "[AI] Cancelled.", resp)
+	assert.Empty(t, exec.ran)
+
+	// The agent cannot lower the level binks assesses itself.
+	r := sess.suggestionRisk("rm -rf build", "low")
+	assert.Equal(t, riskHigh, r.level)
+}
+
+func TestSession_StructuredSuggestion_EditKeepsAIRisk(t *testing.T) {
+	sess, exec := structuredSession(`{"explanation":"","commands":[{"command":"make deploy","risk":"high","needs_tty":false}]}`)
+	sess.editLine = func(_, text string) (string, error) { return text + " ", nil }
+
+	_, err := sess.ExecuteLine(">> ship it")
+	require.NoError(t, err)
+	resp, err := sess.ExecuteLine("e")
+	require.NoError(t, err)
+	assert.Equal(t, "[AI]", resp, "a trivial edit is still confirmed")
+	assert.Empty(t, exec.ran)
+	assert.Equal(t, riskHigh, sess.pendingSuggestion.risk.level)
+	assert.Equal(t, []string{"rated high risk by the AI"}, sess.pendingSuggestion.risk.reasons)
+
+	resp, err = sess.ExecuteLine("y")
+	require.NoError(t, err)
+	assert.Equal(t, "[AI] Cancelled.", resp)
+	assert.Empty(t, exec.ran)
+}
+
+func TestSession_StructuredPlan_EditKeepsAIRisk(t *testing.T) {
+	sess, exec := structuredSession(`{"explanation":"","commands":[
+		{"command":"make deploy","risk":"high","needs_tty":false},
+		{"command":"make test","risk":"low","needs_tty":false}]}`)
+	sess.editLine = func(_, text string) (string, error) { return text + " -j4", nil }
+
+	_, err := sess.ExecuteLine(">> ship it")
+	require.NoError(t, err)
+	_, err = sess.ExecuteLine("e")
+	require.NoError(t, err)
+	p := sess.pendingSuggestion
+	assert.Equal(t, "make deploy -j4", p.command)
+	assert.Equal(t, riskHigh, p.risk.level)
+	assert.Empty(t, exec.ran)
+}
+
+func TestSession_StructuredPlan(t *testing.T) {
+	sess, exec := structuredSession(`{"explanation":"Update and test.","commands":[
+		{"command":"git pull","risk":"low","needs_tty":false},
+		{"command":"make test","risk":"low","needs_tty":false}]}`)
+
+	_, err := sess.ExecuteLine(">> update")
+	require.NoError(t, err)
+	require.True(t, sess.pendingSuggestion.isPlan())
+	for range 2 {
+		_, err = sess.ExecuteLine("r")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []string{"git pull", "make test"}, exec.ran)
+	assert.Nil(t, sess.pendingSuggestion)
+}
+
+func TestSession_StructuredAnswerWithoutCommands(t *testing.T) {
+	sess, _ := structuredSession(`{"explanation":"HEAD is the current commit.","commands":[]}`)
+	resp, err := sess.ExecuteLine(">> what is HEAD?")
+	require.NoError(t, err)
+	assert.Equal(t, "[AI] HEAD is the current commit.", resp)
+	assert.Nil(t, sess.pendingSuggestion)
+}
+
+func TestSession_MarkdownFallback(t *testing.T) {
+	// Answers that are not structured, like those of models without
+	// structured outputs, still have their code blocks parsed.
+	sess, exec := structuredSession("List them with:\n```sh\nls -a\n```")
+	_, err := sess.ExecuteLine(">> list")
+	require.NoError(t, err)
+	require.NotNil(t, sess.pendingSuggestion)
+	assert.Equal(t, "List them with:", sess.pendingSuggestion.explanation)
+	assert.Equal(t, "ls -a", sess.pendingSuggestion.command)
+	assert.False(t, sess.pendingSuggestion.needsTTY)
+	_, err = sess.ExecuteLine("yes")
+	require.NoError(t, err)
+	assert.Equal(t, []bool{false}, exec.tty)
+}
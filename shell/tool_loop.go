@@ -0,0 +1,177 @@
+package shell
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/binks-cli/binks/internal/agent"
+)
+
+// maxToolSteps bounds how many model round-trips a single query may take.
+const maxToolSteps = 10
+
+// toolLoop tracks an AI query during which the model calls tools. Each call is
+// confirmed through PendingSuggestion before it runs, so the loop advances one
+// REPL line at a time.
+type toolLoop struct {
+	agent       agent.ToolCallingAgent
+	query       string
+	messages    []agent.Message  // request sent to the model so far
+	queue       []agent.ToolCall // calls from the latest reply awaiting approval
+	explanation string           // text the model sent alongside the queued calls
+	steps       int              // model round-trips made so far
+	results     []string         // condensed tool results, kept in the conversation
+}
+
+// startToolLoop sends the query with the session's tools and runs the loop until
+// the model asks for a tool (returning "[AI]" with a pending suggestion) or answers.
+func (s *Session) startToolLoop(ctx context.Context, ta agent.ToolCallingAgent, query string) (string, error) {
+	s.toolLoop = &toolLoop{agent: ta, query: query, messages: s.buildMessages(query)}
+	return s.continueToolLoop(ctx)
+}
+
+// continueToolLoop asks for approval of the next queued tool call, or queries
+// the model again once every call from its last reply has been answered.
+func (s *Session) continueToolLoop(ctx context.Context) (string, error) {
+	loop := s.toolLoop
+	for len(loop.queue) == 0 {
+		if loop.steps == maxToolSteps {
+			s.finishToolLoop("")
+			return fmt.Sprintf("[AI] Stopped after %d tool steps.", maxToolSteps), nil
+		}
+		loop.steps++
+		s.streamed = false
+		var reply agent.Reply
+		var err error
+		if sa, ok := loop.agent.(agent.StructuredAgent); ok {
+			reply, err = sa.RespondStructured(ctx, loop.messages, s.Tools.Tools(), s.streamFunc())
+		} else {
+			reply, err = loop.agent.RespondWithTools(ctx, loop.messages, s.Tools.Tools(), s.streamFunc())
+		}
+		if err != nil {
+			s.toolLoop = nil
+			return "[AI] error: " + err.Error(), err
+		}
+		s.recordUsage(loop.agent)
+		loop.messages = append(loop.messages, agent.Message{
+			Role:      agent.RoleAssistant,
+			Content:   reply.Content,
+			ToolCalls: reply.ToolCalls,
+		})
+		if len(reply.ToolCalls) == 0 {
+			s.finishToolLoop(reply.Content)
+			return s.presentResponse(reply.Content)
+		}
+		loop.queue = reply.ToolCalls
+		loop.explanation = reply.Content
+	}
+	call := loop.queue[0]
+	s.pendingSuggestion = &PendingSuggestion{
+		explanation: loop.explanation,
+		command:     s.Tools.Describe(call),
+		raw:         call.Arguments,
+		toolCall:    &call,
+		risk:        s.toolCallRisk(call),
+	}
+	loop.explanation = ""
+	return "[AI]", nil
+}
+
+// toolCallRisk assesses what a tool call would do, so that it is confirmed
+// like a suggested command: the command a run_command call runs, or where a
+// write_file call writes. Other tools are low risk.
+func (s *Session) toolCallRisk(call agent.ToolCall) commandRisk {
+	var args struct {
+		Command string `json:"command"`
+		Path    string `json:"path"`
+	}
+	if err := json.Unmarshal([]byte(call.Arguments), &args); err != nil {
+		return commandRisk{}
+	}
+	var r commandRisk
+	switch call.Name {
+	case "run_command":
+		r = assessRisk(args.Command, s.Cwd())
+	case "write_file":
+		if outsideDir(args.Path, s.Cwd()) {
+			r.add(riskHigh, "writes outside the working directory ("+args.Path+")")
+		}
+	}
+	return r
+}
+
+// answerToolCall runs (or declines) the pending tool call, feeds the result back
+// to the model and continues the loop.
+func (s *Session) answerToolCall(ctx context.Context, approved bool) (string, error) {
+	p := s.pendingSuggestion
+	call := *p.toolCall
+	summary := p.command
+	s.pendingSuggestion = nil
+	loop := s.toolLoop
+	if loop == nil {
+		return "[AI] Cancelled.", nil
+	}
+	loop.queue = loop.queue[1:]
+	var result string
+	if approved {
+		before, started := s.last, time.Now()
+		output, err := s.Tools.Call(ctx, call)
+		var ran *lastCommand
+		if s.last != before {
+			ran = s.last // the tool ran a shell command
+		}
+		s.auditDecision(p, decisionAccepted, ran, time.Since(started))
+		s.writeLive(output)
+		result = toolResult(output, err)
+	} else {
+		s.auditDecision(p, decisionDeclined, nil, 0)
+		s.writeLive("[AI] Skipped.\n")
+		result = "The user declined this tool call."
+	}
+	loop.messages = append(loop.messages, agent.Message{Role: agent.RoleTool, ToolCallID: call.ID, Content: result})
+	loop.results = append(loop.results, fmt.Sprintf("Tool `%s` returned:\n%s", summary, truncateOutput(result)))
+	return s.continueToolLoop(ctx)
+}
+
+// finishToolLoop ends the loop and records the exchange in the conversation.
+// Tool calls are condensed to plain messages so that trimming the conversation
+// can never separate a call from its result.
+func (s *Session) finishToolLoop(answer string) {
+	loop := s.toolLoop
+	s.toolLoop = nil
+	s.remember(agent.RoleUser, loop.query)
+	for _, r := range loop.results {
+		s.remember(agent.RoleUser, r)
+	}
+	if answer != "" {
+		s.remember(agent.RoleAssistant, readableReply(answer))
+	}
+}
+
+// toolResult formats a tool's output and error for the model.
+func toolResult(output string, err error) string {
+	if err != nil {
+		if output != "" {
+			return output + "\nError: " + err.Error()
+		}
+		return "Error: " + err.Error()
+	}
+	if output == "" {
+		return "(no output)"
+	}
+	return output
+}
+
+// writeLive shows tool output to the user while a query is in progress.
+func (s *Session) writeLive(text string) {
+	if s.streamOut == nil || text == "" {
+		return
+	}
+	fmt.Fprint(s.streamOut, text)
+	if !strings.HasSuffix(text, "\n") {
+		fmt.Fprint(s.streamOut, "\n")
+	}
+}
@@ -0,0 +1,270 @@
+package shell
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/binks-cli/binks/internal/agent"
+)
+
+// ErrBudgetExceeded is returned for AI queries once a budget from ai.usage.budget is used up.
+var ErrBudgetExceeded = errors.New("AI budget exceeded")
+
+// usageDays is how many days :usage breaks down.
+const usageDays = 7
+
+// usageRecord is the token usage of one AI request.
+type usageRecord struct {
+	Time     time.Time `json:"time"`
+	Provider string    `json:"provider"`
+	Model    string    `json:"model"`
+	Input    int       `json:"input_tokens"`
+	Output   int       `json:"output_tokens"`
+}
+
+// usagePath returns the file AI usage is appended to, ~/.binks_usage.jsonl.
+func usagePath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".binks_usage.jsonl")
+}
+
+// recordUsage stores the tokens ag reported for its last request.
+func (s *Session) recordUsage(ag agent.Agent) {
+	ur, ok := ag.(agent.UsageReporter)
+	if !ok {
+		return
+	}
+	u := ur.LastUsage()
+	if u.PromptTokens == 0 && u.CompletionTokens == 0 {
+		return
+	}
+	model := u.Model
+	if model == "" {
+		model = agentModel(ag)
+	}
+	rec := usageRecord{
+		Time:     time.Now(),
+		Provider: agentProvider(ag),
+		Model:    model,
+		Input:    u.PromptTokens,
+		Output:   u.CompletionTokens,
+	}
+	s.spent = append(s.spent, rec)
+	if s.usageFile != "" {
+		_ = appendUsage(s.usageFile, rec) // usage tracking must never break a query
+	}
+}
+
+func appendUsage(path string, rec usageRecord) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(line, '\n'))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// usageSince returns the recorded usage from since onwards: from the usage
+// file when there is one, so that other sessions count too, or else from this
+// session.
+func (s *Session) usageSince(since time.Time) []usageRecord {
+	records := s.spent
+	if s.usageFile != "" {
+		if f, err := os.Open(s.usageFile); err == nil {
+			records = readUsage(f)
+			_ = f.Close()
+		}
+	}
+	var out []usageRecord
+	for _, r := range records {
+		if !r.Time.Before(since) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// readUsage parses a usage file, skipping lines it cannot read.
+func readUsage(r io.Reader) []usageRecord {
+	var records []usageRecord
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		var rec usageRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err == nil {
+			records = append(records, rec)
+		}
+	}
+	return records
+}
+
+// price returns the price of a model: an exact match, or else the longest
+// configured name the model starts with (e.g. "gpt-4o" for "gpt-4o-2024-08-06").
+func (c UsageConfig) price(model string) (ModelPrice, bool) {
+	if p, ok := c.Prices[model]; ok {
+		return p, true
+	}
+	best := ""
+	for name := range c.Prices {
+		if strings.HasPrefix(model, name) && len(name) > len(best) {
+			best = name
+		}
+	}
+	if best == "" {
+		return ModelPrice{}, false
+	}
+	return c.Prices[best], true
+}
+
+// usageTotal sums the tokens and cost of some usage. Models without a price
+// cost nothing.
+type usageTotal struct {
+	Input, Output int
+	Cost          float64
+}
+
+func (c UsageConfig) total(records []usageRecord) usageTotal {
+	var t usageTotal
+	for _, r := range records {
+		t.Input += r.Input
+		t.Output += r.Output
+		if p, ok := c.price(r.Model); ok {
+			t.Cost += (float64(r.Input)*p.Input + float64(r.Output)*p.Output) / 1e6
+		}
+	}
+	return t
+}
+
+// startOfDay returns local midnight of the day t falls on.
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// checkBudget refuses further AI queries once the session's or today's
+// spending has reached its budget.
+func (s *Session) checkBudget() error {
+	b := s.Usage.Budget
+	if b.Session > 0 {
+		if spent := s.Usage.total(s.spent).Cost; spent >= b.Session {
+			return fmt.Errorf("%w: spent %s of the %s session budget", ErrBudgetExceeded, formatCost(spent), formatCost(b.Session))
+		}
+	}
+	if b.Daily > 0 {
+		if spent := s.Usage.total(s.usageSince(startOfDay(time.Now()))).Cost; spent >= b.Daily {
+			return fmt.Errorf("%w: spent %s of the %s daily budget", ErrBudgetExceeded, formatCost(spent), formatCost(b.Daily))
+		}
+	}
+	return nil
+}
+
+// prompt returns the REPL prompt, led by the session's AI usage when
+// ai.usage.prompt is on and there is usage to show.
+func (s *Session) prompt() string {
+	p := promptWithAI(s.Cwd(), s.AIEnabled)
+	if !s.Usage.Prompt || len(s.spent) == 0 {
+		return p
+	}
+	t := s.Usage.total(s.spent)
+	if t.Cost > 0 {
+		return "[" + formatCost(t.Cost) + "] " + p
+	}
+	return "[" + formatTokens(t.Input+t.Output) + " tok] " + p
+}
+
+func formatCost(usd float64) string {
+	if usd > 0 && usd < 0.01 {
+		return fmt.Sprintf("$%.4f", usd)
+	}
+	return fmt.Sprintf("$%.2f", usd)
+}
+
+func formatTokens(n int) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(n)/1e6)
+	case n >= 1000:
+		return fmt.Sprintf("%.1fk", float64(n)/1e3)
+	}
+	return fmt.Sprint(n)
+}
+
+func metaUsage(_ context.Context, sess *Session, _ []string, out io.Writer) error {
+	cfg := sess.Usage
+	if len(sess.spent) == 0 {
+		fmt.Fprintln(out, "[AI] No AI usage in this session.")
+	} else {
+		fmt.Fprintln(out, "This session:")
+		byModel := map[string][]usageRecord{}
+		for _, r := range sess.spent {
+			byModel[r.Model] = append(byModel[r.Model], r)
+		}
+		models := make([]string, 0, len(byModel))
+		for m := range byModel {
+			models = append(models, m)
+		}
+		sort.Strings(models)
+		for _, m := range models {
+			printUsageLine(out, m, cfg, byModel[m])
+		}
+		if len(models) > 1 {
+			printUsageLine(out, "total", cfg, sess.spent)
+		}
+	}
+
+	today := startOfDay(time.Now())
+	records := sess.usageSince(today.AddDate(0, 0, -(usageDays - 1)))
+	if len(records) > 0 {
+		fmt.Fprintf(out, "Last %d days:\n", usageDays)
+		byDay := map[string][]usageRecord{}
+		for _, r := range records {
+			day := r.Time.Local().Format(time.DateOnly)
+			byDay[day] = append(byDay[day], r)
+		}
+		for d := 0; d < usageDays; d++ {
+			day := today.AddDate(0, 0, -d).Format(time.DateOnly)
+			if recs, ok := byDay[day]; ok {
+				printUsageLine(out, day, cfg, recs)
+			}
+		}
+	}
+
+	if b := cfg.Budget; b.Session > 0 || b.Daily > 0 {
+		var parts []string
+		if b.Session > 0 {
+			parts = append(parts, fmt.Sprintf("%s of %s this session", formatCost(cfg.total(sess.spent).Cost), formatCost(b.Session)))
+		}
+		if b.Daily > 0 {
+			parts = append(parts, fmt.Sprintf("%s of %s today", formatCost(cfg.total(sess.usageSince(today)).Cost), formatCost(b.Daily)))
+		}
+		fmt.Fprintf(out, "Budget: %s\n", strings.Join(parts, ", "))
+	}
+	return nil
+}
+
+func printUsageLine(out io.Writer, label string, cfg UsageConfig, records []usageRecord) {
+	t := cfg.total(records)
+	cost := "-"
+	if t.Cost > 0 {
+		cost = formatCost(t.Cost)
+	}
+	fmt.Fprintf(out, "  %-28s %9d in %9d out  %s\n", label, t.Input, t.Output, cost)
+}
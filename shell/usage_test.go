@@ -0,0 +1,125 @@
+package shell
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/binks-cli/binks/internal/agent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// usageStandIn returns a session whose agent is an Ollama server reporting the
+// given token counts for every reply.
+func usageStandIn(t *testing.T, input, output int) *Session {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, `{"model":"qwen2.5-coder:7b","message":{"role":"assistant","content":"hello"},"done":true,"prompt_eval_count":%d,"eval_count":%d}`, input, output)
+	}))
+	t.Cleanup(srv.Close)
+	return &Session{
+		cwd:       ".",
+		Executor:  &mockExecutor{},
+		Agent:     &agent.OllamaAgent{Model: "qwen2.5-coder:7b", BaseURL: srv.URL, Client: srv.Client()},
+		usageFile: filepath.Join(t.TempDir(), "usage.jsonl"),
+	}
+}
+
+func TestSession_RecordsUsage(t *testing.T) {
+	sess := usageStandIn(t, 1200, 300)
+	sess.Usage.Prices = map[string]ModelPrice{"qwen2.5": {Input: 1, Output: 4}}
+
+	_, err := sess.ExecuteLine(">> hi")
+	require.NoError(t, err)
+	require.Len(t, sess.spent, 1)
+	assert.Equal(t, "ollama", sess.spent[0].Provider)
+	assert.Equal(t, "qwen2.5-coder:7b", sess.spent[0].Model)
+	assert.Equal(t, usageTotal{Input: 1200, Output: 300, Cost: 0.0024}, sess.Usage.total(sess.spent))
+
+	data, err := os.ReadFile(sess.usageFile)
+	require.NoError(t, err)
+	assert.Len(t, readUsage(strings.NewReader(string(data))), 1)
+
+	var out strings.Builder
+	require.NoError(t, runMetaCommand(context.Background(), ":usage", sess, &out))
+	assert.Contains(t, out.String(), "This session:")
+	assert.Regexp(t, `qwen2\.5-coder:7b\s+1200 in\s+300 out\s+\$0\.0024`, out.String())
+	assert.Regexp(t, time.Now().Format(time.DateOnly)+`\s+1200 in`, out.String())
+}
+
+func TestSession_BudgetRefusesQueries(t *testing.T) {
+	sess := usageStandIn(t, 1000, 1000)
+	sess.Usage.Prices = map[string]ModelPrice{"qwen2.5-coder:7b": {Input: 5, Output: 5}}
+	sess.Usage.Budget.Session = 0.01
+
+	_, err := sess.ExecuteLine(">> first")
+	require.NoError(t, err)
+	resp, budgetErr := sess.ExecuteLine(">> second")
+	require.ErrorIs(t, budgetErr, ErrBudgetExceeded)
+	assert.Equal(t, "[AI] error: AI budget exceeded: spent $0.01 of the $0.01 session budget", resp)
+	assert.Len(t, sess.spent, 1, "the refused query is not sent")
+
+	out, err := sess.ExecuteLine("echo still works")
+	require.NoError(t, err)
+	assert.Equal(t, "executed: echo still works", out)
+
+	var buf strings.Builder
+	printAIError(&buf, budgetErr)
+	assert.Contains(t, buf.String(), ":usage")
+}
+
+func TestSession_DailyBudgetCountsOtherSessions(t *testing.T) {
+	sess := usageStandIn(t, 10, 10)
+	sess.Usage.Prices = map[string]ModelPrice{"gpt-4o": {Input: 2.5, Output: 10}}
+	sess.Usage.Budget.Daily = 1
+	earlier := usageRecord{Time: time.Now(), Provider: "openai", Model: "gpt-4o-2024-08-06", Input: 200_000, Output: 60_000}
+	yesterday := usageRecord{Time: time.Now().AddDate(0, 0, -1), Provider: "openai", Model: "gpt-4o", Input: 1_000_000}
+	require.NoError(t, appendUsage(sess.usageFile, yesterday))
+	require.NoError(t, appendUsage(sess.usageFile, earlier))
+
+	_, err := sess.ExecuteLine(">> hi")
+	assert.ErrorIs(t, err, ErrBudgetExceeded)
+	assert.Contains(t, err.Error(), "$1.10 of the $1.00 daily budget")
+}
+
+func TestUsageConfig_Price(t *testing.T) {
+	cfg := UsageConfig{Prices: map[string]ModelPrice{
+		"gpt-4o":      {Input: 2.5, Output: 10},
+		"gpt-4o-mini": {Input: 0.15, Output: 0.6},
+	}}
+	p, ok := cfg.price("gpt-4o-mini-2024-07-18")
+	assert.True(t, ok)
+	assert.Equal(t, 0.15, p.Input, "the longest matching name wins")
+	p, ok = cfg.price("gpt-4o")
+	assert.True(t, ok)
+	assert.Equal(t, 2.5, p.Input)
+	_, ok = cfg.price("llama3.2")
+	assert.False(t, ok)
+}
+
+func TestSession_PromptShowsUsage(t *testing.T) {
+	sess := usageStandIn(t, 1500, 500)
+	plain := sess.prompt()
+	_, err := sess.ExecuteLine(">> hi")
+	require.NoError(t, err)
+	assert.Equal(t, plain, sess.prompt(), "the indicator is off by default")
+
+	sess.Usage.Prompt = true
+	assert.Equal(t, "[2.0k tok] "+plain, sess.prompt())
+	sess.Usage.Prices = map[string]ModelPrice{"qwen": {Input: 10, Output: 10}}
+	assert.Equal(t, "[$0.02] "+plain, sess.prompt())
+}
+
+func TestMetaUsage_Empty(t *testing.T) {
+	sess := &Session{cwd: "."}
+	var out strings.Builder
+	require.NoError(t, metaUsage(context.Background(), sess, nil, &out))
+	assert.Equal(t, "[AI] No AI usage in this session.\n", out.String())
+}
@@ -1,7 +1,9 @@
 package test
 
 import (
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -57,3 +59,29 @@ func TestCLI_InvalidCommand_ErrorOutput(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, string(output), "Error:")
 }
+
+func TestCLI_AIQuery_ReplaysCassette(t *testing.T) {
+	cassette, err := filepath.Abs("testdata/openai_list_files.json")
+	require.NoError(t, err)
+	binPath, err := filepath.Abs("../binks")
+	require.NoError(t, err)
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".hidden"), nil, 0o644))
+
+	cmd := exec.Command(binPath)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"HOME="+t.TempDir(),
+		"OPENAI_API_KEY=replay",
+		"BINKS_AI_PROVIDER=openai",
+		"BINKS_CASSETTE="+cassette,
+		"BINKS_CASSETTE_MODE=replay",
+	)
+	cmd.Stdin = strings.NewReader(">> how do I list hidden files?\ny\nexit\n")
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+	outStr := string(output)
+	assert.Contains(t, outStr, "To list all files, including hidden ones:")
+	assert.Contains(t, outStr, "AI suggests: ls -a")
+	assert.Contains(t, outStr, ".hidden")
+}